@@ -0,0 +1,230 @@
+// Package apikey issues and authenticates API keys for the service to
+// service authentication.
+//
+// The keys look like "gk_live_<random>_<crc>": a product prefix, an
+// environment, a random base62 part and a base62 CRC-32 of the rest. The
+// checksum lets the typos and the leaked keys be recognized offline, for
+// example by secret scanners, without hitting the store. The random part
+// starts with the public id of the key, which is used to look it up, and
+// only a hash of the key is stored.
+package apikey
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/josestg/gokit/password"
+)
+
+const (
+	// DefaultPrefix is the default product prefix of the keys.
+	DefaultPrefix = "gk"
+	// DefaultEnvironment is the default environment of the keys.
+	DefaultEnvironment = "live"
+)
+
+const (
+	// idLen is the length of the public id of the keys.
+	idLen = 12
+	// secretLen is the length of the secret part of the keys, about 190 bits.
+	secretLen = 32
+	// checksumLen is the length of the base62 encoded CRC-32.
+	checksumLen = 6
+)
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	// ErrInvalidKey is returned when the key is malformed, has a wrong
+	// checksum, is unknown or does not match its stored hash.
+	ErrInvalidKey = errors.New("apikey: invalid key")
+
+	// ErrKeyNotFound is returned by the stores when the key is unknown.
+	ErrKeyNotFound = errors.New("apikey: key not found")
+
+	// ErrKeyExpired is returned when the key is expired.
+	ErrKeyExpired = errors.New("apikey: key expired")
+
+	// ErrKeyRevoked is returned when the key is revoked.
+	ErrKeyRevoked = errors.New("apikey: key revoked")
+
+	// ErrInsufficientScope is returned when the key lacks a required scope.
+	ErrInsufficientScope = errors.New("apikey: insufficient scope")
+)
+
+// Record is the stored state of a key. The key itself is never stored, only
+// its hash.
+type Record struct {
+	// ID is the public id of the key.
+	ID string
+	// Name is a human readable name of the key, for example the name of the
+	// calling service.
+	Name string
+	// Hash is the hash of the key.
+	Hash string
+	// Scopes are the scopes granted to the key.
+	Scopes []string
+	// CreatedAt is the creation time of the key.
+	CreatedAt time.Time
+	// ExpiresAt is the expiration time of the key, zero if it never expires.
+	ExpiresAt time.Time
+	// LastUsedAt is the last time the key was authenticated, zero if it was
+	// never used.
+	LastUsedAt time.Time
+	// RotatedTo is the id of the key that replaced this key, if any.
+	RotatedTo string
+	// Revoked reports whether the key is revoked.
+	Revoked bool
+}
+
+// HasScopes reports whether the key is granted all the given scopes.
+func (r Record) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !r.hasScope(scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Record) hasScope(scope string) bool {
+	for _, s := range r.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expired reports whether the key is expired at the given time.
+func (r Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// hmacHashComparer is a password.HashComparer that hashes with HMAC-SHA256.
+type hmacHashComparer struct {
+	secret []byte
+}
+
+// NewHMACHashComparer returns a password.HashComparer that hashes the keys
+// with HMAC-SHA256 under the given server secret.
+//
+// The keys have enough entropy to not need a slow password hash, so this is
+// the recommended comparer for the keys authenticated on every request. A
+// leaked store is still useless without the secret.
+func NewHMACHashComparer(secret []byte) password.HashComparer {
+	return &hmacHashComparer{secret: secret}
+}
+
+func (h *hmacHashComparer) Compare(hashed, key string) error {
+	expected, err := h.Hash(key)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(hashed), []byte(expected)) {
+		return ErrInvalidKey
+	}
+
+	return nil
+}
+
+func (h *hmacHashComparer) Hash(key string) (string, error) {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(key))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// format formats and parses the keys of a prefix and an environment.
+type format struct {
+	prefix      string
+	environment string
+}
+
+// generate returns a new key and its id, reading the randomness from r.
+func (f format) generate(r io.Reader) (key, id string, err error) {
+	random, err := randomString(r, idLen+secretLen)
+	if err != nil {
+		return "", "", err
+	}
+
+	body := f.prefix + "_" + f.environment + "_" + random
+	return body + "_" + checksum(body), random[:idLen], nil
+}
+
+// parse validates the key and returns its id.
+func (f format) parse(key string) (string, error) {
+	i := strings.LastIndexByte(key, '_')
+	if i < 0 {
+		return "", ErrInvalidKey
+	}
+
+	body, sum := key[:i], key[i+1:]
+	parts := strings.Split(body, "_")
+	if len(parts) != 3 || parts[0] != f.prefix || parts[1] != f.environment {
+		return "", ErrInvalidKey
+	}
+
+	random := parts[2]
+	if len(random) != idLen+secretLen || !isBase62(random) {
+		return "", ErrInvalidKey
+	}
+
+	if sum != checksum(body) {
+		return "", ErrInvalidKey
+	}
+
+	return random[:idLen], nil
+}
+
+// checksum returns the fixed-width base62 encoded CRC-32 of s.
+func checksum(s string) string {
+	n := crc32.ChecksumIEEE([]byte(s))
+	b := make([]byte, checksumLen)
+	for i := checksumLen - 1; i >= 0; i-- {
+		b[i] = base62[n%62]
+		n /= 62
+	}
+
+	return string(b)
+}
+
+// randomString returns n uniformly random base62 characters. The bytes that
+// would bias the distribution are rejected.
+func randomString(r io.Reader, n int) (string, error) {
+	const limit = 256 - 256%len(base62)
+
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) < limit && len(out) < n {
+				out = append(out, base62[int(b)%len(base62)])
+			}
+		}
+	}
+
+	return string(out), nil
+}
+
+func isBase62(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(base62, s[i]) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
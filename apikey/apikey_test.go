@@ -0,0 +1,130 @@
+package apikey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/uniq"
+)
+
+func TestFormat(t *testing.T) {
+	f := format{prefix: DefaultPrefix, environment: DefaultEnvironment}
+
+	key, id, err := f.generate(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(key, "gk_live_"+id) {
+		t.Errorf("expected key with prefix %q but got %q", "gk_live_"+id, key)
+	}
+
+	if n := len(key); n != len("gk_live_")+idLen+secretLen+1+checksumLen {
+		t.Errorf("expected a fixed length key but got %d characters", n)
+	}
+
+	got, err := f.parse(key)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if got != id {
+		t.Errorf("expected id %q but got %q", id, got)
+	}
+
+	typo := []byte(key)
+	typo[len("gk_live_")+3] ^= 1
+	symbol := []byte(key)
+	symbol[len("gk_live_")] = '-'
+	invalid := []string{
+		"",
+		"gk_live",
+		string(typo),
+		strings.Replace(key, "gk_live_", "gk_test_", 1),
+		strings.Replace(key, "gk_live_", "xx_live_", 1),
+		key[:len(key)-1],
+		key + "_x",
+		string(symbol),
+	}
+
+	for _, k := range invalid {
+		if _, err := f.parse(k); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidKey, k, err)
+		}
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	// 255 is above the rejection limit and must be skipped.
+	r := bytes.NewReader([]byte{0, 255, 61, 62, 255, 255, 255, 255})
+	s, err := randomString(r, 3)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if s != "0z0" {
+		t.Errorf("expected %q but got %q", "0z0", s)
+	}
+
+	if _, err := randomString(uniq.EOFReader(), 3); !errors.Is(err, io.EOF) {
+		t.Errorf("expected error %v but got %v", io.EOF, err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	if got := checksum(""); got != "000000" {
+		t.Errorf("expected %q but got %q", "000000", got)
+	}
+
+	if a, b := checksum("gk_live_a"), checksum("gk_live_b"); a == b || len(a) != checksumLen {
+		t.Errorf("expected distinct checksums but got %q and %q", a, b)
+	}
+}
+
+func TestHMACHashComparer(t *testing.T) {
+	h := NewHMACHashComparer([]byte("secret"))
+
+	hash, err := h.Hash("gk_live_key")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := h.Compare(hash, "gk_live_key"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := h.Compare(hash, "gk_live_other"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected error %v but got %v", ErrInvalidKey, err)
+	}
+
+	if err := NewHMACHashComparer([]byte("other")).Compare(hash, "gk_live_key"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected error %v but got %v", ErrInvalidKey, err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	r := Record{Scopes: []string{"orders:read", "orders:write"}}
+	if !r.HasScopes() || !r.HasScopes("orders:read", "orders:write") {
+		t.Errorf("expected the scopes to be granted")
+	}
+
+	if r.HasScopes("orders:read", "users:read") {
+		t.Errorf("expected the scope users:read not to be granted")
+	}
+
+	now := clock.StaticTime
+	if r.Expired(now) {
+		t.Errorf("expected a key without expiration to never expire")
+	}
+
+	r.ExpiresAt = now.Add(time.Second)
+	if r.Expired(now) || !r.Expired(now.Add(time.Second)) {
+		t.Errorf("expected the key to expire at %v", r.ExpiresAt)
+	}
+}
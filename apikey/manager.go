@@ -0,0 +1,218 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/password"
+	"github.com/josestg/gokit/uniq"
+)
+
+// DefaultTouchInterval is the default minimum interval between two updates
+// of the last use of a key.
+const DefaultTouchInterval = time.Minute
+
+// Option is an option to configure the Manager.
+type Option func(*Manager)
+
+// WithPrefix configures the product prefix of the keys. The default is
+// DefaultPrefix. The prefix must not contain an underscore.
+func WithPrefix(prefix string) Option {
+	return func(m *Manager) {
+		m.format.prefix = prefix
+	}
+}
+
+// WithEnvironment configures the environment of the keys, for example "live"
+// or "test". The default is DefaultEnvironment. The keys of the other
+// environments are rejected, so a test key never works in production. The
+// environment must not contain an underscore.
+func WithEnvironment(env string) Option {
+	return func(m *Manager) {
+		m.format.environment = env
+	}
+}
+
+// WithClock configures the clock used to create, expire and touch the keys.
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
+
+// WithRandom configures the source of the randomness of the keys. The
+// default is uniq.RandomReader.
+func WithRandom(f uniq.ReaderFactory) Option {
+	return func(m *Manager) {
+		m.random = f
+	}
+}
+
+// WithTouchInterval configures the minimum interval between two updates of
+// the last use of a key, which saves a store write on most requests. The
+// default is DefaultTouchInterval, zero updates it on every use.
+func WithTouchInterval(d time.Duration) Option {
+	return func(m *Manager) {
+		m.touchInterval = d
+	}
+}
+
+// Manager creates, authenticates, rotates and revokes the keys.
+type Manager struct {
+	store         Store
+	hasher        password.HashComparer
+	format        format
+	clock         clock.Clock
+	random        uniq.ReaderFactory
+	touchInterval time.Duration
+	header        string
+}
+
+// NewManager creates a new Manager that persists the keys in the store and
+// hashes them with the given comparer, usually NewHMACHashComparer.
+func NewManager(store Store, hasher password.HashComparer, opts ...Option) *Manager {
+	m := &Manager{
+		store:         store,
+		hasher:        hasher,
+		format:        format{prefix: DefaultPrefix, environment: DefaultEnvironment},
+		clock:         clock.UTC,
+		random:        uniq.RandomReader,
+		touchInterval: DefaultTouchInterval,
+		header:        DefaultHeader,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Create creates a new key with the given name and scopes. The key expires
+// after the ttl, or never if the ttl is zero.
+//
+// The returned key is the only copy of it, it must be shown to its owner
+// once and can not be recovered later.
+func (m *Manager) Create(ctx context.Context, name string, scopes []string, ttl time.Duration) (string, Record, error) {
+	key, record, err := m.newKey(name, scopes, ttl)
+	if err != nil {
+		return "", Record{}, err
+	}
+
+	if err := m.store.Save(ctx, record); err != nil {
+		return "", Record{}, err
+	}
+
+	return key, record, nil
+}
+
+// Authenticate returns the record of the key. It returns ErrInvalidKey for
+// the malformed, unknown or mismatched keys, ErrKeyRevoked and ErrKeyExpired.
+func (m *Manager) Authenticate(ctx context.Context, key string) (Record, error) {
+	id, err := m.format.parse(key)
+	if err != nil {
+		return Record{}, err
+	}
+
+	record, err := m.store.Find(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Record{}, ErrInvalidKey
+	}
+
+	if err != nil {
+		return Record{}, err
+	}
+
+	if err := m.hasher.Compare(record.Hash, key); err != nil {
+		return Record{}, ErrInvalidKey
+	}
+
+	now := m.clock.Now()
+	if record.Revoked {
+		return Record{}, ErrKeyRevoked
+	}
+
+	if record.Expired(now) {
+		return Record{}, ErrKeyExpired
+	}
+
+	if record.LastUsedAt.IsZero() || now.Sub(record.LastUsedAt) >= m.touchInterval {
+		if err := m.store.Touch(ctx, id, now); err != nil {
+			return Record{}, err
+		}
+
+		record.LastUsedAt = now
+	}
+
+	return record, nil
+}
+
+// Rotate replaces the key with a new key with the same name, scopes and
+// lifetime. The old key keeps working during the grace period, so the
+// clients can be redeployed with the new key without downtime.
+func (m *Manager) Rotate(ctx context.Context, id string, grace time.Duration) (string, Record, error) {
+	current, err := m.store.Find(ctx, id)
+	if err != nil {
+		return "", Record{}, err
+	}
+
+	if current.Revoked {
+		return "", Record{}, ErrKeyRevoked
+	}
+
+	now := m.clock.Now()
+	if current.Expired(now) {
+		return "", Record{}, ErrKeyExpired
+	}
+
+	var ttl time.Duration
+	if !current.ExpiresAt.IsZero() {
+		ttl = current.ExpiresAt.Sub(current.CreatedAt)
+	}
+
+	key, next, err := m.newKey(current.Name, current.Scopes, ttl)
+	if err != nil {
+		return "", Record{}, err
+	}
+
+	if err := m.store.Rotate(ctx, id, now.Add(grace), next); err != nil {
+		return "", Record{}, err
+	}
+
+	return key, next, nil
+}
+
+// Revoke revokes the key immediately.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	return m.store.Revoke(ctx, id)
+}
+
+// newKey generates a new key and its record.
+func (m *Manager) newKey(name string, scopes []string, ttl time.Duration) (string, Record, error) {
+	key, id, err := m.format.generate(m.random())
+	if err != nil {
+		return "", Record{}, err
+	}
+
+	hash, err := m.hasher.Hash(key)
+	if err != nil {
+		return "", Record{}, err
+	}
+
+	now := m.clock.Now()
+	record := Record{
+		ID:        id,
+		Name:      name,
+		Hash:      hash,
+		Scopes:    append([]string(nil), scopes...),
+		CreatedAt: now,
+	}
+
+	if ttl > 0 {
+		record.ExpiresAt = now.Add(ttl)
+	}
+
+	return key, record, nil
+}
@@ -0,0 +1,213 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/password/passwordtest"
+	"github.com/josestg/gokit/uniq"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock { return &fakeClock{now: clock.StaticTime} }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Add(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestManager(c clock.Clock, opts ...Option) (*Manager, *MemoryStore) {
+	store := NewMemoryStore()
+	opts = append([]Option{WithClock(c)}, opts...)
+	return NewManager(store, NewHMACHashComparer([]byte("secret")), opts...), store
+}
+
+func TestManager_CreateAndAuthenticate(t *testing.T) {
+	c := newFakeClock()
+	m, store := newTestManager(c)
+	ctx := context.Background()
+
+	key, created, err := m.Create(ctx, "billing", []string{"orders:read"}, time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if created.Hash == "" || created.Hash == key {
+		t.Errorf("expected the hash of the key to be stored but got %q", created.Hash)
+	}
+
+	if !created.ExpiresAt.Equal(c.Now().Add(time.Hour)) {
+		t.Errorf("expected expiration %v but got %v", c.Now().Add(time.Hour), created.ExpiresAt)
+	}
+
+	record, err := m.Authenticate(ctx, key)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if record.ID != created.ID || record.Name != "billing" || !record.HasScopes("orders:read") {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	stored, _ := store.Find(ctx, created.ID)
+	if !stored.LastUsedAt.Equal(c.Now()) {
+		t.Errorf("expected last use %v but got %v", c.Now(), stored.LastUsedAt)
+	}
+
+	c.Add(time.Second)
+	if _, err := m.Authenticate(ctx, key); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	stored, _ = store.Find(ctx, created.ID)
+	if !stored.LastUsedAt.Equal(c.Now().Add(-time.Second)) {
+		t.Errorf("expected the last use not to be updated within the touch interval but got %v", stored.LastUsedAt)
+	}
+
+	c.Add(DefaultTouchInterval)
+	if _, err := m.Authenticate(ctx, key); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	stored, _ = store.Find(ctx, created.ID)
+	if !stored.LastUsedAt.Equal(c.Now()) {
+		t.Errorf("expected last use %v but got %v", c.Now(), stored.LastUsedAt)
+	}
+
+	c.Add(time.Hour)
+	if _, err := m.Authenticate(ctx, key); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("expected error %v but got %v", ErrKeyExpired, err)
+	}
+}
+
+func TestManager_Authenticate_Invalid(t *testing.T) {
+	c := newFakeClock()
+	m, _ := newTestManager(c)
+	ctx := context.Background()
+
+	key, _, err := m.Create(ctx, "billing", nil, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// a well-formed key with a known id but another secret.
+	forged := key[:len("gk_live_")+idLen] + key[len("gk_live_")+idLen+1:len("gk_live_")+idLen+secretLen] + "0"
+	forged += "_" + checksum(forged)
+
+	// a well-formed key with an unknown id.
+	unknown, _, err := m.format.generate(uniq.RandomReader())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	test, _ := newTestManager(c, WithEnvironment("test"))
+	testKey, _, err := test.Create(ctx, "billing", nil, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for _, k := range []string{"", "gk_live_x_000000", key + "x", forged, unknown, testKey} {
+		if _, err := m.Authenticate(ctx, k); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidKey, k, err)
+		}
+	}
+
+	if _, err := m.Authenticate(ctx, key); err != nil {
+		t.Errorf("expected a key without expiration to be valid but got %v", err)
+	}
+
+	created, _ := m.format.parse(key)
+	if err := m.Revoke(ctx, created); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if _, err := m.Authenticate(ctx, key); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected error %v but got %v", ErrKeyRevoked, err)
+	}
+}
+
+func TestManager_Rotate(t *testing.T) {
+	c := newFakeClock()
+	m, _ := newTestManager(c)
+	ctx := context.Background()
+
+	oldKey, old, err := m.Create(ctx, "billing", []string{"orders:read"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Hour)
+	newKey, next, err := m.Rotate(ctx, old.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if next.ID == old.ID || next.Name != "billing" || !next.HasScopes("orders:read") {
+		t.Errorf("unexpected rotated record %+v", next)
+	}
+
+	if !next.ExpiresAt.Equal(c.Now().Add(24 * time.Hour)) {
+		t.Errorf("expected expiration %v but got %v", c.Now().Add(24*time.Hour), next.ExpiresAt)
+	}
+
+	for _, k := range []string{oldKey, newKey} {
+		if _, err := m.Authenticate(ctx, k); err != nil {
+			t.Errorf("expected both keys to be valid during the grace period but got %v", err)
+		}
+	}
+
+	c.Add(time.Hour)
+	if _, err := m.Authenticate(ctx, oldKey); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("expected error %v but got %v", ErrKeyExpired, err)
+	}
+
+	if _, err := m.Authenticate(ctx, newKey); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if _, _, err := m.Rotate(ctx, old.ID, time.Hour); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("expected error %v but got %v", ErrKeyExpired, err)
+	}
+
+	if err := m.Revoke(ctx, next.ID); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if _, _, err := m.Rotate(ctx, next.ID, time.Hour); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected error %v but got %v", ErrKeyRevoked, err)
+	}
+
+	if _, _, err := m.Rotate(ctx, "unknown", time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected error %v but got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestManager_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewManager(NewMemoryStore(), NewHMACHashComparer(nil), WithRandom(uniq.EOFReader))
+	if _, _, err := m.Create(ctx, "billing", nil, 0); err == nil {
+		t.Errorf("expected an error from the random source")
+	}
+
+	m = NewManager(NewMemoryStore(), passwordtest.NewHashComparer("", ""))
+	if _, _, err := m.Create(ctx, "billing", nil, 0); !errors.Is(err, passwordtest.ErrMismatch) {
+		t.Errorf("expected error %v but got %v", passwordtest.ErrMismatch, err)
+	}
+
+	m = NewManager(NewMemoryStore(), NewHMACHashComparer(nil), WithPrefix("acme"), WithEnvironment("test"))
+	key, _, err := m.Create(ctx, "billing", nil, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if _, err := m.Authenticate(ctx, key); err != nil || key[:len("acme_test_")] != "acme_test_" {
+		t.Errorf("expected a valid acme_test_ key but got %q, %v", key, err)
+	}
+}
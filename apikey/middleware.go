@@ -0,0 +1,74 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/josestg/gokit/httpx"
+)
+
+// DefaultHeader is the default request header of the keys.
+const DefaultHeader = "X-API-Key"
+
+// WithHeader configures the request header read by the Middleware. The
+// default is DefaultHeader.
+func WithHeader(name string) Option {
+	return func(m *Manager) {
+		m.header = name
+	}
+}
+
+// contextKey is the type of the context keys.
+type contextKey struct {
+	name string
+}
+
+var recordContextKey = &contextKey{"record"}
+
+// FromContext returns the record of the key authenticated by the Middleware.
+func FromContext(ctx context.Context) (Record, bool) {
+	r, ok := ctx.Value(recordContextKey).(Record)
+	return r, ok
+}
+
+// Middleware is a middleware that authenticates the requests with the key of
+// the manager's header, and requires the key to be granted all the given
+// scopes. The next handlers get the record of the key with FromContext.
+//
+// The failures are written as problem responses, 401 for the missing and
+// invalid keys, and 403 for the insufficient scopes, see httpx.WriteProblem.
+// The errors of the key store are returned.
+func Middleware(m *Manager, scopes ...string) httpx.Middleware {
+	return func(h httpx.Handler) httpx.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			key := strings.TrimSpace(r.Header.Get(m.header))
+			if key == "" {
+				return writeProblem(w, r, http.StatusUnauthorized, "missing API key")
+			}
+
+			record, err := m.Authenticate(r.Context(), key)
+			if errors.Is(err, ErrInvalidKey) || errors.Is(err, ErrKeyExpired) || errors.Is(err, ErrKeyRevoked) {
+				return writeProblem(w, r, http.StatusUnauthorized, err.Error())
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !record.HasScopes(scopes...) {
+				return writeProblem(w, r, http.StatusForbidden, ErrInsufficientScope.Error())
+			}
+
+			return h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), recordContextKey, record)))
+		}
+	}
+}
+
+// writeProblem writes the problem of the request.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) error {
+	p := httpx.NewProblem(status, detail)
+	p.Instance = r.URL.Path
+	return httpx.WriteProblem(w, p)
+}
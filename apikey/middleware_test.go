@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/gokit/httpx"
+)
+
+func TestMiddleware(t *testing.T) {
+	c := newFakeClock()
+	m, _ := newTestManager(c, WithHeader("X-Service-Key"))
+	ctx := context.Background()
+
+	handler := Middleware(m, "orders:read")(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		record, ok := FromContext(r.Context())
+		if !ok {
+			t.Fatalf("expected the record in the context")
+		}
+
+		w.Header().Set("X-Service", record.Name)
+		return nil
+	}))
+
+	key, _, err := m.Create(ctx, "billing", []string{"orders:read"}, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	readOnly, _, err := m.Create(ctx, "reports", []string{"reports:read"}, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	revoked, record, err := m.Create(ctx, "legacy", []string{"orders:read"}, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Revoke(ctx, record.ID); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"valid", key, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"invalid", key + "x", http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"insufficient scope", readOnly, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.key != "" {
+				req.Header.Set("X-Service-Key", tt.key)
+			}
+
+			rec := httptest.NewRecorder()
+			if err := handler.ServeHTTP(rec, req); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+
+			if tt.status == http.StatusOK && rec.Header().Get("X-Service") != "billing" {
+				t.Errorf("expected service billing but got %q", rec.Header().Get("X-Service"))
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateKey is returned by the stores when a key with the same id is
+// already stored.
+var ErrDuplicateKey = errors.New("apikey: duplicate key")
+
+// Store persists the keys.
+type Store interface {
+	// Save stores a new key, or returns ErrDuplicateKey.
+	Save(ctx context.Context, r Record) error
+	// Find returns the key with the given id, or ErrKeyNotFound.
+	Find(ctx context.Context, id string) (Record, error)
+	// Touch records the last use of the key.
+	Touch(ctx context.Context, id string, usedAt time.Time) error
+	// Rotate atomically expires the key at expiresAt, records next as its
+	// replacement and stores next. It returns ErrKeyRevoked when the key is
+	// revoked.
+	Rotate(ctx context.Context, id string, expiresAt time.Time, next Record) error
+	// Revoke revokes the key.
+	Revoke(ctx context.Context, id string) error
+}
+
+// MemoryStore is an in-memory Store.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]Record
+}
+
+// NewMemoryStore creates a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[r.ID]; ok {
+		return ErrDuplicateKey
+	}
+
+	s.keys[r.ID] = copyRecord(r)
+	return nil
+}
+
+func (s *MemoryStore) Find(_ context.Context, id string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.keys[id]
+	if !ok {
+		return Record{}, ErrKeyNotFound
+	}
+
+	return copyRecord(r), nil
+}
+
+func (s *MemoryStore) Touch(_ context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	// concurrent requests never move the last use backward.
+	if usedAt.After(r.LastUsedAt) {
+		r.LastUsedAt = usedAt
+		s.keys[id] = r
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Rotate(_ context.Context, id string, expiresAt time.Time, next Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	if r.Revoked {
+		return ErrKeyRevoked
+	}
+
+	if _, ok := s.keys[next.ID]; ok {
+		return ErrDuplicateKey
+	}
+
+	if r.ExpiresAt.IsZero() || expiresAt.Before(r.ExpiresAt) {
+		r.ExpiresAt = expiresAt
+	}
+
+	r.RotatedTo = next.ID
+	s.keys[id] = r
+	s.keys[next.ID] = copyRecord(next)
+	return nil
+}
+
+func (s *MemoryStore) Revoke(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	r.Revoked = true
+	s.keys[id] = r
+	return nil
+}
+
+// Len returns the number of the stored keys.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// copyRecord copies the record, so the callers can not modify the stored
+// scopes.
+func copyRecord(r Record) Record {
+	r.Scopes = append([]string(nil), r.Scopes...)
+	return r
+}
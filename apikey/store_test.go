@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	now := clock.StaticTime
+
+	scopes := []string{"orders:read"}
+	if err := s.Save(ctx, Record{ID: "a", Scopes: scopes}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	scopes[0] = "orders:write"
+	if err := s.Save(ctx, Record{ID: "a"}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected error %v but got %v", ErrDuplicateKey, err)
+	}
+
+	r, err := s.Find(ctx, "a")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !r.HasScopes("orders:read") {
+		t.Errorf("expected the stored scopes to be copied but got %v", r.Scopes)
+	}
+
+	if _, err := s.Find(ctx, "b"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected error %v but got %v", ErrKeyNotFound, err)
+	}
+
+	_ = s.Touch(ctx, "a", now)
+	_ = s.Touch(ctx, "a", now.Add(-time.Second))
+	if r, _ := s.Find(ctx, "a"); !r.LastUsedAt.Equal(now) {
+		t.Errorf("expected last use %v but got %v", now, r.LastUsedAt)
+	}
+
+	if err := s.Rotate(ctx, "a", now.Add(time.Hour), Record{ID: "b"}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if r, _ := s.Find(ctx, "a"); r.RotatedTo != "b" || !r.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected rotated record %+v", r)
+	}
+
+	// a rotation never extends the expiration.
+	if err := s.Rotate(ctx, "a", now.Add(2*time.Hour), Record{ID: "c"}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if r, _ := s.Find(ctx, "a"); !r.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected expiration %v but got %v", now.Add(time.Hour), r.ExpiresAt)
+	}
+
+	if err := s.Rotate(ctx, "a", now, Record{ID: "b"}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected error %v but got %v", ErrDuplicateKey, err)
+	}
+
+	if err := s.Revoke(ctx, "a"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := s.Rotate(ctx, "a", now, Record{ID: "d"}); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected error %v but got %v", ErrKeyRevoked, err)
+	}
+
+	for _, err := range []error{
+		s.Touch(ctx, "x", now),
+		s.Rotate(ctx, "x", now, Record{ID: "y"}),
+		s.Revoke(ctx, "x"),
+	} {
+		if !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("expected error %v but got %v", ErrKeyNotFound, err)
+		}
+	}
+
+	if n := s.Len(); n != 3 {
+		t.Errorf("expected 3 keys but got %d", n)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/josestg/gokit/jwtx"
+	"github.com/josestg/gokit/uniq"
+)
+
+// genkey generates a key pair. The private key is written to <kid>.pem, the
+// name LoadPEMKeysFromDir expects, and the public key to <kid>.pub.
+func (a *app) genkey(args []string) error {
+	fs := a.flags("genkey", "-dir <dir> [-type rsa|ec|ed25519] [-kid <kid>]")
+	dir := fs.String("dir", "", "the key directory (required)")
+	typ := fs.String("type", "rsa", "the key type: rsa, ec or ed25519")
+	kid := fs.String("kid", "", "the key id, a random uuid by default")
+	bits := fs.Int("bits", 2048, "the size of the rsa key")
+	curve := fs.String("curve", "P-256", "the curve of the ec key: P-256, P-384 or P-521")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	key, err := generateKey(*typ, *bits, *curve)
+	if err != nil {
+		return err
+	}
+
+	if *kid == "" {
+		if *kid, err = uniq.NewUUID(uniq.RandomReader).NextString(); err != nil {
+			return err
+		}
+	}
+
+	private, err := marshalPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(*dir, *kid+".pem")
+	if err := writeNewFile(path, private, 0o600); err != nil {
+		return err
+	}
+
+	block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+	if err := writeNewFile(filepath.Join(*dir, *kid+".pub"), block, 0o644); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(a.stdout, path)
+	return err
+}
+
+// jwks prints the public keys of the key directory as a JSON Web Key Set.
+func (a *app) jwks(args []string) error {
+	fs := a.flags("jwks", "-dir <dir>")
+	dir := fs.String("dir", "", "the key directory (required)")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	set, err := loadPublicKeys(*dir)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(a.stdout, set)
+}
+
+func generateKey(typ string, bits int, curve string) (crypto.Signer, error) {
+	switch typ {
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ec":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+
+		c, ok := curves[curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", curve)
+		}
+
+		return ecdsa.GenerateKey(c, rand.Reader)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", typ)
+}
+
+// marshalPrivateKey encodes RSA keys in PKCS#1, like the existing key
+// directories, and the other keys in PKCS#8.
+func marshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	if key, ok := key.(*rsa.PrivateKey); ok {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), nil
+	}
+
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), nil
+}
+
+// writeNewFile writes the file, it never overwrites an existing key.
+func writeNewFile(path string, b []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// loadPublicKeys loads the public keys of the directory, from both the
+// public and the private key files.
+func loadPublicKeys(dir string) (jwtx.JWKSet, error) {
+	keys, err := jwtx.LoadPublicPEMKeysFromDir(os.DirFS(dir))
+	if err != nil {
+		return jwtx.JWKSet{}, err
+	}
+
+	if len(keys) == 0 {
+		return jwtx.JWKSet{}, errors.New("no keys found in " + dir)
+	}
+
+	return keys.JWKSet()
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,107 @@
+// Command jwtx generates signing keys, and creates, verifies and inspects
+// tokens with the jwtx package.
+//
+// Usage:
+//
+//	jwtx genkey -dir keys -type ec -curve P-256
+//	echo '{"sub":"user"}' | jwtx sign -dir keys -ttl 15m
+//	jwtx verify -dir keys -aud api <token>
+//	jwtx verify -jwks jwks.json <token>
+//	jwtx decode <token>
+//	jwtx jwks -dir keys
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/josestg/gokit/clock"
+)
+
+// errUsage is returned when the command line is invalid, the usage is
+// already printed.
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage: jwtx <command> [flags] [args]
+
+Commands:
+  genkey  generate a RSA, EC or Ed25519 key pair into a key directory
+  sign    sign the JSON claims read from the standard input
+  verify  verify a token against a key directory or a JWKS file
+  decode  print the header and claims of a token without verifying it
+  jwks    print the public keys of a key directory as a JWKS
+
+Run 'jwtx <command> -h' for the flags of a command.
+`
+
+// app runs the commands with its standard streams and clock.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	clock  clock.Clock
+}
+
+func main() {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, clock: clock.UTC}
+	if err := a.run(os.Args[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(args []string) error {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(a.stderr, usage)
+		return errUsage
+	}
+
+	commands := map[string]func(args []string) error{
+		"genkey": a.genkey,
+		"sign":   a.sign,
+		"verify": a.verify,
+		"decode": a.decode,
+		"jwks":   a.jwks,
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(a.stderr, "jwtx: unknown command %q\n\n%s", args[0], usage)
+		return errUsage
+	}
+
+	return cmd(args[1:])
+}
+
+// flags creates the flag set of the command.
+func (a *app) flags(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(a.stderr, "Usage: jwtx %s %s\n\nFlags:\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parse parses the flags and expects the given number of arguments.
+func parse(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
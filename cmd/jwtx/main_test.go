@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/jwtx"
+)
+
+type testApp struct {
+	*app
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+}
+
+func newTestApp(stdin string) *testApp {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	return &testApp{
+		app:    &app{stdin: strings.NewReader(stdin), stdout: stdout, stderr: stderr, clock: clock.Static},
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+func mustRun(t *testing.T, a *testApp, args ...string) string {
+	t.Helper()
+	if err := a.run(args); err != nil {
+		t.Fatalf("expected no error but got %v, stderr: %s", err, a.stderr)
+	}
+
+	return a.stdout.String()
+}
+
+func TestGenkeyAndSign(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"-type", "rsa", "-kid", "rsa", "-bits", "1024"},
+		{"-type", "ec", "-kid", "ec", "-curve", "P-384"},
+		{"-type", "ed25519", "-kid", "ed"},
+	} {
+		mustRun(t, newTestApp(""), append([]string{"genkey", "-dir", dir}, args...)...)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "ec.pub")); err != nil {
+		t.Errorf("expected the public key to be written but got %v", err)
+	}
+
+	keys, err := jwtx.LoadPEMKeysFromDir(os.DirFS(dir))
+	if err != nil || len(keys) != 3 {
+		t.Fatalf("expected 3 keys but got %d, %v", len(keys), err)
+	}
+
+	rsaDir := t.TempDir()
+	mustRun(t, newTestApp(""), "genkey", "-dir", rsaDir, "-kid", "key-1", "-bits", "1024")
+	if rsaKeys, err := jwtx.LoadRSAPEMKeysFromDir(os.DirFS(rsaDir)); err != nil || rsaKeys["key-1"] == nil {
+		t.Errorf("expected the rsa key to be loaded but got %v", err)
+	}
+
+	if err := newTestApp("").run([]string{"genkey", "-dir", dir, "-kid", "ec"}); err == nil {
+		t.Errorf("expected the existing key not to be overwritten")
+	}
+
+	if err := newTestApp(`{"sub":"user"}`).run([]string{"sign", "-dir", dir}); err == nil {
+		t.Errorf("expected an error when the kid is ambiguous")
+	}
+
+	token := strings.TrimSpace(mustRun(t, newTestApp(`{"sub":"user","aud":"api"}`), "sign", "-dir", dir, "-kid", "ec", "-ttl", "1h"))
+
+	out := mustRun(t, newTestApp(""), "verify", "-dir", dir, "-aud", "api", token)
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if claims["sub"] != "user" || claims["exp"] != float64(clock.StaticTime.Unix()+3600) {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	// the verify-only deployments only have the public keys.
+	publicDir := t.TempDir()
+	for _, kid := range []string{"ec", "ed"} {
+		b, err := os.ReadFile(filepath.Join(dir, kid+".pub"))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(publicDir, kid+".pub"), b, 0o600); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+	}
+
+	mustRun(t, newTestApp(""), "verify", "-dir", publicDir, "-aud", "api", token)
+
+	err = newTestApp("").run([]string{"verify", "-dir", dir, "-aud", "web", token})
+	if !errors.Is(err, jwtx.ErrWrongAudience) {
+		t.Errorf("expected %v but got %v", jwtx.ErrWrongAudience, err)
+	}
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, []byte(mustRun(t, newTestApp(""), "jwks", "-dir", dir)), 0o600); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	mustRun(t, newTestApp(""), "verify", "-jwks", jwksFile, token)
+}
+
+func TestDecode(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, newTestApp(""), "genkey", "-dir", dir, "-type", "ed25519", "-kid", "ed")
+	token := strings.TrimSpace(mustRun(t, newTestApp(`{"sub":"user","nbf":1667264400}`), "sign", "-dir", dir, "-ttl", "-1h"))
+
+	out := mustRun(t, newTestApp(""), "decode", token)
+	for _, want := range []string{
+		`"kid": "ed"`,
+		`"sub": "user"`,
+		"iat       2022-11-01T00:00:00Z (now)",
+		"nbf       2022-11-01T01:00:00Z (not valid for another 1h0m0s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q but got:\n%s", want, out)
+		}
+	}
+
+	if err := newTestApp("").run([]string{"decode", "not-a-token"}); err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := clock.StaticTime
+	tests := []struct {
+		name     string
+		offset   int64
+		expected string
+	}{
+		{name: "exp", offset: -90, expected: "expired 1m30s ago"},
+		{name: "exp", offset: 60, expected: "in 1m0s"},
+		{name: "iat", offset: -60, expected: "1m0s ago"},
+		{name: "nbf", offset: 60, expected: "not valid for another 1m0s"},
+	}
+
+	for _, tt := range tests {
+		got := relative(now, now.Add(time.Duration(tt.offset)*time.Second), tt.name)
+		if got != tt.expected {
+			t.Errorf("expected %q but got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"verify", "token"}, {"genkey"}} {
+		a := newTestApp("")
+		if err := a.run(args); !errors.Is(err, errUsage) {
+			t.Errorf("expected %v for %v but got %v", errUsage, args, err)
+		}
+
+		if a.stderr.Len() == 0 {
+			t.Errorf("expected the usage to be printed for %v", args)
+		}
+	}
+}
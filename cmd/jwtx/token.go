@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/jwtx"
+)
+
+// sign signs the JSON claims read from the standard input.
+func (a *app) sign(args []string) error {
+	fs := a.flags("sign", "-dir <dir> [-kid <kid>] [-ttl <duration>] < claims.json")
+	dir := fs.String("dir", "", "the key directory (required)")
+	kid := fs.String("kid", "", "the signing key id, required when the directory has several keys")
+	ttl := fs.Duration("ttl", 0, "the token lifetime, sets the exp claim when it is missing")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	keys, err := jwtx.LoadPEMKeysFromDir(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	if *kid == "" {
+		if len(keys) != 1 {
+			return fmt.Errorf("found %d keys in %s, choose one with -kid", len(keys), *dir)
+		}
+
+		for k := range keys {
+			*kid = k
+		}
+	}
+
+	key, ok := keys[*kid]
+	if !ok {
+		return fmt.Errorf("key %q not found in %s", *kid, *dir)
+	}
+
+	claims := jwt.MapClaims{}
+	dec := json.NewDecoder(a.stdin)
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return fmt.Errorf("decoding claims: %w", err)
+	}
+
+	now := a.clock.Now()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+
+	if _, ok := claims["exp"]; !ok && *ttl > 0 {
+		claims["exp"] = now.Add(*ttl).Unix()
+	}
+
+	signer := jwtx.NewKeyManager(a.clock)
+	if err := signer.Add(jwtx.ManagedKey{KID: *kid, Key: key}); err != nil {
+		return err
+	}
+
+	token, err := signer.Tokenize(claims)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(a.stdout, token)
+	return err
+}
+
+// verify verifies the token and prints its claims.
+func (a *app) verify(args []string) error {
+	fs := a.flags("verify", "(-dir <dir> | -jwks <file>) [-iss <issuers>] [-aud <audience>] <token>")
+	dir := fs.String("dir", "", "the key directory, the public key files are enough")
+	jwksFile := fs.String("jwks", "", "the JWKS file")
+	iss := fs.String("iss", "", "the comma-separated accepted issuers")
+	aud := fs.String("aud", "", "the required audience")
+	leeway := fs.Duration("leeway", 0, "the tolerated clock skew")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	if (*dir == "") == (*jwksFile == "") {
+		fs.Usage()
+		return errUsage
+	}
+
+	opts := []jwtx.Option{jwtx.WithClock(a.clock), jwtx.WithLeeway(*leeway), jwtx.WithAudience(*aud)}
+	if *iss != "" {
+		opts = append(opts, jwtx.WithIssuer(strings.Split(*iss, ",")...))
+	}
+
+	var (
+		verifier jwtx.Tokenizer
+		err      error
+	)
+
+	if *dir != "" {
+		var set jwtx.JWKSet
+		set, err = loadPublicKeys(*dir)
+		verifier = jwtx.NewJWKSTokenizer(set, opts...)
+	} else {
+		verifier, err = loadJWKS(*jwksFile, opts...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	claims := jwt.MapClaims{}
+	if err := verifier.Detokenize(strings.TrimSpace(fs.Arg(0)), claims); err != nil {
+		return err
+	}
+
+	return writeJSON(a.stdout, claims)
+}
+
+// decode prints the header and claims of the token without verifying it.
+func (a *app) decode(args []string) error {
+	fs := a.flags("decode", "<token>")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	parts := strings.Split(strings.TrimSpace(fs.Arg(0)), ".")
+	if len(parts) != 3 && len(parts) != 5 {
+		return errors.New("token must have 3 or 5 segments")
+	}
+
+	var header map[string]interface{}
+	if err := decodeSegmentJSON(parts[0], &header); err != nil {
+		return fmt.Errorf("decoding header: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(a.stdout, "The signature is NOT verified, use 'jwtx verify' to verify it.\n\nHeader:")
+	if err := writeJSON(a.stdout, header); err != nil {
+		return err
+	}
+
+	if len(parts) == 5 {
+		_, err := fmt.Fprintln(a.stdout, "\nClaims are encrypted.")
+		return err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegmentJSON(parts[1], &claims); err != nil {
+		return fmt.Errorf("decoding claims: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(a.stdout, "\nClaims:")
+	if err := writeJSON(a.stdout, claims); err != nil {
+		return err
+	}
+
+	return a.printTimes(a.stdout, claims)
+}
+
+// printTimes prints the time claims in a human-readable form.
+func (a *app) printTimes(w io.Writer, claims map[string]interface{}) error {
+	names := make([]string, 0, 4)
+	for _, name := range []string{"iat", "nbf", "exp", "auth_time"} {
+		if _, ok := claims[name].(json.Number); ok {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(w, "\nTimes:")
+
+	now := a.clock.Now()
+	for _, name := range names {
+		seconds, err := claims[name].(json.Number).Float64()
+		if err != nil {
+			return fmt.Errorf("claim %s: %w", name, err)
+		}
+
+		t := time.Unix(int64(seconds), 0).UTC()
+		_, _ = fmt.Fprintf(w, "  %-9s %s (%s)\n", name, t.Format(time.RFC3339), relative(now, t, name))
+	}
+
+	return nil
+}
+
+// relative describes t relatively to now.
+func relative(now, t time.Time, name string) string {
+	d := t.Sub(now).Round(time.Second)
+	switch {
+	case d > 0 && name == "nbf":
+		return "not valid for another " + d.String()
+	case d > 0:
+		return "in " + d.String()
+	case name == "exp":
+		return "expired " + (-d).String() + " ago"
+	case d == 0:
+		return "now"
+	default:
+		return (-d).String() + " ago"
+	}
+}
+
+// loadJWKS loads a verifier from the JSON Web Key Set file.
+func loadJWKS(path string, opts ...jwtx.Option) (jwtx.Tokenizer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwtx.JWKSet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	return jwtx.NewJWKSTokenizer(set, opts...), nil
+}
+
+func decodeSegmentJSON(segment string, v interface{}) error {
+	b, err := jwt.DecodeSegment(segment)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
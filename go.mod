@@ -8,3 +8,5 @@ require (
 	github.com/josestg/httprouter v1.1.0
 	golang.org/x/crypto v0.2.0
 )
+
+require golang.org/x/sys v0.2.0 // indirect
@@ -0,0 +1,361 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/container"
+)
+
+// ErrShuttingDown is reported by the readiness check once shutdown begins.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Checker knows how to check the health of a dependency.
+type Checker interface {
+	// Check returns a non-nil error when the dependency is unhealthy.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is an adapter that enabled an ordinary function
+// to implement the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls fn(ctx)
+func (fn CheckerFunc) Check(ctx context.Context) error { return fn(ctx) }
+
+// Status is the status of a check or a report.
+type Status string
+
+const (
+	StatusUp       Status = "up"       // Everything is healthy.
+	StatusDegraded Status = "degraded" // Only non-critical checks are failing.
+	StatusDown     Status = "down"     // At least one critical check is failing.
+)
+
+// Result is the result of a single check.
+type Result struct {
+	Status    Status        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Critical  bool          `json:"critical"`
+	Duration  time.Duration `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Report is the aggregated result of the checks.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// CheckOption is an option to configure a registered check.
+type CheckOption func(*check)
+
+// WithTimeout configures the timeout of a check.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// NonCritical marks a check as non-critical, its failure degrades the report
+// without failing it.
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Liveness includes a check in the liveness report as well as in the
+// readiness report.
+func Liveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// check is a registered check with its cached result.
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	critical bool
+	liveness bool
+
+	mu      sync.Mutex
+	result  Result
+	running *flight
+}
+
+// flight is a run of a check shared by the callers that wait for it.
+type flight struct {
+	done   chan struct{}
+	result Result
+}
+
+// Option is an option to configure the Registry.
+type Option func(*Registry)
+
+// WithClock configures the clock used to time and cache the checks.
+func WithClock(c clock.Clock) Option {
+	return func(r *Registry) {
+		r.clock = c
+	}
+}
+
+// WithRefreshInterval configures how long a check result is cached before the
+// check runs again.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(r *Registry) {
+		r.interval = interval
+	}
+}
+
+// WithDefaultTimeout configures the timeout of checks registered without
+// WithTimeout.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = timeout
+	}
+}
+
+// WithDrainDelay configures how long a runner wrapped with Wrap keeps serving
+// once the registry is draining, so the load balancers have time to see the
+// failing readiness report before the runner shuts down.
+func WithDrainDelay(delay time.Duration) Option {
+	return func(r *Registry) {
+		r.drainDelay = delay
+	}
+}
+
+// Registry is a registry of named checks.
+type Registry struct {
+	clock      clock.Clock
+	interval   time.Duration
+	timeout    time.Duration
+	drainDelay time.Duration
+	draining   int32
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		clock:    clock.UTC,
+		interval: 5 * time.Second,
+		timeout:  time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Register registers a named check. The check is critical unless it is
+// registered with NonCritical. Register panics if the name is already taken.
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{
+		name:     name,
+		checker:  checker,
+		timeout:  r.timeout,
+		critical: true,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.checks {
+		if existing.name == name {
+			panic("health: check " + name + " already registered")
+		}
+	}
+
+	r.checks = append(r.checks, c)
+}
+
+// Drain makes the readiness report fail, so load balancers stop sending new
+// requests while in-flight requests are drained.
+func (r *Registry) Drain() { atomic.StoreInt32(&r.draining, 1) }
+
+// Draining reports whether Drain has been called.
+func (r *Registry) Draining() bool { return atomic.LoadInt32(&r.draining) == 1 }
+
+// Liveness runs the liveness checks and returns the report.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.report(ctx, func(c *check) bool { return c.liveness })
+}
+
+// Readiness runs every check and returns the report. The report is down once
+// the registry is draining.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	report := r.report(ctx, func(*check) bool { return true })
+	if r.Draining() {
+		report.Status = StatusDown
+		report.Checks["shutdown"] = Result{
+			Status:    StatusDown,
+			Error:     ErrShuttingDown.Error(),
+			Critical:  true,
+			CheckedAt: r.clock.Now(),
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) report(ctx context.Context, include func(*check) bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+		if res.Status == StatusUp {
+			continue
+		}
+
+		if c.critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run runs the check unless its cached result is still fresh.
+//
+// The check runs once on a detached context bounded by its timeout, since its
+// result is shared by every caller until the next refresh. A caller whose
+// context is done stops waiting and gets a down result, which is not cached,
+// so one disconnecting client does not fail the check for the others.
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	now := r.clock.Now()
+	if !c.result.CheckedAt.IsZero() && now.Sub(c.result.CheckedAt) < r.interval {
+		res := c.result
+		c.mu.Unlock()
+		return res
+	}
+
+	f := c.running
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		c.running = f
+		go r.execute(c, f, now)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result
+	case <-ctx.Done():
+		return Result{
+			Status:    StatusDown,
+			Error:     ctx.Err().Error(),
+			Critical:  c.critical,
+			Duration:  r.clock.Now().Sub(now),
+			CheckedAt: now,
+		}
+	}
+}
+
+// execute runs the check and caches its result.
+func (r *Registry) execute(c *check, f *flight, now time.Time) {
+	checkCtx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	errChannel := make(chan error, 1)
+	go func() {
+		errChannel <- c.checker.Check(checkCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChannel:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+	}
+
+	f.result = Result{
+		Status:    StatusUp,
+		Critical:  c.critical,
+		Duration:  r.clock.Now().Sub(now),
+		CheckedAt: now,
+	}
+
+	if err != nil {
+		f.result.Status = StatusDown
+		f.result.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.result = f.result
+	c.running = nil
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// Wrap wraps the runner, so the registry starts draining as soon as the
+// runner is asked to shut down or terminate. A graceful shutdown waits for
+// the drain delay, or until its context is done, before shutting down the
+// runner, see WithDrainDelay.
+//
+//	err := container.Execute(ctx, registry.Wrap(server))
+func (r *Registry) Wrap(runner container.Runner) container.Runner {
+	return &drainingRunner{Runner: runner, registry: r}
+}
+
+// drainingRunner drains the registry before shutting down the runner.
+type drainingRunner struct {
+	container.Runner
+	registry *Registry
+}
+
+func (d *drainingRunner) Shutdown(ctx context.Context) error {
+	d.registry.Drain()
+
+	timer := time.NewTimer(d.registry.drainDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	return d.Runner.Shutdown(ctx)
+}
+
+func (d *drainingRunner) Terminate() error {
+	d.registry.Drain()
+	return d.Runner.Terminate()
+}
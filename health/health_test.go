@@ -0,0 +1,287 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRegistry_Readiness(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static))
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusUp {
+		t.Fatalf("expected status %q but got %q", StatusUp, report.Status)
+	}
+
+	r.Register("cache", CheckerFunc(func(ctx context.Context) error { return errors.New("cache down") }), NonCritical())
+	report = r.Readiness(context.Background())
+	if report.Status != StatusDegraded {
+		t.Fatalf("expected status %q but got %q", StatusDegraded, report.Status)
+	}
+
+	if got := report.Checks["cache"].Error; got != "cache down" {
+		t.Errorf("expected error %q but got %q", "cache down", got)
+	}
+
+	r.Register("queue", CheckerFunc(func(ctx context.Context) error { return errors.New("queue down") }))
+	report = r.Readiness(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("expected status %q but got %q", StatusDown, report.Status)
+	}
+
+	if !report.Checks["queue"].Critical || report.Checks["cache"].Critical {
+		t.Errorf("expected criticality to be reported per check")
+	}
+}
+
+func TestRegistry_Liveness(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static))
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+	r.Register("deadlock", CheckerFunc(func(ctx context.Context) error { return nil }), Liveness())
+
+	report := r.Liveness(context.Background())
+	if report.Status != StatusUp {
+		t.Fatalf("expected status %q but got %q", StatusUp, report.Status)
+	}
+
+	if _, ok := report.Checks["db"]; ok {
+		t.Errorf("expected readiness only checks to be excluded from liveness")
+	}
+
+	if _, ok := report.Checks["deadlock"]; !ok {
+		t.Errorf("expected liveness checks to be included")
+	}
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	c := &fakeClock{now: clock.StaticTime}
+	r := NewRegistry(WithClock(c), WithRefreshInterval(time.Minute))
+
+	var calls int32
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+
+	r.Readiness(context.Background())
+	c.Add(30 * time.Second)
+	r.Readiness(context.Background())
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected cached result within interval, got %d calls", n)
+	}
+
+	c.Add(30 * time.Second)
+	r.Readiness(context.Background())
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected the check to refresh after interval, got %d calls", n)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static))
+	r.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), WithTimeout(10*time.Millisecond))
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("expected status %q but got %q", StatusDown, report.Status)
+	}
+
+	if got := report.Checks["slow"].Error; got != context.DeadlineExceeded.Error() {
+		t.Errorf("expected error %q but got %q", context.DeadlineExceeded, got)
+	}
+}
+
+func TestRegistry_Canceled(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static), WithRefreshInterval(time.Minute))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	report := r.Readiness(ctx)
+	close(release)
+	if got := report.Checks["db"].Error; got != context.Canceled.Error() {
+		t.Fatalf("expected error %q but got %q", context.Canceled, got)
+	}
+
+	report = r.Readiness(context.Background())
+	if report.Status != StatusUp {
+		t.Errorf("expected the canceled result not to be cached but got %q", report.Status)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected the running check to be shared, got %d calls", n)
+	}
+}
+
+func TestRegistry_DeadlineExceeded(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static), WithRefreshInterval(time.Minute))
+
+	release := make(chan struct{})
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	// every waiting caller gives up on its own deadline.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			report := r.Readiness(ctx)
+			if got := report.Checks["db"].Error; got != context.DeadlineExceeded.Error() {
+				t.Errorf("expected error %q but got %q", context.DeadlineExceeded, got)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(release)
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusUp {
+		t.Errorf("expected the expired result not to be cached but got %q", report.Status)
+	}
+}
+
+func TestRegistry_Register_Duplicate(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+}
+
+type runnerMock struct {
+	registry *Registry
+	drained  bool
+}
+
+func (m *runnerMock) Run() error { return nil }
+
+func (m *runnerMock) Shutdown(ctx context.Context) error {
+	m.drained = m.registry.Draining()
+	return nil
+}
+
+func (m *runnerMock) Terminate() error { return nil }
+
+func TestRegistry_Wrap(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static))
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	runner := &runnerMock{registry: r}
+	wrapped := r.Wrap(runner)
+
+	if report := r.Readiness(context.Background()); report.Status != StatusUp {
+		t.Fatalf("expected status %q but got %q", StatusUp, report.Status)
+	}
+
+	if err := wrapped.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !runner.drained {
+		t.Errorf("expected the registry to drain before the runner shuts down")
+	}
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("expected status %q but got %q", StatusDown, report.Status)
+	}
+
+	if got := report.Checks["shutdown"].Error; got != ErrShuttingDown.Error() {
+		t.Errorf("expected error %q but got %q", ErrShuttingDown, got)
+	}
+
+	if report := r.Liveness(context.Background()); report.Status != StatusUp {
+		t.Errorf("expected liveness to stay up while draining, got %q", report.Status)
+	}
+}
+
+func TestRegistry_Wrap_DrainDelay(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static), WithDrainDelay(100*time.Millisecond))
+	runner := &runnerMock{registry: r}
+	wrapped := r.Wrap(runner)
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- wrapped.Shutdown(context.Background()) }()
+
+	// the readiness report fails while the runner keeps serving.
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("expected the runner to wait for the drain delay")
+	default:
+	}
+
+	rec := httptest.NewRecorder()
+	_ = ReadinessHandler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while draining but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the shutdown to wait for the drain delay, took %v", elapsed)
+	}
+}
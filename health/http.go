@@ -0,0 +1,41 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/josestg/gokit/encoding/json"
+	"github.com/josestg/gokit/httpx"
+)
+
+// LivenessHandler returns a handler that serves the liveness report.
+func LivenessHandler(r *Registry) httpx.Handler {
+	return httpx.HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+		return writeReport(w, r.Liveness(req.Context()))
+	})
+}
+
+// ReadinessHandler returns a handler that serves the readiness report.
+func ReadinessHandler(r *Registry) httpx.Handler {
+	return httpx.HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+		return writeReport(w, r.Readiness(req.Context()))
+	})
+}
+
+// Mount registers the liveness and readiness handlers on /livez and /readyz.
+func Mount(mux *httpx.ServeMux, r *Registry, middlewares ...httpx.Middleware) {
+	mux.Handle(http.MethodGet, "/livez", LivenessHandler(r), middlewares...)
+	mux.Handle(http.MethodGet, "/readyz", ReadinessHandler(r), middlewares...)
+}
+
+// writeReport writes the report as JSON, a down report responds with 503.
+func writeReport(w http.ResponseWriter, report Report) error {
+	status := http.StatusOK
+	if report.Status == StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	return json.Encoder.Encode(w, report)
+}
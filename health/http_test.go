@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/httpx"
+)
+
+func TestMount(t *testing.T) {
+	r := NewRegistry(WithClock(clock.Static))
+	r.Register("db", CheckerFunc(func(ctx context.Context) error { return errors.New("db down") }))
+
+	mux := httpx.NewServeMux()
+	Mount(mux, r)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected json content type but got %q", got)
+	}
+
+	var report Report
+	if err := stdjson.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if report.Status != StatusDown {
+		t.Errorf("expected status %q but got %q", StatusDown, report.Status)
+	}
+
+	if got := report.Checks["db"]; got.Status != StatusDown || got.Error != "db down" {
+		t.Errorf("expected failing db check detail but got %+v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/josestg/gokit/encoding"
+	"github.com/josestg/gokit/encoding/json"
+	"github.com/josestg/gokit/validate"
+)
+
+// Bind decodes the request body into v and validates it with validate.Value,
+// so a slice or map body validates each of its elements.
+//
+// The body is decoded with the decoder from the request context, falling back
+// to JSON when the context has no decoder. A body that can not be decoded is
+// reported as a 400 Problem, and an invalid v as a 422 Problem listing every
+// invalid field.
+func Bind(r *http.Request, v any) error {
+	err := encoding.DecoderFromContext(r.Context()).Decode(r.Body, v)
+	if errors.Is(err, encoding.ErrNoDecoder) {
+		err = json.Decoder.Decode(r.Body, v)
+	}
+
+	if err != nil {
+		return NewProblem(http.StatusBadRequest, "malformed request body: "+err.Error())
+	}
+
+	return Validate(v)
+}
+
+// Validate validates v with validate.Value and reports the invalid fields as
+// a 422 Problem.
+func Validate(v any) error {
+	err := validate.Value(v)
+	if err == nil {
+		return nil
+	}
+
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	p := NewProblem(http.StatusUnprocessableEntity, "the request has invalid fields")
+	p.InvalidParams = errs
+	return p
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	stdjson "encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/gokit/encoding"
+	"github.com/josestg/gokit/encoding/xml"
+)
+
+type createUser struct {
+	Name  string `json:"name" xml:"name" validate:"required,min=3"`
+	Email string `json:"email" xml:"email" validate:"required,email"`
+}
+
+func bindHandler(v *createUser) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if err := Bind(r, v); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+}
+
+func TestBind(t *testing.T) {
+	var v createUser
+	handler := NewChain(RenderProblems).Then(bindHandler(&v))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"gopher","email":"gopher@example.com"}`))
+	if err := handler.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d but got %d", http.StatusCreated, rec.Code)
+	}
+
+	if v.Name != "gopher" || v.Email != "gopher@example.com" {
+		t.Errorf("expected the body to be decoded but got %+v", v)
+	}
+}
+
+func TestBind_ContextDecoder(t *testing.T) {
+	var v createUser
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`<createUser><name>gopher</name><email>gopher@example.com</email></createUser>`))
+	req = req.WithContext(encoding.WithDecoder(req.Context(), xml.Decoder.Driver()))
+
+	if err := Bind(req, &v); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if v.Name != "gopher" {
+		t.Errorf("expected the body to be decoded with the context decoder but got %+v", v)
+	}
+}
+
+func TestBind_Invalid(t *testing.T) {
+	var v createUser
+	handler := NewChain(RenderProblems).Then(bindHandler(&v))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"go","email":""}`))
+	if err := handler.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("expected problem content type but got %q", got)
+	}
+
+	var p Problem
+	if err := stdjson.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if p.Status != http.StatusUnprocessableEntity || p.Instance != "/users" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	if len(p.InvalidParams) != 2 || p.InvalidParams[0].Field != "name" || p.InvalidParams[1].Field != "email" {
+		t.Errorf("expected every invalid field to be listed but got %+v", p.InvalidParams)
+	}
+}
+
+func TestBind_Slice(t *testing.T) {
+	var v []createUser
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`[{"name":"gopher","email":"gopher@example.com"}]`))
+	if err := Bind(req, &v); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(v) != 1 || v[0].Name != "gopher" {
+		t.Errorf("expected the body to be decoded but got %+v", v)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`[{"name":"gopher","email":"gopher@example.com"},{"name":"go"}]`))
+	var p *Problem
+	if err := Bind(req, &v); !errors.As(err, &p) || p.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a 422 problem but got %v", err)
+	}
+
+	if len(p.InvalidParams) != 2 || p.InvalidParams[0].Field != "[1].name" {
+		t.Errorf("expected the invalid elements to be listed but got %+v", p.InvalidParams)
+	}
+}
+
+func TestBind_Malformed(t *testing.T) {
+	var v createUser
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{`))
+
+	var p *Problem
+	if err := Bind(req, &v); !errors.As(err, &p) || p.Status != http.StatusBadRequest {
+		t.Fatalf("expected a bad request problem but got %v", err)
+	}
+}
+
+func TestRenderProblems_OtherErrors(t *testing.T) {
+	expected := errors.New("boom")
+	handler := NewChain(RenderProblems).ThenFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return expected
+	})
+
+	err := handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != expected {
+		t.Errorf("expected %v but got %v", expected, err)
+	}
+}
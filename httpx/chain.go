@@ -1,20 +1,111 @@
 package httpx
 
 import (
+	"errors"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var (
+	// ErrMiddlewareNotFound is returned when a named middleware is not found in the chain.
+	ErrMiddlewareNotFound = errors.New("middleware not found")
+
+	// ErrMiddlewareExists is returned when a named middleware is already registered in the chain.
+	ErrMiddlewareExists = errors.New("middleware already exists")
+
+	// ErrEmptyMiddlewareName is returned when a named middleware has an empty name.
+	ErrEmptyMiddlewareName = errors.New("empty middleware name")
+
+	// ErrNilPredicate is returned when a conditional middleware has a nil predicate.
+	ErrNilPredicate = errors.New("nil middleware predicate")
 )
 
 // Middleware is a function that wraps a Handler.
 type Middleware func(h Handler) HandlerFunc
 
+// Predicate reports whether a request matches a condition.
+type Predicate func(r *http.Request) bool
+
+// PathPrefix matches requests whose URL path starts with prefix.
+func PathPrefix(prefix string) Predicate {
+	return func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, prefix) }
+}
+
+// MethodIs matches requests with one of the given methods.
+func MethodIs(methods ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if r.Method == m {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// HeaderIs matches requests that carry the header key with the given value.
+// An empty value matches any request that carries the header.
+func HeaderIs(key, value string) Predicate {
+	return func(r *http.Request) bool {
+		values, ok := r.Header[http.CanonicalHeaderKey(key)]
+		if !ok {
+			return false
+		}
+
+		if value == "" {
+			return true
+		}
+
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// link is a single middleware in a chain.
+type link struct {
+	name  string
+	label string
+	cond  string
+	mw    Middleware
+}
+
+// describe returns a human-readable description of the link.
+func (l link) describe() string {
+	name := l.name
+	if name == "" {
+		name = l.label
+	}
+
+	if name == "" {
+		name = funcName(l.mw)
+	}
+
+	if l.cond != "" {
+		name += " (" + l.cond + ")"
+	}
+
+	return name
+}
+
 // Chain is a chain of middleware.
+//
+// A Chain is immutable, every method that modifies the chain returns a new
+// copy, so derived chains are safe to create concurrently.
 type Chain struct {
-	middleware []Middleware
+	links []link
 }
 
 // NewChain creates a new chain of middleware.
 func NewChain(middleware ...Middleware) *Chain {
-	return &Chain{middleware: middleware}
+	return (&Chain{}).Extend(middleware...)
 }
 
 // Then chains the middleware with h and returns a new Handler.
@@ -30,7 +121,104 @@ func (c *Chain) ThenFunc(f HandlerFunc) Handler {
 // Extend extends existing chain with new middlewares, and returns a new copy of
 // chain.
 func (c *Chain) Extend(middleware ...Middleware) *Chain {
-	return &Chain{middleware: append(c.middleware, middleware...)}
+	links := c.clone(len(middleware))
+	for _, mw := range middleware {
+		links = append(links, link{mw: mw})
+	}
+
+	return &Chain{links: links}
+}
+
+// Use extends existing chain with a named middleware, and returns a new copy of
+// chain. The name can later be used to find, insert around or remove the
+// middleware. Like InsertBefore and InsertAfter, Use returns
+// ErrEmptyMiddlewareName if the name is empty and ErrMiddlewareExists if the
+// name is already registered in the chain.
+func (c *Chain) Use(name string, middleware Middleware) (*Chain, error) {
+	if name == "" {
+		return nil, ErrEmptyMiddlewareName
+	}
+
+	if c.index(name) >= 0 {
+		return nil, ErrMiddlewareExists
+	}
+
+	links := c.clone(1)
+	return &Chain{links: append(links, link{name: name, mw: middleware})}, nil
+}
+
+// When extends existing chain with middlewares that only applied to requests
+// that match the predicate, and returns a new copy of chain. It returns
+// ErrNilPredicate if the predicate is nil.
+func (c *Chain) When(p Predicate, middleware ...Middleware) (*Chain, error) {
+	if p == nil {
+		return nil, ErrNilPredicate
+	}
+
+	return c.conditional("when", p, middleware), nil
+}
+
+// Unless extends existing chain with middlewares that only applied to requests
+// that do not match the predicate, and returns a new copy of chain. It returns
+// ErrNilPredicate if the predicate is nil.
+func (c *Chain) Unless(p Predicate, middleware ...Middleware) (*Chain, error) {
+	if p == nil {
+		return nil, ErrNilPredicate
+	}
+
+	return c.conditional("unless", func(r *http.Request) bool { return !p(r) }, middleware), nil
+}
+
+// InsertBefore inserts a named middleware right before the middleware named
+// target, and returns a new copy of chain.
+func (c *Chain) InsertBefore(target, name string, middleware Middleware) (*Chain, error) {
+	return c.insert(target, name, middleware, 0)
+}
+
+// InsertAfter inserts a named middleware right after the middleware named
+// target, and returns a new copy of chain.
+func (c *Chain) InsertAfter(target, name string, middleware Middleware) (*Chain, error) {
+	return c.insert(target, name, middleware, 1)
+}
+
+// Remove removes the middleware with the given name, and returns a new copy of
+// chain.
+func (c *Chain) Remove(name string) (*Chain, error) {
+	i := c.index(name)
+	if i < 0 {
+		return nil, ErrMiddlewareNotFound
+	}
+
+	links := make([]link, 0, len(c.links)-1)
+	links = append(links, c.links[:i]...)
+	links = append(links, c.links[i+1:]...)
+	return &Chain{links: links}, nil
+}
+
+// Names returns the names of the named middlewares in execution order.
+func (c *Chain) Names() []string {
+	names := make([]string, 0, len(c.links))
+	for _, l := range c.links {
+		if l.name != "" {
+			names = append(names, l.name)
+		}
+	}
+
+	return names
+}
+
+// Describe returns a description of every middleware in execution order.
+// Anonymous middlewares are described by their function name and conditional
+// middlewares are marked with their condition.
+func (c *Chain) Describe() []string {
+	desc := make([]string, 0, len(c.links))
+	for _, l := range c.links {
+		if l.mw != nil {
+			desc = append(desc, l.describe())
+		}
+	}
+
+	return desc
 }
 
 // ToHandler converts httpx.Handler to http.Handler
@@ -46,11 +234,92 @@ func (c *Chain) ToHandler(h http.Handler) http.Handler {
 }
 
 func (c *Chain) chain(h Handler) Handler {
-	for i := len(c.middleware) - 1; i >= 0; i-- {
-		if c.middleware[i] != nil {
-			h = c.middleware[i](h)
+	for i := len(c.links) - 1; i >= 0; i-- {
+		if c.links[i].mw != nil {
+			h = c.links[i].mw(h)
 		}
 	}
 
 	return h
 }
+
+// clone copies the links into a new slice with extra capacity, so appending to
+// the copy never aliases the links of c.
+func (c *Chain) clone(extra int) []link {
+	links := make([]link, len(c.links), len(c.links)+extra)
+	copy(links, c.links)
+	return links
+}
+
+func (c *Chain) index(name string) int {
+	for i, l := range c.links {
+		if name != "" && l.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (c *Chain) insert(target, name string, middleware Middleware, offset int) (*Chain, error) {
+	if name == "" {
+		return nil, ErrEmptyMiddlewareName
+	}
+
+	if c.index(name) >= 0 {
+		return nil, ErrMiddlewareExists
+	}
+
+	i := c.index(target)
+	if i < 0 {
+		return nil, ErrMiddlewareNotFound
+	}
+
+	i += offset
+	links := make([]link, 0, len(c.links)+1)
+	links = append(links, c.links[:i]...)
+	links = append(links, link{name: name, mw: middleware})
+	links = append(links, c.links[i:]...)
+	return &Chain{links: links}, nil
+}
+
+func (c *Chain) conditional(cond string, p Predicate, middleware []Middleware) *Chain {
+	links := c.clone(len(middleware))
+	for _, mw := range middleware {
+		if mw == nil {
+			continue
+		}
+
+		links = append(links, link{label: funcName(mw), cond: cond, mw: conditional(p, mw)})
+	}
+
+	return &Chain{links: links}
+}
+
+// conditional wraps mw, so it only applied to requests that match p.
+func conditional(p Predicate, mw Middleware) Middleware {
+	return func(h Handler) HandlerFunc {
+		wrapped := mw(h)
+		return func(w http.ResponseWriter, r *http.Request) error {
+			if p(r) {
+				return wrapped.ServeHTTP(w, r)
+			}
+
+			return h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// funcName returns the name of the function behind the middleware.
+func funcName(mw Middleware) string {
+	if mw == nil {
+		return "<nil>"
+	}
+
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return "<anonymous>"
+	}
+
+	return fn.Name()
+}
@@ -1,9 +1,11 @@
 package httpx
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -58,3 +60,247 @@ func TestChain(t *testing.T) {
 		t.Fatalf("expected %v; got %v", expected, executionTrace)
 	}
 }
+
+func TestChain_Extend_NoAliasing(t *testing.T) {
+	var trace []int
+	factory := func(index int) Middleware {
+		return func(handler Handler) HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) error {
+				trace = append(trace, index)
+				return handler.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	root := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error { return nil })
+
+	// base has spare capacity, so a naive append would share the backing array.
+	base := NewChain(factory(1)).Extend(factory(2))
+	a := base.Extend(factory(3))
+	b := base.Extend(factory(4))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	_ = a.Then(root).ServeHTTP(rec, req)
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(trace, expected) {
+		t.Fatalf("expected %v; got %v", expected, trace)
+	}
+
+	trace = nil
+	_ = b.Then(root).ServeHTTP(rec, req)
+	if expected := []int{1, 2, 4}; !reflect.DeepEqual(trace, expected) {
+		t.Fatalf("expected %v; got %v", expected, trace)
+	}
+}
+
+func TestChain_Extend_Concurrent(t *testing.T) {
+	noop := func(h Handler) HandlerFunc { return h.ServeHTTP }
+	base := NewChain(noop, noop)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := base.Extend(noop).Use("named", noop)
+			if err != nil {
+				t.Errorf("expected no error but got %v", err)
+				return
+			}
+
+			c, err = c.When(MethodIs(http.MethodGet), noop)
+			if err != nil {
+				t.Errorf("expected no error but got %v", err)
+				return
+			}
+
+			if n := len(c.Describe()); n != 5 {
+				t.Errorf("expected 5 middlewares; got %d", n)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestChain_When(t *testing.T) {
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(handler Handler) HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) error {
+				trace = append(trace, name)
+				return handler.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	chain, err := NewChain().When(PathPrefix("/api"), mark("api"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.Unless(MethodIs(http.MethodGet), mark("write"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.When(HeaderIs("X-Debug", ""), mark("debug"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.When(HeaderIs("X-Mode", "strict"), mark("strict"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	handler := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) error {
+		trace = append(trace, "handler")
+		return nil
+	})
+
+	tests := []struct {
+		method   string
+		path     string
+		header   http.Header
+		expected []string
+	}{
+		{http.MethodGet, "/", nil, []string{"handler"}},
+		{http.MethodGet, "/api/users", nil, []string{"api", "handler"}},
+		{http.MethodPost, "/api/users", nil, []string{"api", "write", "handler"}},
+		{http.MethodGet, "/", http.Header{"X-Debug": {"1"}}, []string{"debug", "handler"}},
+		{http.MethodGet, "/", http.Header{"X-Mode": {"lax"}}, []string{"handler"}},
+		{http.MethodGet, "/", http.Header{"X-Mode": {"strict"}}, []string{"strict", "handler"}},
+	}
+
+	for _, tt := range tests {
+		trace = nil
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+
+		_ = handler.ServeHTTP(httptest.NewRecorder(), req)
+		if !reflect.DeepEqual(trace, tt.expected) {
+			t.Errorf("%s %s %v: expected %v; got %v", tt.method, tt.path, tt.header, tt.expected, trace)
+		}
+	}
+
+	if _, err := chain.When(nil, mark("nil")); !errors.Is(err, ErrNilPredicate) {
+		t.Errorf("expected %v but got %v", ErrNilPredicate, err)
+	}
+
+	if _, err := chain.Unless(nil, mark("nil")); !errors.Is(err, ErrNilPredicate) {
+		t.Errorf("expected %v but got %v", ErrNilPredicate, err)
+	}
+}
+
+func TestChain_Named(t *testing.T) {
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(handler Handler) HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) error {
+				trace = append(trace, name)
+				return handler.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	chain, err := NewChain().Use("recover", mark("recover"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.Use("auth", mark("auth"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.InsertBefore("auth", "log", mark("log"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.InsertAfter("auth", "audit", mark("audit"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if expected := []string{"recover", "log", "auth", "audit"}; !reflect.DeepEqual(chain.Names(), expected) {
+		t.Fatalf("expected %v; got %v", expected, chain.Names())
+	}
+
+	removed, err := chain.Remove("auth")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	_ = removed.Then(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error { return nil })).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if expected := []string{"recover", "log", "audit"}; !reflect.DeepEqual(trace, expected) {
+		t.Fatalf("expected %v; got %v", expected, trace)
+	}
+
+	if expected := []string{"recover", "log", "auth", "audit"}; !reflect.DeepEqual(chain.Names(), expected) {
+		t.Fatalf("expected original chain untouched %v; got %v", expected, chain.Names())
+	}
+
+	if _, err := chain.Remove("unknown"); !errors.Is(err, ErrMiddlewareNotFound) {
+		t.Errorf("expected %v but got %v", ErrMiddlewareNotFound, err)
+	}
+
+	if _, err := chain.InsertAfter("unknown", "x", mark("x")); !errors.Is(err, ErrMiddlewareNotFound) {
+		t.Errorf("expected %v but got %v", ErrMiddlewareNotFound, err)
+	}
+
+	if _, err := chain.InsertBefore("auth", "log", mark("log")); !errors.Is(err, ErrMiddlewareExists) {
+		t.Errorf("expected %v but got %v", ErrMiddlewareExists, err)
+	}
+
+	if _, err := chain.Use("auth", mark("auth")); !errors.Is(err, ErrMiddlewareExists) {
+		t.Errorf("expected %v but got %v", ErrMiddlewareExists, err)
+	}
+
+	if _, err := chain.Use("", mark("empty")); !errors.Is(err, ErrEmptyMiddlewareName) {
+		t.Errorf("expected %v but got %v", ErrEmptyMiddlewareName, err)
+	}
+
+	if _, err := chain.InsertBefore("auth", "", mark("empty")); !errors.Is(err, ErrEmptyMiddlewareName) {
+		t.Errorf("expected %v but got %v", ErrEmptyMiddlewareName, err)
+	}
+
+	if _, err := chain.InsertAfter("auth", "", mark("empty")); !errors.Is(err, ErrEmptyMiddlewareName) {
+		t.Errorf("expected %v but got %v", ErrEmptyMiddlewareName, err)
+	}
+
+	if _, err := chain.Remove(""); !errors.Is(err, ErrMiddlewareNotFound) {
+		t.Errorf("expected %v but got %v", ErrMiddlewareNotFound, err)
+	}
+}
+
+func namedTestMiddleware(h Handler) HandlerFunc { return h.ServeHTTP }
+
+func TestChain_Describe(t *testing.T) {
+	chain, err := NewChain(namedTestMiddleware, nil).Use("auth", namedTestMiddleware)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.When(PathPrefix("/"), namedTestMiddleware)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	chain, err = chain.Unless(PathPrefix("/"), namedTestMiddleware)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	const fn = "github.com/josestg/gokit/httpx.namedTestMiddleware"
+	expected := []string{fn, "auth", fn + " (when)", fn + " (unless)"}
+	if got := chain.Describe(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v; got %v", expected, got)
+	}
+}
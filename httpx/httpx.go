@@ -2,11 +2,18 @@ package httpx
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/josestg/httprouter"
 )
 
+// ErrRouteConflict is returned when a route conflicts with a registered route,
+// for example a static segment and a catch-all parameter at the same place.
+var ErrRouteConflict = errors.New("route conflicts with a registered route")
+
 // contextType represents a type for any context for httpx.
 type contextType struct {
 	name string
@@ -48,10 +55,25 @@ type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
 // ServeHTTP calls fn(w, r)
 func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) error { return fn(w, r) }
 
+// Route describes a registered route.
+type Route struct {
+	// Method is the HTTP method of the route.
+	Method string
+	// Path is the path pattern of the route.
+	Path string
+	// Middleware describes the middlewares applied to the route in execution order.
+	Middleware []string
+	// Versions lists the API versions served by the route, if it is versioned.
+	Versions []VersionInfo
+}
+
 // ServeMux is a http router.
 type ServeMux struct {
 	internal *httprouter.Router
 	chain    *Chain
+	policy   VersionPolicy
+	mu       sync.RWMutex
+	routes   []Route
 }
 
 // NewServeMux creates a new ServeMux.
@@ -59,6 +81,7 @@ func NewServeMux() *ServeMux {
 	return &ServeMux{
 		internal: httprouter.New(),
 		chain:    NewChain(),
+		policy:   DefaultVersionPolicy(),
 	}
 
 }
@@ -68,15 +91,15 @@ func NewServeMuxWithChain(chain *Chain) *ServeMux {
 	return &ServeMux{
 		internal: httprouter.New(),
 		chain:    chain,
+		policy:   DefaultVersionPolicy(),
 	}
 }
 
 // Handle registers a new Handler.
 func (mux *ServeMux) Handle(method, path string, handler Handler, middlewares ...Middleware) {
-	mux.internal.Handle(method, path, func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
-		ctx := contextWithParams(r.Context(), Params{p: p})
-		return mux.chain.Extend(middlewares...).Then(handler).ServeHTTP(w, r.WithContext(ctx))
-	})
+	if err := mux.handle(method, path, handler, middlewares, nil); err != nil {
+		panic(err)
+	}
 }
 
 // HandleFunc registers an ordinary function as a Handler.
@@ -84,6 +107,45 @@ func (mux *ServeMux) HandleFunc(method, path string, fn HandlerFunc, middlewares
 	mux.Handle(method, path, fn, middlewares...)
 }
 
+// Routes returns the registered routes in registration order.
+func (mux *ServeMux) Routes() []Route {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	routes := make([]Route, len(mux.routes))
+	copy(routes, mux.routes)
+	return routes
+}
+
+// handle registers the handler, the conflicts with the registered routes are
+// returned as ErrRouteConflict instead of the httprouter panic.
+func (mux *ServeMux) handle(method, path string, handler Handler, middlewares []Middleware, versions []VersionInfo) (err error) {
+	chain := mux.chain.Extend(middlewares...)
+	chained := chain.Then(handler)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s %s: %v", ErrRouteConflict, method, path, r)
+		}
+	}()
+
+	mux.internal.Handle(method, path, func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		ctx := contextWithParams(r.Context(), Params{p: p})
+		return chained.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.routes = append(mux.routes, Route{
+		Method:     method,
+		Path:       path,
+		Middleware: chain.Describe(),
+		Versions:   versions,
+	})
+
+	return nil
+}
+
 // ServeHTTP implements the http.Handler to make it compatible with
 // net/http Handler.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -206,3 +206,25 @@ func (t *tracer) verifyExecutionTrace(expectedExecutionTrace []int) {
 		t.t.Errorf("expected execution trace is %v; got %v", expectedExecutionTrace, t.executionTrace)
 	}
 }
+
+func TestServeMux_Routes(t *testing.T) {
+	chain, err := NewChain().Use("global", namedTestMiddleware)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	mux := NewServeMuxWithChain(chain)
+
+	h := &mockHandler{t: t}
+	mux.HandleFunc(http.MethodGet, "/a", h.Handler(nil))
+	mux.HandleFunc(http.MethodPost, "/b/:id", h.Handler(nil), namedTestMiddleware)
+
+	expected := []Route{
+		{Method: http.MethodGet, Path: "/a", Middleware: []string{"global"}},
+		{Method: http.MethodPost, Path: "/b/:id", Middleware: []string{"global", "github.com/josestg/gokit/httpx.namedTestMiddleware"}},
+	}
+
+	if got := mux.Routes(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected routes %v; got %v", expected, got)
+	}
+}
@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/josestg/gokit/encoding/json"
+	"github.com/josestg/gokit/validate"
+)
+
+// Problem is an RFC 7807 problem details response. It implements error, so
+// handlers can return it and let RenderProblems write it.
+type Problem struct {
+	Type          string                `json:"type,omitempty"`
+	Title         string                `json:"title"`
+	Status        int                   `json:"status"`
+	Detail        string                `json:"detail,omitempty"`
+	Instance      string                `json:"instance,omitempty"`
+	InvalidParams []validate.FieldError `json:"invalid_params,omitempty"`
+}
+
+// NewProblem creates a new Problem with the status text as the title.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+
+	return p.Title + ": " + p.Detail
+}
+
+// WriteProblem writes the problem as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, p *Problem) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	return json.Encoder.Encode(w, p)
+}
+
+// RenderProblems is a middleware that writes the Problem errors returned by
+// the next handler. Other errors are returned untouched.
+func RenderProblems(h Handler) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := h.ServeHTTP(w, r)
+		if err == nil {
+			return nil
+		}
+
+		var p *Problem
+		if !errors.As(err, &p) {
+			return err
+		}
+
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+
+		return WriteProblem(w, p)
+	}
+}
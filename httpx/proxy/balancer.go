@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// Balancer knows how to select an upstream for a request.
+type Balancer interface {
+	// Next selects one of the candidates for the request. The candidates are
+	// never empty and only contain the available upstreams.
+	Next(r *http.Request, candidates []*Upstream) *Upstream
+}
+
+// roundRobin selects the candidates in turn.
+type roundRobin struct {
+	position uint32
+}
+
+// RoundRobin returns a Balancer that selects the upstreams in turn.
+func RoundRobin() Balancer { return &roundRobin{} }
+
+func (b *roundRobin) Next(_ *http.Request, candidates []*Upstream) *Upstream {
+	i := atomic.AddUint32(&b.position, 1) - 1
+	return candidates[int(i%uint32(len(candidates)))]
+}
+
+// leastConnections selects the candidate with the fewest in-flight requests.
+type leastConnections struct{}
+
+// LeastConnections returns a Balancer that selects the upstream with the
+// fewest in-flight requests.
+func LeastConnections() Balancer { return leastConnections{} }
+
+func (leastConnections) Next(_ *http.Request, candidates []*Upstream) *Upstream {
+	selected := candidates[0]
+	for _, u := range candidates[1:] {
+		if u.Connections() < selected.Connections() {
+			selected = u
+		}
+	}
+
+	return selected
+}
+
+// KeyFunc extracts the hashing key from a request.
+type KeyFunc func(r *http.Request) string
+
+// ClientIP is a KeyFunc that uses the host of the request remote address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// HeaderKey returns a KeyFunc that uses the value of the given header.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// consistentHash selects the candidate using rendezvous hashing.
+type consistentHash struct {
+	key KeyFunc
+}
+
+// ConsistentHash returns a Balancer that always selects the same upstream for
+// the same key while the upstream is available. When an upstream becomes
+// unavailable only its keys move to other upstreams.
+func ConsistentHash(key KeyFunc) Balancer { return consistentHash{key: key} }
+
+func (b consistentHash) Next(r *http.Request, candidates []*Upstream) *Upstream {
+	key := b.key(r)
+
+	var (
+		selected *Upstream
+		best     uint64
+	)
+
+	for _, u := range candidates {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte(u.url.String()))
+		if score := h.Sum64(); selected == nil || score > best {
+			selected, best = u, score
+		}
+	}
+
+	return selected
+}
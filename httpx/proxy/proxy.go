@@ -0,0 +1,533 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+var (
+	// ErrNoUpstream is returned when there is no available upstream to serve
+	// the request.
+	ErrNoUpstream = errors.New("no available upstream")
+
+	// ErrInvalidHealthCheck is returned when the interval or the timeout of
+	// the active health check is not positive.
+	ErrInvalidHealthCheck = errors.New("invalid active health check")
+)
+
+// UpstreamError is returned when an upstream fails to serve the request.
+type UpstreamError struct {
+	Upstream string
+	Err      error
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("proxy upstream %s: %v", e.Upstream, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// StatusCode returns the status code that best describes the error,
+// 504 for timeouts and 502 otherwise.
+func (e *UpstreamError) StatusCode() int {
+	var netErr net.Error
+	if errors.Is(e.Err, context.DeadlineExceeded) || (errors.As(e.Err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+// Upstream is a backend server the proxy forwards requests to.
+type Upstream struct {
+	url         *url.URL
+	connections int64
+
+	mu        sync.Mutex
+	fails     int
+	downUntil time.Time
+	down      bool
+}
+
+// URL returns the url of the upstream.
+func (u *Upstream) URL() *url.URL { return u.url }
+
+// Connections returns the number of in-flight requests to the upstream.
+func (u *Upstream) Connections() int64 { return atomic.LoadInt64(&u.connections) }
+
+// available reports whether the upstream passed both active and passive
+// health checks at the given time.
+func (u *Upstream) available(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !u.down && !now.Before(u.downUntil)
+}
+
+// Option is an option to configure the Proxy.
+type Option func(*Proxy)
+
+// WithBalancer configures the upstream selection, the default is RoundRobin.
+func WithBalancer(b Balancer) Option {
+	return func(p *Proxy) {
+		p.balancer = b
+	}
+}
+
+// WithTransport configures the transport used to reach the upstreams.
+func WithTransport(t http.RoundTripper) Option {
+	return func(p *Proxy) {
+		p.transport = t
+	}
+}
+
+// WithClock configures the clock used by the passive health checks.
+func WithClock(c clock.Clock) Option {
+	return func(p *Proxy) {
+		p.clock = c
+	}
+}
+
+// WithRetries configures how many other upstreams are tried when an upstream
+// can not be connected to.
+func WithRetries(n int) Option {
+	return func(p *Proxy) {
+		p.retries = n
+	}
+}
+
+// WithPassiveHealthCheck marks an upstream unavailable for cooldown after
+// maxFails consecutive failures.
+func WithPassiveHealthCheck(maxFails int, cooldown time.Duration) Option {
+	return func(p *Proxy) {
+		p.maxFails = maxFails
+		p.cooldown = cooldown
+	}
+}
+
+// WithActiveHealthCheck configures the path probed by CheckHealth and
+// StartHealthChecks. An upstream is healthy when the probe responds with a
+// status below 400. The interval and the timeout must be positive.
+func WithActiveHealthCheck(path string, interval, timeout time.Duration) Option {
+	return func(p *Proxy) {
+		p.healthPath = path
+		p.healthInterval = interval
+		p.healthTimeout = timeout
+	}
+}
+
+// SetRequestHeader sets a header on every request sent to the upstreams.
+func SetRequestHeader(key, value string) Option {
+	return func(p *Proxy) {
+		p.rewriteRequest = append(p.rewriteRequest, func(h http.Header) { h.Set(key, value) })
+	}
+}
+
+// RemoveRequestHeader removes a header from every request sent to the upstreams.
+func RemoveRequestHeader(key string) Option {
+	return func(p *Proxy) {
+		p.rewriteRequest = append(p.rewriteRequest, func(h http.Header) { h.Del(key) })
+	}
+}
+
+// SetResponseHeader sets a header on every response received from the upstreams.
+func SetResponseHeader(key, value string) Option {
+	return func(p *Proxy) {
+		p.rewriteResponse = append(p.rewriteResponse, func(h http.Header) { h.Set(key, value) })
+	}
+}
+
+// RemoveResponseHeader removes a header from every response received from the
+// upstreams.
+func RemoveResponseHeader(key string) Option {
+	return func(p *Proxy) {
+		p.rewriteResponse = append(p.rewriteResponse, func(h http.Header) { h.Del(key) })
+	}
+}
+
+// Proxy is a load balancing reverse proxy. It implements httpx.Handler, so
+// the upstream failures are returned as errors to the httpx error path
+// instead of being written to the response.
+type Proxy struct {
+	upstreams       []*Upstream
+	balancer        Balancer
+	transport       http.RoundTripper
+	clock           clock.Clock
+	retries         int
+	maxFails        int
+	cooldown        time.Duration
+	healthPath      string
+	healthInterval  time.Duration
+	healthTimeout   time.Duration
+	rewriteRequest  []func(http.Header)
+	rewriteResponse []func(http.Header)
+	reverse         *httputil.ReverseProxy
+}
+
+// New creates a new Proxy for the given upstream urls.
+func New(upstreams []string, opts ...Option) (*Proxy, error) {
+	if len(upstreams) == 0 {
+		return nil, ErrNoUpstream
+	}
+
+	p := &Proxy{
+		balancer:       RoundRobin(),
+		transport:      http.DefaultTransport,
+		clock:          clock.UTC,
+		retries:        1,
+		maxFails:       3,
+		cooldown:       10 * time.Second,
+		healthInterval: 10 * time.Second,
+		healthTimeout:  2 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.healthPath != "" && (p.healthInterval <= 0 || p.healthTimeout <= 0) {
+		return nil, fmt.Errorf("%w: interval %v, timeout %v", ErrInvalidHealthCheck, p.healthInterval, p.healthTimeout)
+	}
+
+	for _, raw := range upstreams {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing upstream %q: %w", raw, err)
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("parsing upstream %q: scheme and host are required", raw)
+		}
+
+		p.upstreams = append(p.upstreams, &Upstream{url: u})
+	}
+
+	p.reverse = &httputil.ReverseProxy{
+		Director:       p.direct,
+		Transport:      p.transport,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler: func(_ http.ResponseWriter, r *http.Request, err error) {
+			attemptFromContext(r.Context()).err = err
+		},
+	}
+
+	return p, nil
+}
+
+// Upstreams returns the upstreams of the proxy.
+func (p *Proxy) Upstreams() []*Upstream {
+	upstreams := make([]*Upstream, len(p.upstreams))
+	copy(upstreams, p.upstreams)
+	return upstreams
+}
+
+// ServeHTTP forwards the request to one of the available upstreams.
+// When the upstream can not be connected to, the request is retried on
+// another upstream. A failure after the upstream response is started is
+// only counted by the passive health check, it is not returned since the
+// error path could not write it anymore.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	tried := make(map[*Upstream]bool, p.retries+1)
+	if p.retries > 0 && r.Body != nil && r.Body != http.NoBody {
+		// the transport closes the body when it fails to connect, keep it
+		// open so the request can be retried on another upstream.
+		r = r.WithContext(r.Context())
+		r.Body = noCloseBody{r.Body}
+	}
+
+	sw := &startedWriter{ResponseWriter: w}
+
+	var lastErr error
+	for i := 0; i <= p.retries; i++ {
+		u := p.next(r, tried)
+		if u == nil {
+			break
+		}
+
+		tried[u] = true
+		err := p.forward(sw, r, u)
+		if err == nil {
+			p.succeed(u)
+			return nil
+		}
+
+		lastErr = &UpstreamError{Upstream: u.url.String(), Err: err}
+		if r.Context().Err() != nil || errors.Is(err, context.Canceled) {
+			// the client went away, which says nothing about the upstream.
+			return lastErr
+		}
+
+		p.fail(u)
+		if sw.started {
+			return nil
+		}
+
+		if !isConnectError(err) {
+			return lastErr
+		}
+	}
+
+	if lastErr == nil {
+		return ErrNoUpstream
+	}
+
+	return lastErr
+}
+
+// forward forwards the request to the upstream and returns its error.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, u *Upstream) error {
+	atomic.AddInt64(&u.connections, 1)
+	defer atomic.AddInt64(&u.connections, -1)
+
+	a := &attempt{upstream: u}
+	p.reverse.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), attemptContextKey, a)))
+	return a.err
+}
+
+// CheckHealth probes every upstream once and updates their availability.
+func (p *Proxy) CheckHealth(ctx context.Context) {
+	if p.healthPath == "" {
+		return
+	}
+
+	client := &http.Client{Transport: p.transport, Timeout: p.healthTimeout}
+
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			healthy := probe(ctx, client, u.url.JoinPath(p.healthPath).String())
+
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			u.down = !healthy
+			if healthy {
+				u.fails = 0
+				u.downUntil = time.Time{}
+			}
+		}(u)
+	}
+
+	wg.Wait()
+}
+
+// StartHealthChecks probes the upstreams periodically until ctx is done.
+func (p *Proxy) StartHealthChecks(ctx context.Context) {
+	if p.healthPath == "" {
+		return
+	}
+
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Proxy) next(r *http.Request, tried map[*Upstream]bool) *Upstream {
+	now := p.clock.Now()
+	candidates := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if !tried[u] && u.available(now) {
+			candidates = append(candidates, u)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return p.balancer.Next(r, candidates)
+}
+
+func (p *Proxy) succeed(u *Upstream) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.fails = 0
+}
+
+func (p *Proxy) fail(u *Upstream) {
+	if p.maxFails <= 0 {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.fails++
+	if u.fails >= p.maxFails {
+		u.fails = 0
+		u.downUntil = p.clock.Now().Add(p.cooldown)
+	}
+}
+
+func (p *Proxy) direct(r *http.Request) {
+	target := attemptFromContext(r.Context()).upstream.url
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.URL.Path, r.URL.RawPath = joinURLPath(target, r.URL)
+	if target.RawQuery == "" || r.URL.RawQuery == "" {
+		r.URL.RawQuery = target.RawQuery + r.URL.RawQuery
+	} else {
+		r.URL.RawQuery = target.RawQuery + "&" + r.URL.RawQuery
+	}
+
+	if _, ok := r.Header["User-Agent"]; !ok {
+		// explicitly disable User-Agent so it's not set to default value
+		r.Header.Set("User-Agent", "")
+	}
+
+	r.Header.Set("X-Forwarded-Host", r.Host)
+	for _, rewrite := range p.rewriteRequest {
+		rewrite(r.Header)
+	}
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	for _, rewrite := range p.rewriteResponse {
+		rewrite(resp.Header)
+	}
+
+	return nil
+}
+
+// attempt is a single try to forward a request to an upstream.
+type attempt struct {
+	upstream *Upstream
+	err      error
+}
+
+type contextKey struct {
+	name string
+}
+
+var attemptContextKey = &contextKey{name: "attempt"}
+
+func attemptFromContext(ctx context.Context) *attempt {
+	a, _ := ctx.Value(attemptContextKey).(*attempt)
+	return a
+}
+
+// startedWriter is a response writer that records whether the response is
+// started, so an upstream failure is not written after it.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) WriteHeader(code int) {
+	// the informational responses do not start the final response.
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		w.started = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *startedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+func (w *startedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	w.started = true
+	return h.Hijack()
+}
+
+func (w *startedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// noCloseBody is a request body that can not be closed by the transport, the
+// server closes the underlying body after the handler returns.
+type noCloseBody struct {
+	io.ReadCloser
+}
+
+func (noCloseBody) Close() error { return nil }
+
+// isConnectError reports whether the request failed before it reached the
+// upstream, so it is safe to retry it on another upstream.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func probe(ctx context.Context, client *http.Client, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+
+	_ = resp.Body.Close()
+	return resp.StatusCode < http.StatusBadRequest
+}
+
+// joinURLPath joins the upstream path with the request path, it mirrors the
+// behavior of httputil.NewSingleHostReverseProxy.
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+
+	apath := a.EscapedPath()
+	bpath := b.EscapedPath()
+
+	aslash := strings.HasSuffix(apath, "/")
+	bslash := strings.HasPrefix(bpath, "/")
+
+	switch {
+	case aslash && bslash:
+		return a.Path + b.Path[1:], apath + bpath[1:]
+	case !aslash && !bslash:
+		return a.Path + "/" + b.Path, apath + "/" + bpath
+	}
+
+	return a.Path + b.Path, apath + bpath
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+
+	return a + b
+}
@@ -0,0 +1,345 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/httpx"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newUpstream(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Internal", "secret")
+		w.Header().Set("X-Echo-Header", r.Header.Get("X-Proxy"))
+		_, _ = w.Write([]byte(name + ":" + r.URL.Path + ":" + string(body)))
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// closedURL returns the url of a server that refuses connections.
+func closedURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func serve(t *testing.T, p *Proxy, req *http.Request) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	err := p.ServeHTTP(rec, req)
+	return rec, err
+}
+
+func TestProxy_RoundRobin(t *testing.T) {
+	a, b := newUpstream(t, "a"), newUpstream(t, "b")
+	p, err := New([]string{a.URL, b.URL})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		rec, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/x", nil))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		got = append(got, rec.Header().Get("X-Upstream"))
+	}
+
+	if strings.Join(got, "") != "abab" {
+		t.Errorf("expected round robin order abab but got %v", got)
+	}
+}
+
+func TestProxy_ConsistentHash(t *testing.T) {
+	a, b, c := newUpstream(t, "a"), newUpstream(t, "b"), newUpstream(t, "c")
+	p, err := New([]string{a.URL, b.URL, c.URL}, WithBalancer(ConsistentHash(HeaderKey("X-User"))))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	seen := map[string]string{}
+	for i := 0; i < 3; i++ {
+		for _, user := range []string{"alice", "bob", "carol", "dave"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User", user)
+			rec, err := serve(t, p, req)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			upstream := rec.Header().Get("X-Upstream")
+			if prev, ok := seen[user]; ok && prev != upstream {
+				t.Errorf("expected %s to stick to %s but got %s", user, prev, upstream)
+			}
+
+			seen[user] = upstream
+		}
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	busy := &Upstream{connections: 3}
+	idle := &Upstream{connections: 1}
+	if got := LeastConnections().Next(nil, []*Upstream{busy, idle}); got != idle {
+		t.Errorf("expected the upstream with fewest connections")
+	}
+}
+
+func TestProxy_RetryOnConnectionFailure(t *testing.T) {
+	a := newUpstream(t, "a")
+	p, err := New([]string{closedURL(t), a.URL}, WithRetries(1))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec, err := serve(t, p, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader("payload")))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if got := rec.Body.String(); got != "a:/items:payload" {
+			t.Errorf("expected the request to be retried with its body but got %q", got)
+		}
+	}
+}
+
+func TestProxy_PassiveHealthCheck(t *testing.T) {
+	c := &fakeClock{now: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)}
+	dead := closedURL(t)
+	p, err := New([]string{dead}, WithRetries(0), WithClock(c), WithPassiveHealthCheck(2, time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil))
+		var upstreamErr *UpstreamError
+		if !errors.As(err, &upstreamErr) {
+			t.Fatalf("expected upstream error but got %v", err)
+		}
+
+		if upstreamErr.StatusCode() != http.StatusBadGateway {
+			t.Errorf("expected status %d but got %d", http.StatusBadGateway, upstreamErr.StatusCode())
+		}
+	}
+
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, ErrNoUpstream) {
+		t.Fatalf("expected %v but got %v", ErrNoUpstream, err)
+	}
+
+	c.Add(time.Minute)
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); errors.Is(err, ErrNoUpstream) {
+		t.Fatalf("expected the upstream to be available after cooldown")
+	}
+}
+
+func TestProxy_PassiveHealthCheck_Canceled(t *testing.T) {
+	c := &fakeClock{now: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)}
+	p, err := New([]string{closedURL(t)}, WithRetries(0), WithClock(c), WithPassiveHealthCheck(1, time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		_, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected %v but got %v", context.Canceled, err)
+		}
+	}
+
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); errors.Is(err, ErrNoUpstream) {
+		t.Fatalf("expected the canceled requests not to count as failures")
+	}
+}
+
+// brokenHijacker is a response writer whose hijacked connection can not be
+// written to.
+type brokenHijacker struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *brokenHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	conn, peer := net.Pipe()
+	_ = peer.Close()
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+func TestProxy_FailureAfterResponseStarted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+
+		defer func(conn net.Conn) { _ = conn.Close() }(conn)
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = brw.Flush()
+		_, _ = io.Copy(io.Discard, conn)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &fakeClock{now: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)}
+	p, err := New([]string{srv.URL}, WithRetries(0), WithClock(c), WithPassiveHealthCheck(1, time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+
+	w := &brokenHijacker{ResponseRecorder: httptest.NewRecorder()}
+	if err := p.ServeHTTP(w, req); err != nil {
+		t.Errorf("expected no error after the response started but got %v", err)
+	}
+
+	if !w.hijacked {
+		t.Fatalf("expected the connection to be hijacked")
+	}
+
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, ErrNoUpstream) {
+		t.Errorf("expected the failure to count but got %v", err)
+	}
+
+	if n := p.Upstreams()[0].Connections(); n != 0 {
+		t.Errorf("expected no connections but got %d", n)
+	}
+}
+
+func TestProxy_ActiveHealthCheck(t *testing.T) {
+	var healthy = true
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/healthz" && !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	p, err := New([]string{srv.URL}, WithActiveHealthCheck("/healthz", time.Second, time.Second))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	mu.Lock()
+	healthy = false
+	mu.Unlock()
+
+	p.CheckHealth(context.Background())
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, ErrNoUpstream) {
+		t.Fatalf("expected %v but got %v", ErrNoUpstream, err)
+	}
+
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+
+	p.CheckHealth(context.Background())
+	if _, err := serve(t, p, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+}
+
+func TestProxy_HeaderRewriting(t *testing.T) {
+	a := newUpstream(t, "a")
+	p, err := New([]string{a.URL + "/base"},
+		SetRequestHeader("X-Proxy", "gokit"),
+		RemoveResponseHeader("X-Internal"),
+		SetResponseHeader("X-Served-By", "edge"),
+	)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	mux := httpx.NewServeMux()
+	mux.Handle(http.MethodGet, "/*path", p)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if got := rec.Body.String(); got != "a:/base/users:" {
+		t.Errorf("expected the upstream path to be joined but got %q", got)
+	}
+
+	if got := rec.Header().Get("X-Echo-Header"); got != "gokit" {
+		t.Errorf("expected request header to be set but got %q", got)
+	}
+
+	if got := rec.Header().Get("X-Internal"); got != "" {
+		t.Errorf("expected response header to be removed but got %q", got)
+	}
+
+	if got := rec.Header().Get("X-Served-By"); got != "edge" {
+		t.Errorf("expected response header to be set but got %q", got)
+	}
+}
+
+func TestNew_InvalidUpstream(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrNoUpstream) {
+		t.Errorf("expected %v but got %v", ErrNoUpstream, err)
+	}
+
+	if _, err := New([]string{"localhost"}); err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestNew_InvalidHealthCheck(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := New([]string{"http://localhost"}, WithActiveHealthCheck("/healthz", interval, time.Second))
+		if !errors.Is(err, ErrInvalidHealthCheck) {
+			t.Errorf("interval %v: expected %v but got %v", interval, ErrInvalidHealthCheck, err)
+		}
+	}
+
+	if _, err := New([]string{"http://localhost"}, WithActiveHealthCheck("/healthz", time.Second, 0)); !errors.Is(err, ErrInvalidHealthCheck) {
+		t.Errorf("expected %v but got %v", ErrInvalidHealthCheck, err)
+	}
+}
@@ -0,0 +1,245 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoVersions is returned when a versioned route has no versions.
+	ErrNoVersions = errors.New("at least one version is required")
+
+	// ErrInvalidVersion is returned when a version name is empty or registered
+	// twice for the same route.
+	ErrInvalidVersion = errors.New("invalid version")
+)
+
+// Version is a single version of a versioned route.
+type Version struct {
+	// Name identifies the version, for example "v2". When the version is
+	// requested by header or Accept parameter the leading "v" is optional.
+	Name string
+
+	// Handler serves the requests for this version.
+	Handler Handler
+
+	// Deprecation is the time the version is deprecated.
+	// Zero means the version is not deprecated.
+	Deprecation time.Time
+
+	// Sunset is the time the version is expected to become unavailable.
+	// Zero means there is no planned sunset.
+	Sunset time.Time
+}
+
+// VersionInfo describes a version of a route for introspection.
+type VersionInfo struct {
+	Name        string
+	Default     bool
+	Deprecation time.Time
+	Sunset      time.Time
+}
+
+// VersionPolicy configures how ServeMux selects the version of a versioned
+// route.
+type VersionPolicy struct {
+	// Default is the version used when the request does not ask for one.
+	// When empty, or not served by the route, the last version is used.
+	Default string
+
+	// Prefix registers every version under "/{name}{path}" as well.
+	Prefix bool
+
+	// Header is the request header carrying the requested version.
+	// Empty disables header selection.
+	Header string
+
+	// AcceptParam is the Accept media type parameter carrying the requested
+	// version, for example "version" in "application/vnd.x+json;version=2".
+	// Empty disables Accept selection.
+	AcceptParam string
+}
+
+// DefaultVersionPolicy returns the default version policy.
+func DefaultVersionPolicy() VersionPolicy {
+	return VersionPolicy{
+		Prefix:      true,
+		Header:      "X-API-Version",
+		AcceptParam: "version",
+	}
+}
+
+var versionContextKey = &contextType{name: "version"}
+
+// VersionFromContext gets the selected route version from context.
+func VersionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(versionContextKey).(string)
+	return v
+}
+
+// SetVersionPolicy sets the policy used by HandleVersions.
+// It only affects the routes registered after it is called.
+func (mux *ServeMux) SetVersionPolicy(policy VersionPolicy) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.policy = policy
+}
+
+// HandleVersions registers multiple versions of the same route.
+//
+// The version is selected by URL prefix, custom header or Accept media type
+// parameter as configured by the VersionPolicy, falling back to the default
+// version. Deprecated versions respond with the Deprecation and Sunset
+// headers, unknown versions with a 404 Problem.
+//
+// It returns ErrNoVersions without versions, ErrInvalidVersion for an empty
+// or duplicate version name and ErrRouteConflict when a route, the prefixed
+// ones included, conflicts with a registered route. The routes registered
+// before the conflict are kept.
+func (mux *ServeMux) HandleVersions(method, path string, versions []Version, middlewares ...Middleware) error {
+	if len(versions) == 0 {
+		return ErrNoVersions
+	}
+
+	mux.mu.RLock()
+	policy := mux.policy
+	mux.mu.RUnlock()
+
+	vh := &versionedHandler{
+		policy:   policy,
+		versions: make(map[string]*Version, len(versions)),
+		def:      &versions[len(versions)-1],
+	}
+
+	for i := range versions {
+		v := &versions[i]
+		key := normalizeVersion(v.Name)
+		if key == "" {
+			return fmt.Errorf("%w: empty name for path %s", ErrInvalidVersion, path)
+		}
+
+		if _, exists := vh.versions[key]; exists {
+			return fmt.Errorf("%w: duplicate %s for path %s", ErrInvalidVersion, v.Name, path)
+		}
+
+		vh.versions[key] = v
+		if policy.Default != "" && key == normalizeVersion(policy.Default) {
+			vh.def = v
+		}
+	}
+
+	infos := make([]VersionInfo, len(versions))
+	for i, v := range versions {
+		infos[i] = VersionInfo{
+			Name:        v.Name,
+			Default:     &versions[i] == vh.def,
+			Deprecation: v.Deprecation,
+			Sunset:      v.Sunset,
+		}
+	}
+
+	if err := mux.handle(method, path, vh, middlewares, infos); err != nil {
+		return err
+	}
+
+	if !policy.Prefix {
+		return nil
+	}
+
+	for i := range versions {
+		v := &versions[i]
+		prefixed := "/" + strings.Trim(v.Name, "/") + path
+		err := mux.handle(method, prefixed, HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return serveVersion(w, r, v)
+		}), middlewares, infos[i:i+1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// versionedHandler selects the version of a route per request.
+type versionedHandler struct {
+	policy   VersionPolicy
+	versions map[string]*Version
+	def      *Version
+}
+
+func (vh *versionedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	if vh.policy.Header != "" {
+		w.Header().Add("Vary", vh.policy.Header)
+	}
+
+	if vh.policy.AcceptParam != "" {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	requested := vh.requested(r)
+	if requested == "" {
+		return serveVersion(w, r, vh.def)
+	}
+
+	v, ok := vh.versions[normalizeVersion(requested)]
+	if !ok {
+		p := NewProblem(http.StatusNotFound, "unsupported api version: "+requested)
+		p.Instance = r.URL.Path
+		return WriteProblem(w, p)
+	}
+
+	return serveVersion(w, r, v)
+}
+
+// requested returns the version requested by the header or the Accept media
+// type parameter, the header takes precedence.
+func (vh *versionedHandler) requested(r *http.Request) string {
+	if vh.policy.Header != "" {
+		if v := strings.TrimSpace(r.Header.Get(vh.policy.Header)); v != "" {
+			return v
+		}
+	}
+
+	if vh.policy.AcceptParam != "" {
+		for _, accept := range r.Header.Values("Accept") {
+			for _, mediaType := range strings.Split(accept, ",") {
+				_, params, err := mime.ParseMediaType(mediaType)
+				if err != nil {
+					continue
+				}
+
+				if v := params[vh.policy.AcceptParam]; v != "" {
+					return v
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// serveVersion writes the lifecycle headers of v and serves the request with it.
+func serveVersion(w http.ResponseWriter, r *http.Request, v *Version) error {
+	if !v.Deprecation.IsZero() {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+	}
+
+	if !v.Sunset.IsZero() {
+		w.Header().Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	ctx := context.WithValue(r.Context(), versionContextKey, v.Name)
+	return v.Handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// normalizeVersion makes "v2", "V2" and "2" the same version.
+func normalizeVersion(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "/")
+	return strings.TrimPrefix(strings.ToLower(name), "v")
+}
@@ -0,0 +1,175 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func versionHandler(name string) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		_, _ = w.Write([]byte(name + ":" + VersionFromContext(r.Context())))
+		return nil
+	}
+}
+
+func TestServeMux_HandleVersions(t *testing.T) {
+	deprecation := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mux := NewServeMux()
+	err := mux.HandleVersions(http.MethodGet, "/users", []Version{
+		{Name: "v1", Handler: versionHandler("one"), Deprecation: deprecation, Sunset: sunset},
+		{Name: "v2", Handler: versionHandler("two")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error; got %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		header     http.Header
+		status     int
+		body       string
+		ctype      string
+		deprecated bool
+	}{
+		{name: "default", path: "/users", status: http.StatusOK, body: "two:v2"},
+		{name: "prefix v1", path: "/v1/users", status: http.StatusOK, body: "one:v1", deprecated: true},
+		{name: "prefix v2", path: "/v2/users", status: http.StatusOK, body: "two:v2"},
+		{name: "header", path: "/users", header: http.Header{"X-Api-Version": {"1"}}, status: http.StatusOK, body: "one:v1", deprecated: true},
+		{name: "accept", path: "/users", header: http.Header{"Accept": {"text/plain, application/vnd.x+json;version=1"}}, status: http.StatusOK, body: "one:v1", deprecated: true},
+		{name: "header wins", path: "/users", header: http.Header{"X-Api-Version": {"v2"}, "Accept": {"application/vnd.x+json;version=1"}}, status: http.StatusOK, body: "two:v2"},
+		{name: "unknown", path: "/users", header: http.Header{"X-Api-Version": {"9"}}, status: http.StatusNotFound, body: `{"title":"Not Found","status":404,"detail":"unsupported api version: 9","instance":"/users"}` + "\n", ctype: "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d; got %d", tt.status, rec.Code)
+			}
+
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("expected body %q; got %q", tt.body, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Content-Type"); tt.ctype != "" && got != tt.ctype {
+				t.Errorf("expected content type %q; got %q", tt.ctype, got)
+			}
+
+			if !tt.deprecated {
+				if got := rec.Header().Get("Deprecation"); got != "" {
+					t.Errorf("expected no deprecation header; got %q", got)
+				}
+				return
+			}
+
+			if got, expected := rec.Header().Get("Deprecation"), "@1640995200"; got != expected {
+				t.Errorf("expected deprecation header %q; got %q", expected, got)
+			}
+
+			if got, expected := rec.Header().Get("Sunset"), "Sun, 01 Jan 2023 00:00:00 GMT"; got != expected {
+				t.Errorf("expected sunset header %q; got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestServeMux_HandleVersions_Policy(t *testing.T) {
+	mux := NewServeMux()
+	mux.SetVersionPolicy(VersionPolicy{Default: "v1", Header: "Api-Version"})
+	err := mux.HandleVersions(http.MethodGet, "/items", []Version{
+		{Name: "v1", Handler: versionHandler("one")},
+		{Name: "v2", Handler: versionHandler("two")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error; got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if rec.Body.String() != "one:v1" {
+		t.Errorf("expected the policy default version; got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/items", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected prefix routes to be disabled; got status %d", rec.Code)
+	}
+
+	expected := []Route{
+		{
+			Method:     http.MethodGet,
+			Path:       "/items",
+			Middleware: []string{},
+			Versions:   []VersionInfo{{Name: "v1", Default: true}, {Name: "v2"}},
+		},
+	}
+
+	if got := mux.Routes(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected routes %+v; got %+v", expected, got)
+	}
+}
+
+func TestServeMux_HandleVersions_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []Version
+		err      error
+	}{
+		{name: "no versions", err: ErrNoVersions},
+		{name: "empty name", versions: []Version{{Name: " ", Handler: versionHandler("a")}}, err: ErrInvalidVersion},
+		{
+			name: "duplicate",
+			versions: []Version{
+				{Name: "v1", Handler: versionHandler("a")},
+				{Name: "1", Handler: versionHandler("b")},
+			},
+			err: ErrInvalidVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewServeMux()
+			if err := mux.HandleVersions(http.MethodGet, "/x", tt.versions); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v; got %v", tt.err, err)
+			}
+
+			if routes := mux.Routes(); len(routes) != 0 {
+				t.Errorf("expected no routes; got %+v", routes)
+			}
+		})
+	}
+}
+
+func TestServeMux_HandleVersions_Conflict(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle(http.MethodGet, "/v1/*rest", versionHandler("static"))
+
+	err := mux.HandleVersions(http.MethodGet, "/users", []Version{
+		{Name: "v1", Handler: versionHandler("one")},
+	})
+	if !errors.Is(err, ErrRouteConflict) {
+		t.Fatalf("expected error %v; got %v", ErrRouteConflict, err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users", nil))
+	if rec.Body.String() != "static:" {
+		t.Errorf("expected the catch-all route to be kept; got %q", rec.Body.String())
+	}
+}
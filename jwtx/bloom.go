@@ -0,0 +1,207 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// bloomFilter is a Bloom filter that uses double hashing of the 64-bit FNV-1a
+// hash to derive its k hashes.
+type bloomFilter struct {
+	mu   sync.RWMutex
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newBloomFilter creates a Bloom filter sized for n entries with the false
+// positive rate p.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (f *bloomFilter) add(key string) {
+	h1, h2 := bloomHash(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain reports whether the key may have been added, a false result
+// means the key has never been added.
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHash(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func bloomHash(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	// an odd step visits distinct bits for every hash.
+	return sum & math.MaxUint32, (sum >> 32) | 1
+}
+
+// ErrNotListable is returned when a revocation store can not list its
+// revocations.
+var ErrNotListable = errors.New("jwtx: revocation store can not list its revocations")
+
+// BloomRevocationStore is a RevocationStore decorator that keeps the revoked
+// jtis and subjects in a Bloom filter, so that the lookups of tokens that are
+// not revoked, which are most of them, never reach the underlying store.
+//
+// The filter is local to the process and a miss is answered without the
+// underlying store, so the revocations made by other processes sharing the
+// store are only seen once the filter is rebuilt from the store with Rebuild
+// or Watch, which requires the store to be a RevocationLister. Until then,
+// the tokens they revoke are accepted. The revocations made through the
+// decorator are seen immediately. The filter forgets the expired entries
+// when it is rebuilt, until then they only cause lookups that the underlying
+// store answers.
+type BloomRevocationStore struct {
+	store RevocationStore
+	n     int
+	p     float64
+
+	mu     sync.RWMutex
+	filter *bloomFilter
+	// next is the filter being rebuilt, which receives the revocations made
+	// while the store is listed.
+	next *bloomFilter
+}
+
+// NewBloomRevocationStore creates a new BloomRevocationStore sized for n
+// revocations with the false positive rate p.
+func NewBloomRevocationStore(store RevocationStore, n int, p float64) *BloomRevocationStore {
+	return &BloomRevocationStore{store: store, n: n, p: p, filter: newBloomFilter(n, p)}
+}
+
+// Preload adds the revocations already stored in the underlying store to the
+// filter.
+func (s *BloomRevocationStore) Preload(jtis, subjects []string) {
+	for _, jti := range jtis {
+		s.add("jti:" + jti)
+	}
+
+	for _, subject := range subjects {
+		s.add("sub:" + subject)
+	}
+}
+
+// Rebuild replaces the filter with a filter of the revocations listed by the
+// underlying store, it returns ErrNotListable if the store is not a
+// RevocationLister.
+func (s *BloomRevocationStore) Rebuild(ctx context.Context) error {
+	lister, ok := s.store.(RevocationLister)
+	if !ok {
+		return ErrNotListable
+	}
+
+	next := newBloomFilter(s.n, s.p)
+	s.mu.Lock()
+	s.next = next
+	s.mu.Unlock()
+
+	jtis, subjects, err := lister.Revocations(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = nil
+	if err != nil {
+		return err
+	}
+
+	for _, jti := range jtis {
+		next.add("jti:" + jti)
+	}
+
+	for _, subject := range subjects {
+		next.add("sub:" + subject)
+	}
+
+	s.filter = next
+	return nil
+}
+
+// Watch rebuilds the filter every interval until ctx is done. The rebuild
+// errors are reported to onError, which may be nil.
+func (s *BloomRevocationStore) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Rebuild(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (s *BloomRevocationStore) RevokeID(ctx context.Context, jti string, expiresAt time.Time) error {
+	// the filter is updated first, so that the revocation is never missed.
+	s.add("jti:" + jti)
+	return s.store.RevokeID(ctx, jti, expiresAt)
+}
+
+func (s *BloomRevocationStore) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	s.add("sub:" + subject)
+	return s.store.RevokeSubject(ctx, subject, issuedBefore, expiresAt)
+}
+
+func (s *BloomRevocationStore) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	if (jti == "" || !s.mayContain("jti:"+jti)) && (subject == "" || !s.mayContain("sub:"+subject)) {
+		return false, nil
+	}
+
+	return s.store.IsRevoked(ctx, jti, subject, issuedAt)
+}
+
+// add adds the key to the filter and to the filter being rebuilt.
+func (s *BloomRevocationStore) add(key string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.filter.add(key)
+	if s.next != nil {
+		s.next.add(key)
+	}
+}
+
+func (s *BloomRevocationStore) mayContain(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.mayContain(key)
+}
@@ -0,0 +1,109 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type countingRevocationStore struct {
+	RevocationStore
+	lookups int
+}
+
+func (s *countingRevocationStore) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	s.lookups++
+	return s.RevocationStore.IsRevoked(ctx, jti, subject, issuedAt)
+}
+
+func TestBloomFilter(t *testing.T) {
+	f := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.add("in-" + strconv.Itoa(i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !f.mayContain("in-" + strconv.Itoa(i)) {
+			t.Fatalf("expected no false negative for key %d", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.mayContain("out-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+
+	if rate := float64(falsePositives) / 10000; rate > 0.03 {
+		t.Errorf("expected false positive rate around 0.01 but got %v", rate)
+	}
+}
+
+func TestBloomRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	counting := &countingRevocationStore{RevocationStore: NewMemoryRevocationStore(c)}
+	store := NewBloomRevocationStore(counting, 100, 0.001)
+
+	_ = store.RevokeID(ctx, "revoked", c.Now().Add(time.Hour))
+	_ = store.RevokeSubject(ctx, "alice", c.Now(), c.Now().Add(time.Hour))
+
+	if revoked, _ := store.IsRevoked(ctx, "valid", "bob", c.Now()); revoked {
+		t.Errorf("expected the token not to be revoked")
+	}
+
+	if counting.lookups != 0 {
+		t.Errorf("expected the store not to be queried but got %d lookups", counting.lookups)
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, "revoked", "bob", c.Now()); !revoked {
+		t.Errorf("expected the jti to be revoked")
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, "valid", "alice", c.Now().Add(-time.Second)); !revoked {
+		t.Errorf("expected the subject to be revoked")
+	}
+
+	store.Preload([]string{"preloaded"}, nil)
+	if !store.mayContain("jti:preloaded") {
+		t.Errorf("expected the preloaded jti to be in the filter")
+	}
+}
+
+func TestBloomRevocationStore_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	shared := NewMemoryRevocationStore(c)
+	store := NewBloomRevocationStore(shared, 100, 0.001)
+	other := NewBloomRevocationStore(shared, 100, 0.001)
+
+	_ = other.RevokeID(ctx, "revoked", c.Now().Add(time.Hour))
+	if revoked, _ := store.IsRevoked(ctx, "revoked", "", c.Now()); revoked {
+		t.Fatalf("expected the revocation of another instance to be unknown before the rebuild")
+	}
+
+	if err := store.Rebuild(ctx); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, "revoked", "", c.Now()); !revoked {
+		t.Errorf("expected the revocation of another instance to be seen after the rebuild")
+	}
+
+	c.Add(time.Hour)
+	if err := store.Rebuild(ctx); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if store.mayContain("jti:revoked") {
+		t.Errorf("expected the expired revocation to be forgotten")
+	}
+
+	unlisted := NewBloomRevocationStore(&countingRevocationStore{RevocationStore: shared}, 100, 0.001)
+	if err := unlisted.Rebuild(ctx); !errors.Is(err, ErrNotListable) {
+		t.Errorf("expected %v but got %v", ErrNotListable, err)
+	}
+}
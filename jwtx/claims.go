@@ -0,0 +1,145 @@
+package jwtx
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/uniq"
+)
+
+// DefaultTTL is the default lifetime of the tokens created by Issue.
+const DefaultTTL = 15 * time.Minute
+
+// Claims are the registered claims with the common custom claims. Custom
+// claims types embed Claims to be used with Issue and Verify.
+// For example,
+//
+//	type UserClaims struct {
+//		jwtx.Claims
+//		Role string `json:"role"`
+//	}
+type Claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space-separated list of the granted scopes.
+	Scope string `json:"scope,omitempty"`
+	// Confirmation binds the token to a key, see DPoPAuth.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// Registered returns the registered claims.
+func (c *Claims) Registered() *jwt.RegisteredClaims { return &c.RegisteredClaims }
+
+// RegisteredClaimer is implemented by the claims types that embed Claims.
+type RegisteredClaimer interface {
+	jwt.Claims
+	// Registered returns the registered claims.
+	Registered() *jwt.RegisteredClaims
+}
+
+// claimsPointer constraints P to be a pointer to T that implements jwt.Claims.
+type claimsPointer[T any] interface {
+	*T
+	jwt.Claims
+}
+
+// registeredPointer constraints P to be a pointer to T that implements
+// RegisteredClaimer.
+type registeredPointer[T any] interface {
+	*T
+	RegisteredClaimer
+}
+
+// IssueOption is an option to configure how Issue populates the claims.
+type IssueOption func(*issuer)
+
+// WithIssueClock configures the clock used to populate the iat, nbf and exp
+// claims. The default is clock.UTC.
+func WithIssueClock(c clock.Clock) IssueOption {
+	return func(i *issuer) {
+		i.clock = c
+	}
+}
+
+// WithTTL configures the lifetime of the token, a non-positive ttl leaves the
+// exp claim empty. The default is DefaultTTL.
+func WithTTL(ttl time.Duration) IssueOption {
+	return func(i *issuer) {
+		i.ttl = ttl
+	}
+}
+
+// WithIDGenerator configures the generator of the jti claim. The default
+// generates random UUIDs.
+func WithIDGenerator(ids uniq.Stringer) IssueOption {
+	return func(i *issuer) {
+		i.ids = ids
+	}
+}
+
+// issuer populates the registered claims.
+type issuer struct {
+	clock clock.Clock
+	ttl   time.Duration
+	ids   uniq.Stringer
+}
+
+// Issue populates the empty iat, nbf, exp and jti claims and creates a token
+// from the claims. It returns the populated claims along with the token.
+func Issue[T any, P registeredPointer[T]](t Tokenizer, claims T, opts ...IssueOption) (string, T, error) {
+	i := issuer{
+		clock: clock.UTC,
+		ttl:   DefaultTTL,
+		ids:   uniq.NewUUID(uniq.RandomReader),
+	}
+
+	for _, opt := range opts {
+		opt(&i)
+	}
+
+	rc := P(&claims).Registered()
+	now := i.clock.Now()
+	if rc.IssuedAt == nil {
+		rc.IssuedAt = jwt.NewNumericDate(now)
+	}
+
+	if rc.NotBefore == nil {
+		rc.NotBefore = jwt.NewNumericDate(now)
+	}
+
+	if rc.ExpiresAt == nil && i.ttl > 0 {
+		rc.ExpiresAt = jwt.NewNumericDate(now.Add(i.ttl))
+	}
+
+	if rc.ID == "" {
+		id, err := i.ids.NextString()
+		if err != nil {
+			var zero T
+			return "", zero, err
+		}
+
+		rc.ID = id
+	}
+
+	token, err := t.Tokenize(P(&claims))
+	if err != nil {
+		var zero T
+		return "", zero, err
+	}
+
+	return token, claims, nil
+}
+
+// Verify validates the token and returns its claims.
+// For example,
+//
+//	claims, err := jwtx.Verify[UserClaims](tokenizer, token)
+func Verify[T any, P claimsPointer[T]](t Tokenizer, token string) (T, error) {
+	var claims T
+	if err := t.Detokenize(token, P(&claims)); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return claims, nil
+}
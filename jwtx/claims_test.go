@@ -0,0 +1,80 @@
+package jwtx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/uniq"
+)
+
+type userClaims struct {
+	Claims
+	Role string `json:"role"`
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	tokenizer := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")}, WithClock(clock.Static))
+
+	claims := userClaims{Role: "admin"}
+	claims.Subject = "user"
+	claims.Scope = "read write"
+
+	token, issued, err := Issue(tokenizer, claims,
+		WithIssueClock(clock.Static),
+		WithTTL(time.Hour),
+		WithIDGenerator(uniq.NewUUID(uniq.StaticReader)),
+	)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	now := clock.StaticTime
+	if !issued.IssuedAt.Equal(now) || !issued.NotBefore.Equal(now) || !issued.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected time claims %v, %v, %v", issued.IssuedAt, issued.NotBefore, issued.ExpiresAt)
+	}
+
+	if issued.ID != uniq.StaticUUID {
+		t.Errorf("expected jti %s but got %s", uniq.StaticUUID, issued.ID)
+	}
+
+	verified, err := Verify[userClaims](tokenizer, token)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if verified.Role != "admin" || verified.Subject != "user" || verified.Scope != "read write" || verified.ID != issued.ID {
+		t.Errorf("unexpected claims %+v", verified)
+	}
+
+	if _, err := Verify[userClaims](tokenizer, token+"x"); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected %v but got %v", ErrBadSignature, err)
+	}
+}
+
+func TestIssue_KeepsClaims(t *testing.T) {
+	tokenizer := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")})
+
+	exp := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "my-id", ExpiresAt: exp}}
+
+	_, issued, err := Issue(tokenizer, claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if issued.ID != "my-id" || !issued.ExpiresAt.Equal(exp.Time) {
+		t.Errorf("expected the claims to be kept but got %+v", issued)
+	}
+
+	_, _, err = Issue(tokenizer, Claims{}, WithIDGenerator(uniq.NewUUID(uniq.EOFReader)))
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+
+	if _, _, err := Issue(NewHS256Tokenizer(nil), Claims{}); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected %v but got %v", ErrNoSigningKey, err)
+	}
+}
@@ -0,0 +1,418 @@
+package jwtx
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/uniq"
+)
+
+// DPoPHeader is the request header that carries the DPoP proof.
+const DPoPHeader = "DPoP"
+
+// DefaultDPoPMaxAge is the default maximum age of the DPoP proofs.
+const DefaultDPoPMaxAge = time.Minute
+
+var (
+	// ErrInvalidDPoPProof is returned when the DPoP proof is missing or
+	// invalid. errors.Is also matches the cause, for example ErrBadSignature.
+	ErrInvalidDPoPProof = errors.New("jwtx: invalid DPoP proof")
+
+	// ErrDPoPReplayed is returned when the DPoP proof was already used.
+	ErrDPoPReplayed = errors.New("jwtx: DPoP proof replayed")
+
+	// ErrDPoPKeyMismatch is returned when the DPoP proof key is not the key
+	// bound to the access token, or when the access token is not bound.
+	ErrDPoPKeyMismatch = errors.New("jwtx: DPoP key does not match the token")
+
+	// ErrInvalidDPoPMaxAge is returned when the maximum age of the DPoP
+	// proofs is not positive.
+	ErrInvalidDPoPMaxAge = errors.New("jwtx: DPoP max age must be positive")
+)
+
+// Confirmation is the cnf claim of RFC 7800, which binds a token to a key.
+type Confirmation struct {
+	// JKT is the JWK thumbprint of the DPoP key, see RFC 9449.
+	JKT string `json:"jkt,omitempty"`
+}
+
+// ReplayCache remembers the identifiers of the used DPoP proofs.
+type ReplayCache interface {
+	// Use records the id until expiresAt, it returns ErrDPoPReplayed when
+	// the id is already recorded.
+	Use(ctx context.Context, id string, expiresAt time.Time) error
+}
+
+// DPoPOption is an option to configure the DPoPVerifier.
+type DPoPOption func(*DPoPVerifier)
+
+// WithDPoPClock configures the clock used to validate the iat claim of the
+// proofs. The default is clock.UTC.
+func WithDPoPClock(c clock.Clock) DPoPOption {
+	return func(v *DPoPVerifier) {
+		v.clock = c
+	}
+}
+
+// WithDPoPMaxAge configures the maximum age of the proofs, measured from
+// their iat claim. It must be positive, the default is DefaultDPoPMaxAge.
+func WithDPoPMaxAge(maxAge time.Duration) DPoPOption {
+	return func(v *DPoPVerifier) {
+		v.maxAge = maxAge
+	}
+}
+
+// WithDPoPLeeway configures the clock skew tolerated on the iat claim of the
+// proofs.
+func WithDPoPLeeway(leeway time.Duration) DPoPOption {
+	return func(v *DPoPVerifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithDPoPAlgorithms configures the algorithms accepted for the proofs. By
+// default every asymmetric algorithm is accepted.
+func WithDPoPAlgorithms(algs ...string) DPoPOption {
+	return func(v *DPoPVerifier) {
+		v.algs = algs
+	}
+}
+
+// WithDPoPRequestURL configures how VerifyRequest builds the URL of the
+// request, for example from the public URL of a service behind a proxy. The
+// default uses the Host header, and https when the request uses TLS.
+func WithDPoPRequestURL(fn func(r *http.Request) string) DPoPOption {
+	return func(v *DPoPVerifier) {
+		v.requestURL = fn
+	}
+}
+
+// DPoPVerifier verifies the DPoP proofs of RFC 9449, which prove that the
+// client holds the private key bound to its access token. Every proof is
+// only accepted once, its jti is recorded in the replay cache.
+type DPoPVerifier struct {
+	cache      ReplayCache
+	clock      clock.Clock
+	maxAge     time.Duration
+	leeway     time.Duration
+	algs       []string
+	requestURL func(r *http.Request) string
+	parser     *parser
+}
+
+// NewDPoPVerifier creates a new DPoPVerifier. The proofs must carry the jti,
+// htm, htu and iat claims, see RFC 9449 section 4.2. It returns
+// ErrInvalidDPoPMaxAge when the maximum age is not positive.
+func NewDPoPVerifier(cache ReplayCache, opts ...DPoPOption) (*DPoPVerifier, error) {
+	v := &DPoPVerifier{
+		cache:      cache,
+		clock:      clock.UTC,
+		maxAge:     DefaultDPoPMaxAge,
+		algs:       asymmetricAlgorithms,
+		requestURL: requestURL,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if v.maxAge <= 0 {
+		return nil, ErrInvalidDPoPMaxAge
+	}
+
+	v.parser = newParser(v.algs,
+		WithType("dpop+jwt"),
+		WithClock(v.clock),
+		WithLeeway(v.leeway),
+		WithMaxAge(v.maxAge),
+		WithRequiredClaims("jti", "htm", "htu", "iat"),
+	)
+
+	return v, nil
+}
+
+// Algorithms returns the algorithms accepted for the proofs.
+func (v *DPoPVerifier) Algorithms() []string { return v.algs }
+
+// dpopClaims are the claims of a DPoP proof.
+type dpopClaims struct {
+	jwt.RegisteredClaims
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+}
+
+// VerifyRequest verifies the DPoP proof of the request, see Verify.
+func (v *DPoPVerifier) VerifyRequest(r *http.Request, accessToken string) (string, error) {
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) != 1 {
+		return "", &tokenError{kind: ErrInvalidDPoPProof, cause: fmt.Errorf("expected one %s header but got %d", DPoPHeader, len(proofs))}
+	}
+
+	return v.Verify(r.Context(), proofs[0], r.Method, v.requestURL(r), accessToken)
+}
+
+// Verify verifies the proof of a request with the given method and URL, and
+// returns the thumbprint of the proof key. When an access token is given,
+// the proof must carry its hash in the ath claim.
+func (v *DPoPVerifier) Verify(ctx context.Context, proof, method, rawURL, accessToken string) (string, error) {
+	var (
+		jkt    string
+		claims dpopClaims
+	)
+
+	err := v.parser.parse(proof, &claims, func(t *jwt.Token) (interface{}, error) {
+		jwk, err := proofJWK(t.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+
+		if jkt, err = jwk.ComputeThumbprint(); err != nil {
+			return nil, err
+		}
+
+		return jwk.PublicKey()
+	})
+
+	if err != nil {
+		return "", &tokenError{kind: ErrInvalidDPoPProof, cause: err}
+	}
+
+	if claims.HTM != method {
+		return "", &tokenError{kind: ErrInvalidDPoPProof, cause: fmt.Errorf("htm %q does not match the method %q", claims.HTM, method)}
+	}
+
+	if htu, target := normalizeHTU(claims.HTU), normalizeHTU(rawURL); htu == "" || htu != target {
+		return "", &tokenError{kind: ErrInvalidDPoPProof, cause: fmt.Errorf("htu %q does not match the URL %q", claims.HTU, rawURL)}
+	}
+
+	if accessToken != "" && claims.ATH != accessTokenHash(accessToken) {
+		return "", &tokenError{kind: ErrInvalidDPoPProof, cause: errors.New("ath does not match the access token")}
+	}
+
+	expiresAt := claims.IssuedAt.Add(v.maxAge + v.leeway)
+	if err := v.cache.Use(ctx, jkt+":"+claims.ID, expiresAt); err != nil {
+		return "", err
+	}
+
+	return jkt, nil
+}
+
+// proofJWK decodes the jwk header of a proof, which must be a public key.
+func proofJWK(header interface{}) (JWK, error) {
+	if header == nil {
+		return JWK{}, errors.New("missing jwk header")
+	}
+
+	b, err := json.Marshal(header)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	var jwk struct {
+		JWK
+		D string `json:"d"`
+	}
+
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return JWK{}, err
+	}
+
+	if jwk.D != "" {
+		return JWK{}, errors.New("jwk header contains a private key")
+	}
+
+	return jwk.JWK, nil
+}
+
+// normalizeHTU normalizes the URL as the htu claim is compared, without
+// query and fragment. It returns an empty string for invalid URLs.
+func normalizeHTU(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return ""
+	}
+
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if (scheme == "https" && strings.HasSuffix(host, ":443")) || (scheme == "http" && strings.HasSuffix(host, ":80")) {
+		host = host[:strings.LastIndexByte(host, ':')]
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return scheme + "://" + host + path
+}
+
+// requestURL is the default URL of the request compared with the htu claim.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+// accessTokenHash is the ath claim of the access token.
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return encodeSegment(sum[:])
+}
+
+// DPoPProver creates the DPoP proofs of a client with its private key.
+type DPoPProver struct {
+	key    crypto.Signer
+	method jwt.SigningMethod
+	jwk    JWK
+	issuer issuer
+}
+
+// NewDPoPProver creates a new DPoPProver for a RSA, ECDSA or Ed25519 key.
+// The clock and the jti generator can be configured with WithIssueClock and
+// WithIDGenerator.
+func NewDPoPProver(key crypto.Signer, opts ...IssueOption) (*DPoPProver, error) {
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := NewJWK("", "", key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &DPoPProver{
+		key:    key,
+		method: method,
+		jwk:    jwk,
+		issuer: issuer{clock: clock.UTC, ids: uniq.NewUUID(uniq.RandomReader)},
+	}
+
+	for _, opt := range opts {
+		opt(&p.issuer)
+	}
+
+	return p, nil
+}
+
+// Thumbprint returns the JWK thumbprint of the key, which is bound to the
+// access tokens in their cnf claim.
+func (p *DPoPProver) Thumbprint() string { return p.jwk.Thumbprint }
+
+// Proof creates a proof for a request with the given method and URL. The
+// access token, if any, is bound to the proof with the ath claim.
+func (p *DPoPProver) Proof(method, rawURL, accessToken string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.RawQuery, u.Fragment = "", ""
+
+	jti, err := p.issuer.ids.NextString()
+	if err != nil {
+		return "", err
+	}
+
+	claims := dpopClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       jti,
+			IssuedAt: jwt.NewNumericDate(p.issuer.clock.Now()),
+		},
+		HTM: method,
+		HTU: u.String(),
+	}
+
+	if accessToken != "" {
+		claims.ATH = accessTokenHash(accessToken)
+	}
+
+	public := p.jwk
+	public.Thumbprint = ""
+	token := &jwt.Token{
+		Header: map[string]interface{}{
+			"typ": "dpop+jwt",
+			"alg": p.method.Alg(),
+			"jwk": public,
+		},
+		Claims: claims,
+		Method: p.method,
+	}
+
+	return token.SignedString(p.key)
+}
+
+// minPruneSize is the size from which MemoryReplayCache removes the expired
+// entries.
+const minPruneSize = 1024
+
+// MemoryReplayCache is an in-memory ReplayCache. The expired entries are
+// removed whenever the cache doubled in size since the last removal, which
+// keeps the cost of every Use constant on average.
+type MemoryReplayCache struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	ids       map[string]time.Time
+	pruneSize int
+}
+
+// NewMemoryReplayCache creates a new MemoryReplayCache that expires the
+// entries with the given clock.
+func NewMemoryReplayCache(c clock.Clock) *MemoryReplayCache {
+	return &MemoryReplayCache{
+		clock:     c,
+		ids:       make(map[string]time.Time),
+		pruneSize: minPruneSize,
+	}
+}
+
+// Use records the id until expiresAt, it returns ErrDPoPReplayed if the id is
+// already recorded and not expired yet.
+func (c *MemoryReplayCache) Use(_ context.Context, id string, expiresAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if expiresAt, ok := c.ids[id]; ok && now.Before(expiresAt) {
+		return ErrDPoPReplayed
+	}
+
+	if len(c.ids) >= c.pruneSize {
+		c.prune(now)
+		c.pruneSize = 2*len(c.ids) + minPruneSize
+	}
+
+	c.ids[id] = expiresAt
+	return nil
+}
+
+// Len returns the number of the recorded entries, including the expired
+// entries that are not removed yet.
+func (c *MemoryReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.ids)
+}
+
+func (c *MemoryReplayCache) prune(now time.Time) {
+	for id, expiresAt := range c.ids {
+		if !now.Before(expiresAt) {
+			delete(c.ids, id)
+		}
+	}
+}
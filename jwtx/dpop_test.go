@@ -0,0 +1,293 @@
+package jwtx
+
+import (
+	"context"
+	"crypto"
+	"crypto/elliptic"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+func mustDPoPProver(t *testing.T, key crypto.Signer, c *fakeClock) *DPoPProver {
+	t.Helper()
+	p, err := NewDPoPProver(key, WithIssueClock(c))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return p
+}
+
+func mustDPoPVerifier(t *testing.T, cache ReplayCache, opts ...DPoPOption) *DPoPVerifier {
+	t.Helper()
+	v, err := NewDPoPVerifier(cache, opts...)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return v
+}
+
+func mustProof(t *testing.T, p *DPoPProver, method, url, accessToken string) string {
+	t.Helper()
+	proof, err := p.Proof(method, url, accessToken)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return proof
+}
+
+func TestDPoPVerifier_Verify(t *testing.T) {
+	c := newFakeClock()
+	keys := map[string]crypto.Signer{
+		"ES256": mustECDSAKey(t, elliptic.P256()),
+		"EdDSA": mustEd25519Key(t),
+		"RS256": mustRSAKey(t),
+	}
+
+	for alg, key := range keys {
+		t.Run(alg, func(t *testing.T) {
+			p := mustDPoPProver(t, key, c)
+			v := mustDPoPVerifier(t, NewMemoryReplayCache(c), WithDPoPClock(c))
+
+			proof := mustProof(t, p, "POST", "https://api.example.com/orders?page=2#top", "access-token")
+			jkt, err := v.Verify(context.Background(), proof, "POST", "https://API.example.com:443/orders", "access-token")
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if jkt == "" || jkt != p.Thumbprint() {
+				t.Errorf("expected thumbprint %q but got %q", p.Thumbprint(), jkt)
+			}
+
+			if _, err := v.Verify(context.Background(), proof, "POST", "https://api.example.com/orders", "access-token"); !errors.Is(err, ErrDPoPReplayed) {
+				t.Errorf("expected error %v but got %v", ErrDPoPReplayed, err)
+			}
+		})
+	}
+}
+
+func TestDPoPVerifier_Verify_Invalid(t *testing.T) {
+	const url = "https://api.example.com/orders"
+	c := newFakeClock()
+	p := mustDPoPProver(t, mustECDSAKey(t, elliptic.P256()), c)
+	key := mustECDSAKey(t, elliptic.P256())
+
+	sign := func(header map[string]interface{}, claims dpopClaims) string {
+		token := &jwt.Token{Header: header, Claims: claims, Method: jwt.SigningMethodES256}
+
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		return s
+	}
+
+	withHeader := func(header map[string]interface{}) string {
+		return sign(header, dpopClaims{
+			RegisteredClaims: jwt.RegisteredClaims{ID: "id", IssuedAt: jwt.NewNumericDate(c.Now())},
+			HTM:              "GET",
+			HTU:              url,
+		})
+	}
+
+	jwk, err := NewJWK("", "", key.Public())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		proof       func() string
+		method      string
+		url         string
+		accessToken string
+		err         error
+	}{
+		{
+			name:   "wrong method",
+			proof:  func() string { return mustProof(t, p, "GET", url, "") },
+			method: "POST",
+			url:    url,
+			err:    ErrInvalidDPoPProof,
+		},
+		{
+			name:   "wrong url",
+			proof:  func() string { return mustProof(t, p, "GET", url, "") },
+			method: "GET",
+			url:    "https://api.example.com/users",
+			err:    ErrInvalidDPoPProof,
+		},
+		{
+			name:        "missing ath",
+			proof:       func() string { return mustProof(t, p, "GET", url, "") },
+			method:      "GET",
+			url:         url,
+			accessToken: "access-token",
+			err:         ErrInvalidDPoPProof,
+		},
+		{
+			name:        "wrong ath",
+			proof:       func() string { return mustProof(t, p, "GET", url, "other-token") },
+			method:      "GET",
+			url:         url,
+			accessToken: "access-token",
+			err:         ErrInvalidDPoPProof,
+		},
+		{
+			name: "too old",
+			proof: func() string {
+				proof := mustProof(t, p, "GET", url, "")
+				c.Add(DefaultDPoPMaxAge + time.Second)
+				return proof
+			},
+			method: "GET",
+			url:    url,
+			err:    ErrTokenTooOld,
+		},
+		{
+			name: "issued in the future",
+			proof: func() string {
+				c.Add(time.Minute)
+				defer c.Add(-time.Minute)
+				return mustProof(t, p, "GET", url, "")
+			},
+			method: "GET",
+			url:    url,
+			err:    ErrInvalidClaims,
+		},
+		{
+			name: "missing iat",
+			proof: func() string {
+				return sign(map[string]interface{}{"typ": "dpop+jwt", "alg": "ES256", "jwk": jwk}, dpopClaims{
+					RegisteredClaims: jwt.RegisteredClaims{ID: "id"},
+					HTM:              "GET",
+					HTU:              url,
+				})
+			},
+			method: "GET",
+			url:    url,
+			err:    ErrMissingClaim,
+		},
+		{
+			name:   "wrong type",
+			proof:  func() string { return withHeader(map[string]interface{}{"typ": "JWT", "alg": "ES256", "jwk": jwk}) },
+			method: "GET",
+			url:    url,
+			err:    ErrWrongType,
+		},
+		{
+			name:   "missing jwk",
+			proof:  func() string { return withHeader(map[string]interface{}{"typ": "dpop+jwt", "alg": "ES256"}) },
+			method: "GET",
+			url:    url,
+			err:    ErrInvalidDPoPProof,
+		},
+		{
+			name: "private jwk",
+			proof: func() string {
+				return withHeader(map[string]interface{}{"typ": "dpop+jwt", "alg": "ES256", "jwk": map[string]string{
+					"kty": jwk.Kty, "crv": jwk.Crv, "x": jwk.X, "y": jwk.Y, "d": encodeSegment(key.D.Bytes()),
+				}})
+			},
+			method: "GET",
+			url:    url,
+			err:    ErrInvalidDPoPProof,
+		},
+		{
+			name: "other key",
+			proof: func() string {
+				other, err := NewJWK("", "", mustECDSAKey(t, elliptic.P256()).Public())
+				if err != nil {
+					t.Fatalf("expected no error but got %v", err)
+				}
+
+				return withHeader(map[string]interface{}{"typ": "dpop+jwt", "alg": "ES256", "jwk": other})
+			},
+			method: "GET",
+			url:    url,
+			err:    ErrBadSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := mustDPoPVerifier(t, NewMemoryReplayCache(c), WithDPoPClock(c))
+			_, err := v.Verify(context.Background(), tt.proof(), tt.method, tt.url, tt.accessToken)
+			if !errors.Is(err, tt.err) || !errors.Is(err, ErrInvalidDPoPProof) {
+				t.Errorf("expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("valid header", func(t *testing.T) {
+		v := mustDPoPVerifier(t, NewMemoryReplayCache(c), WithDPoPClock(c))
+		proof := withHeader(map[string]interface{}{"typ": "dpop+jwt", "alg": "ES256", "jwk": jwk})
+		if _, err := v.Verify(context.Background(), proof, "GET", url, ""); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	})
+}
+
+func TestNewDPoPVerifier_MaxAge(t *testing.T) {
+	for _, maxAge := range []time.Duration{0, -time.Second} {
+		if _, err := NewDPoPVerifier(NewMemoryReplayCache(clock.UTC), WithDPoPMaxAge(maxAge)); !errors.Is(err, ErrInvalidDPoPMaxAge) {
+			t.Errorf("max age %v: expected error %v but got %v", maxAge, ErrInvalidDPoPMaxAge, err)
+		}
+	}
+}
+
+func TestNormalizeHTU(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://api.example.com/orders", "https://api.example.com/orders"},
+		{"HTTPS://API.example.com:443/orders?a=b#c", "https://api.example.com/orders"},
+		{"http://localhost:80", "http://localhost/"},
+		{"http://localhost:8080/a", "http://localhost:8080/a"},
+		{"/orders", ""},
+		{"://bad", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeHTU(tt.in); got != tt.want {
+			t.Errorf("expected %q but got %q", tt.want, got)
+		}
+	}
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	c := newFakeClock()
+	cache := NewMemoryReplayCache(c)
+	ctx := context.Background()
+
+	if err := cache.Use(ctx, "a", c.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := cache.Use(ctx, "a", c.Now().Add(time.Minute)); !errors.Is(err, ErrDPoPReplayed) {
+		t.Errorf("expected error %v but got %v", ErrDPoPReplayed, err)
+	}
+
+	c.Add(time.Minute)
+	if err := cache.Use(ctx, "a", c.Now().Add(time.Minute)); err != nil {
+		t.Errorf("expected the expired entry to be forgotten but got %v", err)
+	}
+
+	for i := 0; i < minPruneSize-1; i++ {
+		_ = cache.Use(ctx, string(rune('b'+i)), c.Now().Add(time.Second))
+	}
+
+	c.Add(time.Second)
+	_ = cache.Use(ctx, "last", c.Now().Add(time.Minute))
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expected the expired entries to be removed but got %d entries", n)
+	}
+}
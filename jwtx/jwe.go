@@ -0,0 +1,334 @@
+package jwtx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrDecryption is returned when an encrypted token can not be decrypted.
+var ErrDecryption = errors.New("jwtx: decryption failed")
+
+// The supported JWE algorithms.
+const (
+	// AlgRSAOAEP256 encrypts a random content encryption key with RSA-OAEP
+	// using SHA-256.
+	AlgRSAOAEP256 = "RSA-OAEP-256"
+	// AlgDir uses the shared key as the content encryption key.
+	AlgDir = "dir"
+	// EncA256GCM encrypts the content with AES-GCM using a 256-bit key.
+	EncA256GCM = "A256GCM"
+)
+
+// jweHeader is the protected header of the encrypted tokens.
+type jweHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+	Cty string `json:"cty,omitempty"`
+	Zip string `json:"zip,omitempty"`
+}
+
+// JWETokenizer is a tokenizer that encrypts the claims as a JSON Web
+// Encryption in compact serialization, with A256GCM content encryption.
+// It encrypts with its keys in round-robin order, and decrypts with the key
+// addressed by the kid header.
+//
+// The encryption only provides confidentiality. With RSA-OAEP, anyone who
+// has the public key can create a token the tokenizer accepts, so the claims
+// are not authenticated. Use NestedTokenizer to sign the claims before they
+// are encrypted.
+type JWETokenizer struct {
+	alg      string
+	keys     map[string]interface{}
+	indexes  []string
+	position uint32
+	parser   *parser
+}
+
+// NewRSAOAEPTokenizer creates a new JWETokenizer that uses the RSA-OAEP-256
+// key management algorithm.
+func NewRSAOAEPTokenizer(keys map[string]*rsa.PrivateKey, opts ...Option) *JWETokenizer {
+	m := make(map[string]interface{}, len(keys))
+	for kid, key := range keys {
+		m[kid] = key
+	}
+
+	return newJWETokenizer(AlgRSAOAEP256, m, opts)
+}
+
+// NewDirTokenizer creates a new JWETokenizer that uses the 256-bit shared
+// keys directly as content encryption keys.
+func NewDirTokenizer(keys map[string][]byte, opts ...Option) *JWETokenizer {
+	m := make(map[string]interface{}, len(keys))
+	for kid, key := range keys {
+		m[kid] = key
+	}
+
+	return newJWETokenizer(AlgDir, m, opts)
+}
+
+func newJWETokenizer(alg string, keys map[string]interface{}, opts []Option) *JWETokenizer {
+	indexes := make([]string, 0, len(keys))
+	for kid := range keys {
+		indexes = append(indexes, kid)
+	}
+
+	sort.Strings(indexes)
+
+	return &JWETokenizer{
+		alg:     alg,
+		keys:    keys,
+		indexes: indexes,
+		parser:  newParser([]string{alg}, opts...),
+	}
+}
+
+// Algorithm returns the key management algorithm.
+func (t *JWETokenizer) Algorithm() string { return t.alg }
+
+func (t *JWETokenizer) Tokenize(claims jwt.Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return t.encrypt(payload, "")
+}
+
+func (t *JWETokenizer) Detokenize(token string, claims jwt.Claims) error {
+	header, payload, err := t.decrypt(token)
+	if err != nil {
+		return err
+	}
+
+	if header.Cty != "" {
+		return &tokenError{kind: ErrWrongType, cause: fmt.Errorf("unexpected content type %q", header.Cty)}
+	}
+
+	rc, present, err := parseClaims(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return &tokenError{kind: ErrMalformed, cause: err}
+	}
+
+	if err := t.parser.validate(rc, present); err != nil {
+		return err
+	}
+
+	return validClaims(claims)
+}
+
+// encrypt encrypts the payload with the next key.
+func (t *JWETokenizer) encrypt(payload []byte, cty string) (string, error) {
+	if len(t.indexes) == 0 {
+		return "", ErrNoSigningKey
+	}
+
+	kid := t.indexes[int(atomic.AddUint32(&t.position, 1)%uint32(len(t.indexes)))]
+	cek, encryptedKey, err := t.newContentKey(t.keys[kid])
+	if err != nil {
+		return "", err
+	}
+
+	typ := t.parser.typ
+	if typ == "" {
+		typ = "JWT"
+	}
+
+	header, err := json.Marshal(jweHeader{Alg: t.alg, Enc: EncA256GCM, Kid: kid, Typ: typ, Cty: cty})
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	// the additional authenticated data is the encoded protected header.
+	protected := encodeSegment(header)
+	sealed := gcm.Seal(nil, iv, payload, []byte(protected))
+	ciphertext, tag := sealed[:len(payload)], sealed[len(payload):]
+
+	return strings.Join([]string{
+		protected,
+		encodeSegment(encryptedKey),
+		encodeSegment(iv),
+		encodeSegment(ciphertext),
+		encodeSegment(tag),
+	}, "."), nil
+}
+
+// decrypt verifies the header and decrypts the payload of the token.
+func (t *JWETokenizer) decrypt(token string) (jweHeader, []byte, error) {
+	if t.parser.maxLength > 0 && len(token) > t.parser.maxLength {
+		return jweHeader{}, nil, ErrTokenTooLong
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return jweHeader{}, nil, &tokenError{kind: ErrMalformed, cause: errors.New("token must have 5 segments")}
+	}
+
+	segments := make([][]byte, len(parts))
+	for i, part := range parts {
+		b, err := decodeSegment(part)
+		if err != nil {
+			return jweHeader{}, nil, &tokenError{kind: ErrMalformed, cause: err}
+		}
+
+		segments[i] = b
+	}
+
+	var header jweHeader
+	if err := json.Unmarshal(segments[0], &header); err != nil {
+		return jweHeader{}, nil, &tokenError{kind: ErrMalformed, cause: err}
+	}
+
+	if !t.parser.accepts(header.Alg) || header.Enc != EncA256GCM {
+		return jweHeader{}, nil, &tokenError{kind: ErrWrongAlg, cause: fmt.Errorf("unexpected algorithm %q with %q", header.Alg, header.Enc)}
+	}
+
+	if header.Zip != "" {
+		return jweHeader{}, nil, &tokenError{kind: ErrMalformed, cause: fmt.Errorf("unsupported compression %q", header.Zip)}
+	}
+
+	if t.parser.typ != "" && !strings.EqualFold(header.Typ, t.parser.typ) {
+		return jweHeader{}, nil, &tokenError{kind: ErrWrongType, cause: fmt.Errorf("unexpected type %q", header.Typ)}
+	}
+
+	key, ok := t.keys[header.Kid]
+	if !ok {
+		return jweHeader{}, nil, unknownKID
+	}
+
+	cek, err := t.contentKey(key, segments[1])
+	if err != nil {
+		return jweHeader{}, nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return jweHeader{}, nil, err
+	}
+
+	iv, ciphertext, tag := segments[2], segments[3], segments[4]
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return jweHeader{}, nil, &tokenError{kind: ErrMalformed, cause: errors.New("invalid iv or tag size")}
+	}
+
+	payload, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return jweHeader{}, nil, &tokenError{kind: ErrDecryption, cause: err}
+	}
+
+	return header, payload, nil
+}
+
+// newContentKey returns the content encryption key and its encrypted form.
+func (t *JWETokenizer) newContentKey(key interface{}) (cek, encryptedKey []byte, err error) {
+	switch key := key.(type) {
+	case []byte:
+		return key, nil, nil
+	case *rsa.PrivateKey:
+		cek = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return nil, nil, err
+		}
+
+		encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, cek, nil)
+		return cek, encryptedKey, err
+	}
+
+	return nil, nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+}
+
+// contentKey recovers the content encryption key from its encrypted form.
+func (t *JWETokenizer) contentKey(key interface{}, encryptedKey []byte) ([]byte, error) {
+	switch key := key.(type) {
+	case []byte:
+		if len(encryptedKey) != 0 {
+			return nil, &tokenError{kind: ErrMalformed, cause: errors.New("dir must have an empty encrypted key")}
+		}
+
+		return key, nil
+	case *rsa.PrivateKey:
+		cek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, encryptedKey, nil)
+		if err != nil {
+			return nil, &tokenError{kind: ErrDecryption, cause: err}
+		}
+
+		return cek, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+}
+
+// newGCM creates the A256GCM cipher.
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	if len(cek) != 32 {
+		return nil, fmt.Errorf("%w: A256GCM requires a 256-bit key", ErrUnsupportedKey)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// NestedTokenizer signs the claims with a signing tokenizer, then encrypts the
+// signed token, as recommended to both protect and authenticate the claims.
+type NestedTokenizer struct {
+	signer    Tokenizer
+	encrypter *JWETokenizer
+}
+
+// NewNestedTokenizer creates a new NestedTokenizer.
+func NewNestedTokenizer(signer Tokenizer, encrypter *JWETokenizer) *NestedTokenizer {
+	return &NestedTokenizer{signer: signer, encrypter: encrypter}
+}
+
+func (t *NestedTokenizer) Tokenize(claims jwt.Claims) (string, error) {
+	signed, err := t.signer.Tokenize(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return t.encrypter.encrypt([]byte(signed), "JWT")
+}
+
+func (t *NestedTokenizer) Detokenize(token string, claims jwt.Claims) error {
+	header, payload, err := t.encrypter.decrypt(token)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(header.Cty, "JWT") {
+		return &tokenError{kind: ErrWrongType, cause: fmt.Errorf("unexpected content type %q", header.Cty)}
+	}
+
+	return t.signer.Detokenize(string(payload), claims)
+}
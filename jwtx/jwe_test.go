@@ -0,0 +1,146 @@
+package jwtx
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+func TestJWETokenizer(t *testing.T) {
+	rsaTokenizer := NewRSAOAEPTokenizer(map[string]*rsa.PrivateKey{"key-1": mustRSAKey(t)})
+	dirTokenizer := NewDirTokenizer(map[string][]byte{"key-1": bytes.Repeat([]byte{1}, 32)})
+
+	for _, tokenizer := range []*JWETokenizer{rsaTokenizer, dirTokenizer} {
+		t.Run(tokenizer.Algorithm(), func(t *testing.T) {
+			testTokenizeAndDetokenize(t, tokenizer)
+
+			claims := userClaims{Role: "admin"}
+			claims.Subject = "user"
+			token, _, err := Issue(tokenizer, claims)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if n := strings.Count(token, "."); n != 4 {
+				t.Fatalf("expected 5 segments but got %d", n+1)
+			}
+
+			if strings.Contains(token, "admin") {
+				t.Errorf("expected the claims to be encrypted")
+			}
+
+			decoded, err := Verify[userClaims](tokenizer, token)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if decoded.Role != "admin" || decoded.Subject != "user" {
+				t.Errorf("unexpected claims %+v", decoded)
+			}
+
+			parts := strings.Split(token, ".")
+			ciphertext := mustDecodeSegment(t, parts[3])
+			ciphertext[0] ^= 1
+			parts[3] = encodeSegment(ciphertext)
+			if err := tokenizer.Detokenize(strings.Join(parts, "."), &Claims{}); !errors.Is(err, ErrDecryption) {
+				t.Errorf("expected %v but got %v", ErrDecryption, err)
+			}
+		})
+	}
+
+	token, err := dirTokenizer.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := rsaTokenizer.Detokenize(token, &Claims{}); !errors.Is(err, ErrWrongAlg) {
+		t.Errorf("expected %v but got %v", ErrWrongAlg, err)
+	}
+
+	other := NewDirTokenizer(map[string][]byte{"key-2": bytes.Repeat([]byte{1}, 32)})
+	if err := other.Detokenize(token, &Claims{}); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected %v but got %v", ErrUnknownKID, err)
+	}
+
+	if err := dirTokenizer.Detokenize("a.b.c", &Claims{}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+
+	short := NewDirTokenizer(map[string][]byte{"key-1": []byte("short")})
+	if _, err := short.Tokenize(jwt.RegisteredClaims{}); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("expected %v but got %v", ErrUnsupportedKey, err)
+	}
+}
+
+func mustDecodeSegment(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := decodeSegment(s)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return b
+}
+
+func TestJWETokenizer_ClaimsValidation(t *testing.T) {
+	key := map[string][]byte{"key-1": bytes.Repeat([]byte{1}, 32)}
+	tokenizer := NewDirTokenizer(key, WithClock(clock.Static), WithAudience("api"))
+
+	claims := jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{"api"},
+		ExpiresAt: jwt.NewNumericDate(clock.StaticTime.Add(-time.Second)),
+	}
+
+	token, err := tokenizer.Tokenize(claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(token, &jwt.RegisteredClaims{}); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected %v but got %v", ErrExpired, err)
+	}
+}
+
+func TestNestedTokenizer(t *testing.T) {
+	signer := NewEdDSATokenizer(map[string]ed25519.PrivateKey{"sig": mustEd25519Key(t)})
+	encrypter := NewRSAOAEPTokenizer(map[string]*rsa.PrivateKey{"enc": mustRSAKey(t)})
+	tokenizer := NewNestedTokenizer(signer, encrypter)
+
+	testTokenizeAndDetokenize(t, tokenizer)
+
+	token, err := tokenizer.Tokenize(jwt.RegisteredClaims{Subject: "user"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// the encrypted signed token is not a plain encrypted token.
+	if err := encrypter.Detokenize(token, &Claims{}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected %v but got %v", ErrWrongType, err)
+	}
+
+	plain, err := encrypter.Tokenize(jwt.RegisteredClaims{Subject: "user"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(plain, &Claims{}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected %v but got %v", ErrWrongType, err)
+	}
+
+	forged := NewNestedTokenizer(NewEdDSATokenizer(map[string]ed25519.PrivateKey{"sig": mustEd25519Key(t)}), encrypter)
+	token, err = forged.Tokenize(jwt.RegisteredClaims{Subject: "user"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(token, &Claims{}); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected %v but got %v", ErrBadSignature, err)
+	}
+}
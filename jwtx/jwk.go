@@ -0,0 +1,223 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// JWK is a public JSON Web Key as defined in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+
+	// RSA public key members.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP public key members.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// Thumbprint is the RFC 7638 SHA-256 thumbprint of the key.
+	Thumbprint string `json:"jkt,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Lookup returns the key with the given kid.
+func (s JWKSet) Lookup(kid string) (JWK, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+
+	return JWK{}, false
+}
+
+// PublicKeySource is implemented by the asymmetric tokenizers to expose the
+// public halves of their keys.
+type PublicKeySource interface {
+	// Algorithm returns the algorithm used to sign the tokens.
+	Algorithm() string
+	// PublicKeys returns the public keys addressed by kid.
+	PublicKeys() map[string]crypto.PublicKey
+}
+
+// JWKSetSource is implemented by the sources whose keys use different
+// algorithms, NewJWKSet uses their own key set instead of PublicKeys.
+type JWKSetSource interface {
+	PublicKeySource
+	// JWKSet returns the public keys as a JWKSet.
+	JWKSet() (JWKSet, error)
+}
+
+// NewJWK creates a signature verification JWK from an RSA, ECDSA or Ed25519
+// public key, including its thumbprint.
+func NewJWK(kid, alg string, key crypto.PublicKey) (JWK, error) {
+	jwk := JWK{Kid: kid, Alg: alg, Use: "sig"}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(key.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = encodeSegment(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(key)
+	default:
+		return JWK{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	thumbprint, err := jwk.ComputeThumbprint()
+	if err != nil {
+		return JWK{}, err
+	}
+
+	jwk.Thumbprint = thumbprint
+	return jwk, nil
+}
+
+// ComputeThumbprint computes the RFC 7638 SHA-256 thumbprint of the key,
+// encoded in base64url.
+func (k JWK) ComputeThumbprint() (string, error) {
+	var members map[string]string
+	switch k.Kty {
+	case "RSA":
+		members = map[string]string{"e": k.E, "kty": k.Kty, "n": k.N}
+	case "EC":
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X, "y": k.Y}
+	case "OKP":
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X}
+	default:
+		return "", fmt.Errorf("%w: kty %q", ErrUnsupportedKey, k.Kty)
+	}
+
+	// json.Marshal sorts the map keys, which gives the required member
+	// ordering without whitespace.
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return encodeSegment(sum[:]), nil
+}
+
+// PublicKey returns the public key of the JWK.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, k.Crv)
+		}
+
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve %s", ErrUnsupportedKey, k.Crv)
+		}
+
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, k.Crv)
+		}
+
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key size", ErrUnsupportedKey)
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, k.Kty)
+}
+
+// NewJWKSet creates a JWKSet from the public keys of the given sources.
+// The keys are sorted by kid.
+func NewJWKSet(sources ...PublicKeySource) (JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0)}
+	for _, source := range sources {
+		if s, ok := source.(JWKSetSource); ok {
+			own, err := s.JWKSet()
+			if err != nil {
+				return JWKSet{}, err
+			}
+
+			set.Keys = append(set.Keys, own.Keys...)
+			continue
+		}
+
+		for kid, key := range source.PublicKeys() {
+			jwk, err := NewJWK(kid, source.Algorithm(), key)
+			if err != nil {
+				return JWKSet{}, fmt.Errorf("key %s: %w", kid, err)
+			}
+
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
+
+func encodeSegment(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func decodeSegment(s string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(s) }
@@ -0,0 +1,116 @@
+package jwtx
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJWK_ComputeThumbprint(t *testing.T) {
+	// the example of RFC 7638 section 3.1.
+	jwk := JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+		Alg: "RS256",
+		Kid: "2011-04-29",
+	}
+
+	thumbprint, err := jwk.ComputeThumbprint()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if expected := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; thumbprint != expected {
+		t.Errorf("expected thumbprint %q but got %q", expected, thumbprint)
+	}
+}
+
+func TestNewJWK_RoundTrip(t *testing.T) {
+	rsaKey := mustRSAKey(t)
+	ecKey := mustECDSAKey(t, elliptic.P384())
+	edKey := mustEd25519Key(t)
+
+	tests := []struct {
+		name string
+		alg  string
+		key  interface{}
+		kty  string
+	}{
+		{name: "rsa", alg: "RS256", key: &rsaKey.PublicKey, kty: "RSA"},
+		{name: "ecdsa", alg: "ES384", key: &ecKey.PublicKey, kty: "EC"},
+		{name: "ed25519", alg: "EdDSA", key: edKey.Public(), kty: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := NewJWK("kid-1", tt.alg, tt.key)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if jwk.Kty != tt.kty || jwk.Kid != "kid-1" || jwk.Alg != tt.alg || jwk.Use != "sig" {
+				t.Errorf("unexpected jwk %+v", jwk)
+			}
+
+			if jwk.Thumbprint == "" {
+				t.Errorf("expected the thumbprint to be computed")
+			}
+
+			key, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if !reflect.DeepEqual(key, tt.key) {
+				t.Errorf("expected public key %v but got %v", tt.key, key)
+			}
+		})
+	}
+}
+
+func TestNewJWK_Unsupported(t *testing.T) {
+	if _, err := NewJWK("kid", "HS256", []byte("secret")); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("expected %v but got %v", ErrUnsupportedKey, err)
+	}
+
+	if _, err := (JWK{Kty: "oct"}).PublicKey(); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("expected %v but got %v", ErrUnsupportedKey, err)
+	}
+
+	if _, err := (JWK{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}).PublicKey(); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("expected %v for a point that is not on the curve but got %v", ErrUnsupportedKey, err)
+	}
+}
+
+func TestNewJWKSet(t *testing.T) {
+	rs := NewRSA256RoundRobinTokenizer(map[string]*rsa.PrivateKey{"rsa-1": mustRSAKey(t)})
+	es := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"ec-1": mustECDSAKey(t, elliptic.P256())})
+	ed := NewEdDSATokenizer(map[string]ed25519.PrivateKey{"ed-1": mustEd25519Key(t)})
+
+	set, err := NewJWKSet(rs, es, ed)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var kids []string
+	for _, k := range set.Keys {
+		kids = append(kids, k.Kid+":"+k.Alg)
+	}
+
+	if expected := []string{"ec-1:ES256", "ed-1:EdDSA", "rsa-1:RS256"}; !reflect.DeepEqual(kids, expected) {
+		t.Errorf("expected keys %v but got %v", expected, kids)
+	}
+
+	if _, ok := set.Lookup("ed-1"); !ok {
+		t.Errorf("expected to find ed-1")
+	}
+
+	if _, ok := set.Lookup("unknown"); ok {
+		t.Errorf("expected not to find unknown")
+	}
+}
@@ -0,0 +1,80 @@
+package jwtx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/httpx"
+)
+
+// JWKSHandler returns a handler that serves the public keys of the sources as
+// a JSON Web Key Set. The set is built on every request, so rotated keys are
+// published as soon as the sources expose them. The response can be cached by
+// clients for maxAge and revalidated with its ETag.
+func JWKSHandler(maxAge time.Duration, sources ...PublicKeySource) httpx.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		set, err := NewJWKSet(sources...)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(set)
+		if err != nil {
+			return err
+		}
+
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(b))
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", etag)
+
+		// ServeContent answers the conditional requests using the ETag.
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+		return nil
+	})
+}
+
+// JWKSTokenizer is a verify-only tokenizer that verifies tokens with the keys
+// of a static JSON Web Key Set, for example loaded from a file.
+type JWKSTokenizer struct {
+	keys   map[string]remoteKey
+	parser *parser
+}
+
+// NewJWKSTokenizer creates a new JWKSTokenizer. By default every asymmetric
+// algorithm is accepted, the keys that declare an algorithm only verify
+// tokens of that algorithm.
+func NewJWKSTokenizer(set JWKSet, opts ...Option) *JWKSTokenizer {
+	return &JWKSTokenizer{
+		keys:   verificationKeys(set),
+		parser: newParser(asymmetricAlgorithms, opts...),
+	}
+}
+
+// Tokenize always returns ErrVerifyOnly.
+func (t *JWKSTokenizer) Tokenize(_ jwt.Claims) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (t *JWKSTokenizer) Detokenize(token string, claims jwt.Claims) error {
+	return t.parser.parse(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, unknownKID
+		}
+
+		key, ok := t.keys[kid]
+		if !ok {
+			return nil, unknownKID
+		}
+
+		return key.verify(kid, token)
+	})
+}
@@ -0,0 +1,103 @@
+package jwtx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	tokenizer := NewRSA256RoundRobinTokenizer(map[string]*rsa.PrivateKey{"key-1": mustRSAKey(t)})
+	handler := JWKSHandler(time.Hour, tokenizer)
+
+	rec := httptest.NewRecorder()
+	if err := handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("expected cache control header but got %q", got)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "application/jwk-set+json" {
+		t.Errorf("expected jwk set content type but got %q", got)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	jwk, ok := set.Lookup("key-1")
+	if !ok {
+		t.Fatalf("expected key-1 to be published")
+	}
+
+	// the published key verifies the tokens of the tokenizer.
+	token, err := tokenizer.Tokenize(jwt.RegisteredClaims{Subject: "gopher"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err = jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) { return jwk.PublicKey() })
+	if err != nil || claims.Subject != "gopher" {
+		t.Errorf("expected the published key to verify the token but got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	if err := handler.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status %d but got %d", http.StatusNotModified, rec.Code)
+	}
+}
+
+func TestJWKSTokenizer(t *testing.T) {
+	signer := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	set, err := NewJWKSet(signer)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tokenizer := NewJWKSTokenizer(set)
+	if _, err := tokenizer.Tokenize(jwt.RegisteredClaims{}); !errors.Is(err, ErrVerifyOnly) {
+		t.Errorf("expected %v but got %v", ErrVerifyOnly, err)
+	}
+
+	token, err := signer.Tokenize(jwt.RegisteredClaims{Subject: "user"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var claims jwt.RegisteredClaims
+	if err := tokenizer.Detokenize(token, &claims); err != nil || claims.Subject != "user" {
+		t.Errorf("expected the token to be verified but got %v", err)
+	}
+
+	other := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-2": mustECDSAKey(t, elliptic.P256())})
+	token, err = other.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(token, &claims); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected %v but got %v", ErrUnknownKID, err)
+	}
+}
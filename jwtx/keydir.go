@@ -0,0 +1,428 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/encoding/json"
+)
+
+var (
+	// ErrEncryptedKey is returned when a key file is encrypted but no
+	// passphrase provider is configured.
+	ErrEncryptedKey = errors.New("jwtx: encrypted key without passphrase")
+
+	// ErrWrongPassphrase is returned when an encrypted key can not be
+	// decrypted with its passphrase.
+	ErrWrongPassphrase = errors.New("jwtx: wrong passphrase")
+
+	// ErrDuplicateKID is returned when several key files have the same kid.
+	ErrDuplicateKID = errors.New("jwtx: duplicate key id")
+)
+
+// PassphraseFunc returns the passphrase of the encrypted key file at the
+// given path of the key directory.
+type PassphraseFunc func(path string) ([]byte, error)
+
+// LoadOption is an option to configure how the keys are loaded from a key
+// directory.
+type LoadOption func(*keyLoader)
+
+// WithPassphrase configures the passphrase provider of the encrypted keys.
+// Both the PKCS#8 ENCRYPTED PRIVATE KEY blocks and the legacy encrypted PEM
+// blocks with a Proc-Type header are supported.
+func WithPassphrase(fn PassphraseFunc) LoadOption {
+	return func(l *keyLoader) {
+		l.passphrase = fn
+	}
+}
+
+// WithKIDManifest configures the JSON manifest of the key directory, which
+// maps the key file paths to their kid, for example:
+//
+//	{"2022/signing.pem": "prod-2022-11"}
+//
+// The files listed in the manifest are loaded whatever their extension, the
+// other key files keep their file name as kid.
+func WithKIDManifest(name string) LoadOption {
+	return func(l *keyLoader) {
+		l.manifest = name
+	}
+}
+
+// KeyFileError is the error of a key file that can not be loaded.
+type KeyFileError struct {
+	Path string
+	// KID is the kid of the file, empty when it is not known.
+	KID string
+	Err error
+}
+
+func (e *KeyFileError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *KeyFileError) Unwrap() error { return e.Err }
+
+// LoadError is returned when some files of a key directory can not be
+// loaded, it lists the error of every failed file. The keys of the other
+// files are still returned. errors.Is and errors.As match the error of any
+// of the files.
+type LoadError struct {
+	Files []*KeyFileError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Files))
+	for i, f := range e.Files {
+		msgs[i] = f.Error()
+	}
+
+	return fmt.Sprintf("jwtx: loading %d key files failed: %s", len(e.Files), strings.Join(msgs, "; "))
+}
+
+func (e *LoadError) Is(target error) bool {
+	for _, f := range e.Files {
+		if errors.Is(f, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *LoadError) As(target interface{}) bool {
+	for _, f := range e.Files {
+		if errors.As(f, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RSAPublicKeys is a set of RSA public keys addressed by kid. It is a
+// PublicKeySource, so a verify-only tokenizer can be created with NewJWKSet
+// and NewJWKSTokenizer.
+type RSAPublicKeys map[string]*rsa.PublicKey
+
+// Algorithm returns RS256.
+func (k RSAPublicKeys) Algorithm() string { return jwt.SigningMethodRS256.Alg() }
+
+// PublicKeys returns the public keys addressed by kid.
+func (k RSAPublicKeys) PublicKeys() map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey, len(k))
+	for kid, key := range k {
+		keys[kid] = key
+	}
+
+	return keys
+}
+
+// LoadRSAPublicPEMKeysFromDir loads the RSA public keys of the given
+// directory, for verify-only deployments. The keys are read from the public
+// key files, PKIX (PUBLIC KEY) or PKCS#1 (RSA PUBLIC KEY) blocks in .pem or
+// .pub files, and from the private key files. A private and a public key file
+// may share their kid, for example key-1.pem and key-1.pub, when they hold
+// the same key.
+func LoadRSAPublicPEMKeysFromDir(dir fs.FS, opts ...LoadOption) (RSAPublicKeys, error) {
+	l := newKeyLoader(opts)
+	keys := make(RSAPublicKeys)
+	for _, f := range l.load(dir) {
+		public := f.public
+		if f.private != nil {
+			public = f.private.Public()
+		}
+
+		key, ok := public.(*rsa.PublicKey)
+		if !ok {
+			l.failKID(f.path, f.kid, fmt.Errorf("%w: %T", ErrUnsupportedKey, public))
+			continue
+		}
+
+		if existing, ok := keys[f.kid]; ok && existing.Equal(key) {
+			continue
+		}
+
+		addKey(l, keys, f, key)
+	}
+
+	return keys, l.err()
+}
+
+// PublicKeys is a set of RSA, ECDSA and Ed25519 public keys addressed by kid.
+type PublicKeys map[string]crypto.PublicKey
+
+// JWKSet returns the public keys as a JWKSet, every key declares the
+// algorithm of its type, see NewJWKSTokenizer.
+func (k PublicKeys) JWKSet() (JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0, len(k))}
+	for kid, key := range k {
+		method, err := verifyingMethodFor(key)
+		if err != nil {
+			return JWKSet{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		jwk, err := NewJWK(kid, method.Alg(), key)
+		if err != nil {
+			return JWKSet{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
+
+// LoadPublicPEMKeysFromDir loads the RSA, ECDSA and Ed25519 public keys of
+// the given directory, for verify-only deployments. Like
+// LoadRSAPublicPEMKeysFromDir, the keys are read from both the public and
+// the private key files, which may share their kid when they hold the same
+// key.
+func LoadPublicPEMKeysFromDir(dir fs.FS, opts ...LoadOption) (PublicKeys, error) {
+	l := newKeyLoader(opts)
+	keys := make(PublicKeys)
+	for _, f := range l.load(dir) {
+		public := f.public
+		if f.private != nil {
+			public = f.private.Public()
+		}
+
+		if existing, ok := keys[f.kid]; ok && sameVerifyingKey(existing, public) {
+			continue
+		}
+
+		addKey(l, keys, f, public)
+	}
+
+	return keys, l.err()
+}
+
+// sameVerifyingKey reports whether both public keys are equal.
+func sameVerifyingKey(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface {
+		Equal(x crypto.PublicKey) bool
+	})
+	return ok && k.Equal(b)
+}
+
+// keyLoader loads the keys of a key directory and collects the errors of the
+// files that can not be loaded.
+type keyLoader struct {
+	passphrase PassphraseFunc
+	manifest   string
+	errs       []*KeyFileError
+}
+
+func newKeyLoader(opts []LoadOption) *keyLoader {
+	l := &keyLoader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// keyFile is a key loaded from a key directory, either private or public.
+type keyFile struct {
+	path    string
+	kid     string
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// load loads the keys of the .pem and .pub files of the directory and of the
+// files listed in the manifest.
+func (l *keyLoader) load(dir fs.FS) []keyFile {
+	kids, err := l.readManifest(dir)
+	if err != nil {
+		l.fail(l.manifest, err)
+		return nil
+	}
+
+	var files []keyFile
+	err = fs.WalkDir(dir, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			l.fail(p, err)
+			return nil
+		}
+
+		kid, listed := kids[p]
+		ext := path.Ext(p)
+		if d.IsDir() || (!listed && ext != ".pem" && ext != ".pub") {
+			return nil
+		}
+
+		delete(kids, p)
+		if !listed {
+			kid = strings.TrimSuffix(path.Base(p), ext)
+		}
+
+		b, err := fs.ReadFile(dir, p)
+		if err != nil {
+			l.failKID(p, kid, err)
+			return nil
+		}
+
+		private, public, err := l.parse(p, b)
+		if err != nil {
+			l.failKID(p, kid, err)
+			return nil
+		}
+
+		files = append(files, keyFile{path: p, kid: kid, private: private, public: public})
+		return nil
+	})
+
+	if err != nil {
+		l.fail(".", err)
+	}
+
+	missing := make([]string, 0, len(kids))
+	for p := range kids {
+		missing = append(missing, p)
+	}
+
+	sort.Strings(missing)
+	for _, p := range missing {
+		l.failKID(p, kids[p], fmt.Errorf("listed in %s: %w", l.manifest, fs.ErrNotExist))
+	}
+
+	return files
+}
+
+// readManifest reads the kid of the files listed in the manifest.
+func (l *keyLoader) readManifest(dir fs.FS) (map[string]string, error) {
+	kids := make(map[string]string)
+	if l.manifest == "" {
+		return kids, nil
+	}
+
+	f, err := dir.Open(l.manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f fs.File) {
+		_ = f.Close()
+	}(f)
+
+	if err := json.Decoder.Decode(f, &kids); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	return kids, nil
+}
+
+// parse parses the first PEM block of the file, which holds either a private
+// or a public key.
+func (l *keyLoader) parse(p string, b []byte) (crypto.Signer, crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, nil, ErrNotPEM
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+			return nil, key, nil
+		}
+
+		return nil, nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		return nil, key, err
+	}
+
+	// the legacy encrypted PEM blocks are deprecated but still produced by OpenSSL.
+	encrypted := block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block)
+	if encrypted {
+		decrypted, err := l.decrypt(p, block)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		block = decrypted
+	}
+
+	key, err := parsePrivateKeyBlock(block)
+	if err != nil && encrypted && !errors.Is(err, ErrUnsupportedKey) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrWrongPassphrase, err)
+	}
+
+	return key, nil, err
+}
+
+// decrypt decrypts the encrypted PEM block with the passphrase of the file.
+func (l *keyLoader) decrypt(p string, block *pem.Block) (*pem.Block, error) {
+	if l.passphrase == nil {
+		return nil, ErrEncryptedKey
+	}
+
+	passphrase, err := l.passphrase(p)
+	if err != nil {
+		return nil, fmt.Errorf("getting passphrase: %w", err)
+	}
+
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		der, err := decryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+	}
+
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, ErrWrongPassphrase
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &pem.Block{Type: block.Type, Bytes: der}, nil
+}
+
+func (l *keyLoader) fail(p string, err error) { l.failKID(p, "", err) }
+
+func (l *keyLoader) failKID(p, kid string, err error) {
+	l.errs = append(l.errs, &KeyFileError{Path: p, KID: kid, Err: err})
+}
+
+// err returns the errors of the failed files sorted by path as a LoadError,
+// or nil.
+func (l *keyLoader) err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(l.errs, func(i, j int) bool { return l.errs[i].Path < l.errs[j].Path })
+	return &LoadError{Files: l.errs}
+}
+
+// addKey adds the key of the file, unless its kid is already used.
+func addKey[K any](l *keyLoader, keys map[string]K, f keyFile, key K) {
+	if _, ok := keys[f.kid]; ok {
+		l.failKID(f.path, f.kid, fmt.Errorf("%w: %s", ErrDuplicateKID, f.kid))
+		return
+	}
+
+	keys[f.kid] = key
+}
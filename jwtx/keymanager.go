@@ -0,0 +1,529 @@
+package jwtx
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+var (
+	// ErrRevokedKey is returned when a token is signed by a revoked key.
+	ErrRevokedKey = errors.New("jwtx: revoked key")
+
+	// ErrKeyExists is returned when a key with the same kid is already managed.
+	ErrKeyExists = errors.New("jwtx: key already exists")
+
+	// ErrKeyChanged is returned when the key file of a managed kid holds
+	// another key.
+	ErrKeyChanged = errors.New("jwtx: key changed")
+)
+
+const (
+	// DefaultPublishDelay is the default time between the publication of a
+	// key found by Reload and its activation, the default refresh interval of
+	// RemoteJWKSTokenizer, so the verifiers know the key before it signs.
+	DefaultPublishDelay = time.Hour
+
+	// DefaultRetireGrace is the default time a key whose file is removed
+	// still verifies the tokens it signed before Reload drops it.
+	DefaultRetireGrace = 24 * time.Hour
+)
+
+// KeyState is the lifecycle state of a managed key.
+type KeyState uint8
+
+const (
+	KeyPending  KeyState = iota // Published for verification, not used for signing yet.
+	KeyActive                   // Used for signing and verification.
+	KeyRetiring                 // Only used for verification.
+	KeyRevoked                  // Neither used for signing nor verification.
+)
+
+func (s KeyState) String() string {
+	switch s {
+	case KeyPending:
+		return "pending"
+	case KeyActive:
+		return "active"
+	case KeyRetiring:
+		return "retiring"
+	case KeyRevoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// ManagedKey is a signing key with its lifecycle schedule.
+type ManagedKey struct {
+	// KID is the key id.
+	KID string
+	// Key is an RSA, ECDSA or Ed25519 private key.
+	Key crypto.Signer
+	// ActivateAt is the time the key starts signing, zero means immediately.
+	ActivateAt time.Time
+	// RetireAt is the time the key stops signing, zero means never.
+	RetireAt time.Time
+	// Revoked reports whether the key is revoked.
+	Revoked bool
+
+	method   jwt.SigningMethod
+	fromDir  bool
+	addedSeq int
+	// removed reports whether the key was retired by Reload because its file
+	// was removed.
+	removed bool
+}
+
+// State returns the state of the key at the given time.
+func (k ManagedKey) State(now time.Time) KeyState {
+	switch {
+	case k.Revoked:
+		return KeyRevoked
+	case !k.RetireAt.IsZero() && !now.Before(k.RetireAt):
+		return KeyRetiring
+	case now.Before(k.ActivateAt):
+		return KeyPending
+	default:
+		return KeyActive
+	}
+}
+
+// KeyStatus describes a managed key at a point in time.
+type KeyStatus struct {
+	KID        string
+	Algorithm  string
+	State      KeyState
+	Signing    bool
+	ActivateAt time.Time
+	RetireAt   time.Time
+}
+
+// KeyManager is a tokenizer that manages the lifecycle of its signing keys.
+//
+// Only the current active key signs, which is the most recently activated
+// key that is neither retiring nor revoked. Pending, active and retiring keys
+// verify tokens and are exposed as public keys, so they can be published
+// ahead of their activation.
+//
+// The managed keys are never modified in place, every change replaces the
+// key of its kid, so they can be used without holding the lock.
+type KeyManager struct {
+	clock  clock.Clock
+	parser *parser
+
+	mu           sync.RWMutex
+	keys         map[string]*ManagedKey
+	seq          int
+	publishDelay time.Duration
+	retireGrace  time.Duration
+}
+
+// NewKeyManager creates a new KeyManager that evaluates the key schedules with
+// the given clock, which also validates the claims unless WithClock is given.
+func NewKeyManager(c clock.Clock, opts ...Option) *KeyManager {
+	return &KeyManager{
+		clock:        c,
+		parser:       newParser(asymmetricAlgorithms, append([]Option{WithClock(c)}, opts...)...),
+		keys:         make(map[string]*ManagedKey),
+		publishDelay: DefaultPublishDelay,
+		retireGrace:  DefaultRetireGrace,
+	}
+}
+
+// SetPublishDelay sets the time the keys found by Reload are published
+// before they start signing, DefaultPublishDelay by default. It should be at
+// least the time the verifiers cache the JWKS.
+func (m *KeyManager) SetPublishDelay(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishDelay = d
+}
+
+// SetRetireGrace sets the time the retired keys of the removed files still
+// verify tokens before Reload drops them, DefaultRetireGrace by default. It
+// should be at least the lifetime of the tokens.
+func (m *KeyManager) SetRetireGrace(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retireGrace = d
+}
+
+// Add adds a key to the manager.
+func (m *KeyManager) Add(key ManagedKey) error {
+	method, err := signingMethodFor(key.Key)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", key.KID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.keys[key.KID]; exists {
+		return fmt.Errorf("key %s: %w", key.KID, ErrKeyExists)
+	}
+
+	m.seq++
+	key.method = method
+	key.addedSeq = m.seq
+	m.keys[key.KID] = &key
+	return nil
+}
+
+// Retire schedules the key to stop signing at the given time.
+func (m *KeyManager) Retire(kid string, at time.Time) error {
+	return m.update(kid, func(k *ManagedKey) { k.RetireAt = at })
+}
+
+// Revoke revokes the key immediately.
+func (m *KeyManager) Revoke(kid string) error {
+	return m.update(kid, func(k *ManagedKey) { k.Revoked = true })
+}
+
+// Remove removes the key from the manager.
+func (m *KeyManager) Remove(kid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[kid]; !ok {
+		return fmt.Errorf("key %s: %w", kid, ErrUnknownKID)
+	}
+
+	delete(m.keys, kid)
+	return nil
+}
+
+// Keys returns the status of the managed keys, sorted by kid.
+func (m *KeyManager) Keys() []KeyStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	signing := m.signingKey(now)
+
+	statuses := make([]KeyStatus, 0, len(m.keys))
+	for _, k := range m.keys {
+		statuses = append(statuses, KeyStatus{
+			KID:        k.KID,
+			Algorithm:  k.method.Alg(),
+			State:      k.State(now),
+			Signing:    k == signing,
+			ActivateAt: k.ActivateAt,
+			RetireAt:   k.RetireAt,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].KID < statuses[j].KID })
+	return statuses
+}
+
+// Algorithm returns the algorithm of the current signing key.
+func (m *KeyManager) Algorithm() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if k := m.signingKey(m.clock.Now()); k != nil {
+		return k.method.Alg()
+	}
+
+	return ""
+}
+
+// PublicKeys returns the public keys of the pending, active and retiring keys.
+func (m *KeyManager) PublicKeys() map[string]crypto.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	keys := make(map[string]crypto.PublicKey, len(m.keys))
+	for kid, k := range m.keys {
+		if k.State(now) != KeyRevoked {
+			keys[kid] = k.Key.Public()
+		}
+	}
+
+	return keys
+}
+
+// JWKSet returns the public keys as a JWKSet, every key declares its own
+// algorithm.
+func (m *KeyManager) JWKSet() (JWKSet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	set := JWKSet{Keys: make([]JWK, 0, len(m.keys))}
+	for kid, k := range m.keys {
+		if k.State(now) == KeyRevoked {
+			continue
+		}
+
+		jwk, err := NewJWK(kid, k.method.Alg(), k.Key.Public())
+		if err != nil {
+			return JWKSet{}, err
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
+
+func (m *KeyManager) Tokenize(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	k := m.signingKey(m.clock.Now())
+	m.mu.RUnlock()
+
+	if k == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := &jwt.Token{
+		Header: map[string]interface{}{
+			"typ": "JWT",
+			"kid": k.KID,
+			"alg": k.method.Alg(),
+		},
+		Claims: claims,
+		Method: k.method,
+	}
+
+	return token.SignedString(k.Key)
+}
+
+func (m *KeyManager) Detokenize(token string, claims jwt.Claims) error {
+	return m.parser.parse(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		m.mu.RLock()
+		k, ok := m.keys[kid]
+		m.mu.RUnlock()
+
+		if !ok || kid == "" {
+			return nil, unknownKID
+		}
+
+		if k.State(m.clock.Now()) == KeyRevoked {
+			return nil, &tokenError{kind: ErrRevokedKey, cause: fmt.Errorf("key %s", kid)}
+		}
+
+		if k.method.Alg() != token.Method.Alg() {
+			return nil, &tokenError{kind: ErrWrongAlg, cause: fmt.Errorf("key %s is for %s", kid, k.method.Alg())}
+		}
+
+		return k.Key.Public(), nil
+	})
+}
+
+// Reload synchronizes the keys with the PEM files of the directory, see
+// LoadPEMKeysFromDir. The keys added with Add are left untouched.
+//
+// The keys of new files are published as pending keys and start signing
+// after the publish delay, see SetPublishDelay, unless there is no signing
+// key yet. The keys of removed files are retired, until their files are
+// restored, and dropped once the retire grace period is over, see
+// SetRetireGrace.
+//
+// The tokens only carry the kid of their key, so a file whose key changes is
+// rejected with ErrKeyChanged and its previous key is kept: a new key must
+// be added under a new kid. Likewise, the files that can not be loaded keep
+// their previous key. Both are reported in a *LoadError once the other files
+// are synchronized.
+func (m *KeyManager) Reload(dir fs.FS, opts ...LoadOption) error {
+	l := newKeyLoader(opts)
+	files := l.load(dir)
+
+	// the kids of the failed files keep their key, unless the failure may
+	// hide any file, such as an unreadable manifest.
+	failed := make(map[string]bool, len(l.errs))
+	keepAll := false
+	for _, f := range l.errs {
+		failed[f.KID] = true
+		keepAll = keepAll || f.KID == ""
+	}
+
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activateAt := now
+	if m.signingKey(now) != nil {
+		activateAt = now.Add(m.publishDelay)
+	}
+
+	loaded := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.private == nil {
+			continue
+		}
+
+		if loaded[f.kid] {
+			l.failKID(f.path, f.kid, fmt.Errorf("%w: %s", ErrDuplicateKID, f.kid))
+			continue
+		}
+
+		loaded[f.kid] = true
+		existing, ok := m.keys[f.kid]
+		if ok && !existing.fromDir {
+			continue
+		}
+
+		if ok && !sameKey(existing.Key, f.private) {
+			l.failKID(f.path, f.kid, fmt.Errorf("%w: %s", ErrKeyChanged, f.kid))
+			continue
+		}
+
+		if ok {
+			if existing.removed {
+				restored := *existing
+				restored.removed = false
+				restored.RetireAt = time.Time{}
+				m.keys[f.kid] = &restored
+			}
+
+			continue
+		}
+
+		method, err := signingMethodFor(f.private)
+		if err != nil {
+			l.failKID(f.path, f.kid, err)
+			continue
+		}
+
+		m.seq++
+		m.keys[f.kid] = &ManagedKey{
+			KID:        f.kid,
+			Key:        f.private,
+			ActivateAt: activateAt,
+			method:     method,
+			fromDir:    true,
+			addedSeq:   m.seq,
+		}
+	}
+
+	for kid, k := range m.keys {
+		if keepAll || loaded[kid] || failed[kid] || !k.fromDir {
+			continue
+		}
+
+		if !k.RetireAt.IsZero() {
+			if !now.Before(k.RetireAt.Add(m.retireGrace)) {
+				delete(m.keys, kid)
+			}
+
+			continue
+		}
+
+		retired := *k
+		retired.RetireAt = now
+		retired.removed = true
+		m.keys[kid] = &retired
+	}
+
+	return l.err()
+}
+
+// Watch reloads the directory every interval until ctx is done. The reload
+// errors are reported to onError, which may be nil.
+func (m *KeyManager) Watch(ctx context.Context, dir fs.FS, interval time.Duration, onError func(err error), opts ...LoadOption) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Reload(dir, opts...); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *KeyManager) update(kid string, fn func(k *ManagedKey)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.keys[kid]
+	if !ok {
+		return fmt.Errorf("key %s: %w", kid, ErrUnknownKID)
+	}
+
+	updated := *k
+	fn(&updated)
+	m.keys[kid] = &updated
+	return nil
+}
+
+// signingKey returns the most recently activated active key. The keys
+// activated at the same time are ordered by the time they are added.
+func (m *KeyManager) signingKey(now time.Time) *ManagedKey {
+	var current *ManagedKey
+	for _, k := range m.keys {
+		if k.State(now) != KeyActive {
+			continue
+		}
+
+		if current == nil ||
+			k.ActivateAt.After(current.ActivateAt) ||
+			(k.ActivateAt.Equal(current.ActivateAt) && k.addedSeq > current.addedSeq) {
+			current = k
+		}
+	}
+
+	return current
+}
+
+// sameKey reports whether both private keys are equal.
+func sameKey(a, b crypto.Signer) bool {
+	k, ok := a.(interface {
+		Equal(x crypto.PrivateKey) bool
+	})
+	return ok && k.Equal(b)
+}
+
+// signingMethodFor returns the signing method of the key type.
+func signingMethodFor(key crypto.Signer) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return verifyingMethodFor(key.Public())
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+}
+
+// verifyingMethodFor returns the signing method of the public key type.
+func verifyingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+}
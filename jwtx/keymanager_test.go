@@ -0,0 +1,359 @@
+package jwtx
+
+import (
+	"crypto/elliptic"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func tokenKID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyManager_Rotation(t *testing.T) {
+	c := newFakeClock()
+	m := NewKeyManager(c)
+
+	if _, err := m.Tokenize(jwt.RegisteredClaims{}); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected %v but got %v", ErrNoSigningKey, err)
+	}
+
+	if err := m.Add(ManagedKey{KID: "old", Key: mustRSAKey(t)}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Add(ManagedKey{KID: "old", Key: mustRSAKey(t)}); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("expected %v but got %v", ErrKeyExists, err)
+	}
+
+	next := ManagedKey{KID: "next", Key: mustECDSAKey(t, elliptic.P256()), ActivateAt: c.Now().Add(time.Hour)}
+	if err := m.Add(next); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	claims := jwt.RegisteredClaims{Subject: "user"}
+	oldToken, err := m.Tokenize(claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if kid := tokenKID(t, oldToken); kid != "old" {
+		t.Errorf("expected the old key to sign but got %q", kid)
+	}
+
+	if _, ok := m.PublicKeys()["next"]; !ok {
+		t.Errorf("expected the pending key to be published")
+	}
+
+	c.Add(time.Hour)
+	if err := m.Retire("old", c.Now()); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	newToken, err := m.Tokenize(claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if kid := tokenKID(t, newToken); kid != "next" {
+		t.Errorf("expected the next key to sign but got %q", kid)
+	}
+
+	if alg := m.Algorithm(); alg != "ES256" {
+		t.Errorf("expected algorithm ES256 but got %q", alg)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		if err := m.Detokenize(token, &jwt.RegisteredClaims{}); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	}
+
+	statuses := m.Keys()
+	if len(statuses) != 2 ||
+		statuses[0].KID != "next" || statuses[0].State != KeyActive || !statuses[0].Signing ||
+		statuses[1].KID != "old" || statuses[1].State != KeyRetiring || statuses[1].Signing {
+		t.Errorf("unexpected key statuses %+v", statuses)
+	}
+
+	if err := m.Revoke("old"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Detokenize(oldToken, &jwt.RegisteredClaims{}); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("expected %v but got %v", ErrRevokedKey, err)
+	}
+
+	if _, ok := m.PublicKeys()["old"]; ok {
+		t.Errorf("expected the revoked key not to be published")
+	}
+
+	if err := m.Revoke("missing"); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected %v but got %v", ErrUnknownKID, err)
+	}
+
+	if err := m.Remove("old"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Detokenize(oldToken, &jwt.RegisteredClaims{}); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected %v but got %v", ErrUnknownKID, err)
+	}
+}
+
+func TestKeyManager_JWKSet(t *testing.T) {
+	m := NewKeyManager(newFakeClock())
+	_ = m.Add(ManagedKey{KID: "rsa", Key: mustRSAKey(t)})
+	_ = m.Add(ManagedKey{KID: "ed", Key: mustEd25519Key(t)})
+	_ = m.Add(ManagedKey{KID: "revoked", Key: mustEd25519Key(t), Revoked: true})
+
+	set, err := NewJWKSet(m)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys but got %d", len(set.Keys))
+	}
+
+	if k, _ := set.Lookup("rsa"); k.Alg != "RS256" {
+		t.Errorf("expected alg RS256 but got %q", k.Alg)
+	}
+
+	if k, _ := set.Lookup("ed"); k.Alg != "EdDSA" {
+		t.Errorf("expected alg EdDSA but got %q", k.Alg)
+	}
+}
+
+func TestKeyManager_Reload(t *testing.T) {
+	edBytes, edErr := x509.MarshalPKCS8PrivateKey(mustEd25519Key(t))
+	ecBytes, ecErr := x509.MarshalECPrivateKey(mustECDSAKey(t, elliptic.P384()))
+	dir := fstest.MapFS{
+		"a.pem": pemFile(t, "PRIVATE KEY", edBytes, edErr),
+	}
+
+	c := newFakeClock()
+	m := NewKeyManager(c)
+	_ = m.Add(ManagedKey{KID: "manual", Key: mustRSAKey(t), ActivateAt: c.Now().Add(-time.Hour)})
+
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// the loaded key is published before it signs.
+	if _, ok := m.PublicKeys()["a"]; !ok || m.Algorithm() != "RS256" {
+		t.Errorf("expected the loaded key to be pending but got %+v", m.Keys())
+	}
+
+	c.Add(DefaultPublishDelay)
+	a, err := m.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if kid := tokenKID(t, a); kid != "a" {
+		t.Errorf("expected the loaded key to sign but got %q", kid)
+	}
+
+	// reloading the same files is a no-op.
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Detokenize(a, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Minute)
+	delete(dir, "a.pem")
+	dir["b.pem"] = pemFile(t, "EC PRIVATE KEY", ecBytes, ecErr)
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(DefaultPublishDelay)
+	statuses := m.Keys()
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 keys but got %+v", statuses)
+	}
+
+	want := map[string]KeyState{"a": KeyRetiring, "b": KeyActive, "manual": KeyActive}
+	for _, s := range statuses {
+		if s.State != want[s.KID] {
+			t.Errorf("expected key %s to be %s but got %s", s.KID, want[s.KID], s.State)
+		}
+
+		if s.Signing != (s.KID == "b") {
+			t.Errorf("unexpected signing key %s", s.KID)
+		}
+	}
+
+	if err := m.Detokenize(a, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected the retired key to verify but got %v", err)
+	}
+
+	b, err := m.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Minute)
+	changedBytes, changedErr := x509.MarshalPKCS8PrivateKey(mustEd25519Key(t))
+	dir["b.pem"] = pemFile(t, "PRIVATE KEY", changedBytes, changedErr)
+	if err := m.Reload(dir); !errors.Is(err, ErrKeyChanged) {
+		t.Fatalf("expected error %v but got %v", ErrKeyChanged, err)
+	}
+
+	if alg := m.Algorithm(); alg != "ES384" {
+		t.Errorf("expected the changed key to be rejected but got %q", alg)
+	}
+
+	if err := m.Detokenize(b, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected the previous key to verify but got %v", err)
+	}
+
+	// an unreadable file keeps its previous key.
+	dir["b.pem"] = &fstest.MapFile{Data: []byte("garbage")}
+	var loadErr *LoadError
+	if err := m.Reload(dir); !errors.As(err, &loadErr) || !errors.Is(err, ErrNotPEM) {
+		t.Fatalf("expected a load error %v but got %v", ErrNotPEM, err)
+	}
+
+	if loadErr.Files[0].KID != "b" {
+		t.Errorf("expected the error of kid b but got %+v", loadErr.Files[0])
+	}
+
+	if alg := m.Algorithm(); alg != "ES384" {
+		t.Errorf("expected the previous key to sign but got %q", alg)
+	}
+
+	// restoring a removed file reactivates its key.
+	dir["a.pem"] = pemFile(t, "PRIVATE KEY", edBytes, edErr)
+	dir["b.pem"] = pemFile(t, "EC PRIVATE KEY", ecBytes, ecErr)
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for _, s := range m.Keys() {
+		if s.KID == "a" && (s.State != KeyActive || !s.RetireAt.IsZero()) {
+			t.Errorf("expected the restored key to be active but got %+v", s)
+		}
+	}
+
+	// the key of a removed file is dropped after the retire grace period.
+	delete(dir, "a.pem")
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(DefaultRetireGrace - time.Second)
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Detokenize(a, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected the retired key to verify but got %v", err)
+	}
+
+	c.Add(time.Second)
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := m.Detokenize(a, &jwt.RegisteredClaims{}); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKID, err)
+	}
+}
+
+func TestKeyManager_SetPublishDelay(t *testing.T) {
+	ecBytes, ecErr := x509.MarshalECPrivateKey(mustECDSAKey(t, elliptic.P256()))
+	dir := fstest.MapFS{"a.pem": pemFile(t, "EC PRIVATE KEY", ecBytes, ecErr)}
+
+	c := newFakeClock()
+	m := NewKeyManager(c)
+	m.SetPublishDelay(time.Minute)
+	_ = m.Add(ManagedKey{KID: "manual", Key: mustRSAKey(t)})
+
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Minute - time.Second)
+	if alg := m.Algorithm(); alg != "RS256" {
+		t.Errorf("expected the pending key not to sign but got %q", alg)
+	}
+
+	c.Add(time.Second)
+	if alg := m.Algorithm(); alg != "ES256" {
+		t.Errorf("expected the loaded key to sign but got %q", alg)
+	}
+}
+
+func TestKeyManager_Reload_Encrypted(t *testing.T) {
+	dir := fstest.MapFS{"ec.pem": &fstest.MapFile{Data: []byte(encryptedECKey)}}
+	m := NewKeyManager(newFakeClock())
+
+	if err := m.Reload(dir); !errors.Is(err, ErrEncryptedKey) {
+		t.Errorf("expected error %v but got %v", ErrEncryptedKey, err)
+	}
+
+	if err := m.Reload(dir, WithPassphrase(passphrase("correct-horse"))); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if alg := m.Algorithm(); alg != "ES256" {
+		t.Errorf("expected the decrypted key to sign but got %q", alg)
+	}
+}
+
+func TestKeyManager_ConcurrentReload(t *testing.T) {
+	ecBytes, ecErr := x509.MarshalECPrivateKey(mustECDSAKey(t, elliptic.P256()))
+	dir := fstest.MapFS{"a.pem": pemFile(t, "EC PRIVATE KEY", ecBytes, ecErr)}
+
+	c := newFakeClock()
+	m := NewKeyManager(c)
+	if err := m.Reload(dir); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_ = m.Reload(dir)
+			_ = m.Retire("a", c.Now().Add(time.Hour))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			token, err := m.Tokenize(jwt.RegisteredClaims{})
+			if err != nil {
+				t.Errorf("expected no error but got %v", err)
+				return
+			}
+
+			if err := m.Detokenize(token, &jwt.RegisteredClaims{}); err != nil {
+				t.Errorf("expected no error but got %v", err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
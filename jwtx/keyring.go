@@ -0,0 +1,97 @@
+package jwtx
+
+import (
+	"crypto"
+	"errors"
+	"sort"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoSigningKey is returned when a tokenizer has no key to sign with.
+var ErrNoSigningKey = errors.New("jwtx: no signing key")
+
+// keyring is a tokenizer that signs with its keys in round-robin order, and
+// verifies with the key addressed by the kid header.
+type keyring[K any] struct {
+	method   jwt.SigningMethod
+	keys     map[string]K
+	indexes  []string
+	position uint32
+	public   func(key K) interface{}
+	parser   *parser
+}
+
+// newKeyring creates a new keyring. The public function returns the key used
+// to verify the tokens signed by the given key.
+func newKeyring[K any](method jwt.SigningMethod, keys map[string]K, public func(key K) interface{}, opts []Option) *keyring[K] {
+	indexes := make([]string, 0, len(keys))
+	for kid := range keys {
+		indexes = append(indexes, kid)
+	}
+
+	sort.Strings(indexes)
+
+	return &keyring[K]{
+		method:  method,
+		keys:    keys,
+		indexes: indexes,
+		public:  public,
+		parser:  newParser([]string{method.Alg()}, opts...),
+	}
+}
+
+// Algorithm returns the algorithm used to sign the tokens.
+func (k *keyring[K]) Algorithm() string { return k.method.Alg() }
+
+func (k *keyring[K]) Tokenize(claims jwt.Claims) (string, error) {
+	if len(k.indexes) == 0 {
+		return "", ErrNoSigningKey
+	}
+
+	kid := k.indexes[k.nextIndex()]
+	key := k.keys[kid]
+
+	token := &jwt.Token{
+		Header: map[string]interface{}{
+			"typ": "JWT",
+			"kid": kid,
+			"alg": k.method.Alg(),
+		},
+		Claims: claims,
+		Method: k.method,
+	}
+
+	return token.SignedString(key)
+}
+
+func (k *keyring[K]) Detokenize(token string, claims jwt.Claims) error {
+	return k.parser.parse(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, unknownKID
+		}
+
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, unknownKID
+		}
+
+		return k.public(key), nil
+	})
+}
+
+// publicKeys returns the verification keys addressed by kid.
+func (k *keyring[K]) publicKeys() map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey, len(k.keys))
+	for kid, key := range k.keys {
+		keys[kid] = k.public(key)
+	}
+
+	return keys
+}
+
+func (k *keyring[K]) nextIndex() int {
+	return int(atomic.AddUint32(&k.position, 1) % uint32(len(k.indexes)))
+}
@@ -0,0 +1,98 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+var (
+	// ErrNotPEM is returned when the data does not contain a PEM block.
+	ErrNotPEM = errors.New("jwtx: not a PEM encoded key")
+
+	// ErrUnsupportedKey is returned when the PEM block contains an unsupported key.
+	ErrUnsupportedKey = errors.New("jwtx: unsupported key type")
+)
+
+// PrivateKeys is a set of private keys addressed by kid.
+type PrivateKeys map[string]crypto.Signer
+
+// RSA returns the RSA keys of the set.
+func (k PrivateKeys) RSA() map[string]*rsa.PrivateKey { return filterKeys[*rsa.PrivateKey](k) }
+
+// ECDSA returns the ECDSA keys of the set.
+func (k PrivateKeys) ECDSA() map[string]*ecdsa.PrivateKey { return filterKeys[*ecdsa.PrivateKey](k) }
+
+// Ed25519 returns the Ed25519 keys of the set.
+func (k PrivateKeys) Ed25519() map[string]ed25519.PrivateKey {
+	return filterKeys[ed25519.PrivateKey](k)
+}
+
+func filterKeys[K crypto.Signer](keys PrivateKeys) map[string]K {
+	filtered := make(map[string]K)
+	for kid, key := range keys {
+		if k, ok := key.(K); ok {
+			filtered[kid] = k
+		}
+	}
+
+	return filtered
+}
+
+// LoadPEMKeysFromDir loads RSA, ECDSA and Ed25519 PEM keys from the given
+// directory. Like LoadRSAPEMKeysFromDir, the kid is the file name without
+// extension unless it is overridden by the manifest, the encrypted keys are
+// decrypted with the passphrase provider, the public-key-only files are
+// skipped, and the failed files are reported together in a *LoadError.
+func LoadPEMKeysFromDir(dir fs.FS, opts ...LoadOption) (PrivateKeys, error) {
+	l := newKeyLoader(opts)
+	keys := make(PrivateKeys)
+	for _, f := range l.load(dir) {
+		if f.private != nil {
+			addKey(l, keys, f, f.private)
+		}
+	}
+
+	return keys, l.err()
+}
+
+// ParsePrivateKeyPEM parses a private key from the first PEM block of b.
+// The key type is detected from the PKCS#1 (RSA PRIVATE KEY), SEC1
+// (EC PRIVATE KEY) and PKCS#8 (PRIVATE KEY) blocks.
+func ParsePrivateKeyPEM(b []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrNotPEM
+	}
+
+	return parsePrivateKeyBlock(block)
+}
+
+func parsePrivateKeyBlock(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return key.(crypto.Signer), nil
+		}
+
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+}
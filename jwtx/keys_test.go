@@ -0,0 +1,75 @@
+package jwtx
+
+import (
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func pemFile(t *testing.T, typ string, b []byte, err error) *fstest.MapFile {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return &fstest.MapFile{Data: pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b})}
+}
+
+func TestLoadPEMKeysFromDir(t *testing.T) {
+	rsaKey := mustRSAKey(t)
+	ecKey := mustECDSAKey(t, elliptic.P256())
+	edKey := mustEd25519Key(t)
+
+	pkcs8EC, err := x509.MarshalPKCS8PrivateKey(mustECDSAKey(t, elliptic.P384()))
+	ecBytes, ecErr := x509.MarshalECPrivateKey(ecKey)
+	edBytes, edErr := x509.MarshalPKCS8PrivateKey(edKey)
+
+	dir := fstest.MapFS{
+		"rsa.pem":        pemFile(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil),
+		"ec.pem":         pemFile(t, "EC PRIVATE KEY", ecBytes, ecErr),
+		"nested/ed.pem":  pemFile(t, "PRIVATE KEY", edBytes, edErr),
+		"pkcs8-ec.pem":   pemFile(t, "PRIVATE KEY", pkcs8EC, err),
+		"ignored.txt":    &fstest.MapFile{Data: []byte("not a key")},
+		"nested/ignored": &fstest.MapFile{Data: []byte("not a key")},
+	}
+
+	keys, err := LoadPEMKeysFromDir(dir)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("expected 4 keys but got %d", len(keys))
+	}
+
+	if rsa := keys.RSA(); len(rsa) != 1 || !rsa["rsa"].Equal(rsaKey) {
+		t.Errorf("expected the rsa key but got %v", rsa)
+	}
+
+	if ec := keys.ECDSA(); len(ec) != 2 || !ec["ec"].Equal(ecKey) || ec["pkcs8-ec"] == nil {
+		t.Errorf("expected the ecdsa keys but got %v", ec)
+	}
+
+	if ed := keys.Ed25519(); len(ed) != 1 || !ed["ed"].Equal(edKey) {
+		t.Errorf("expected the ed25519 key but got %v", ed)
+	}
+}
+
+func TestParsePrivateKeyPEM_Errors(t *testing.T) {
+	if _, err := ParsePrivateKeyPEM([]byte("not a pem")); !errors.Is(err, ErrNotPEM) {
+		t.Errorf("expected %v but got %v", ErrNotPEM, err)
+	}
+
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})
+	if _, err := ParsePrivateKeyPEM(b); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("expected %v but got %v", ErrUnsupportedKey, err)
+	}
+
+	b = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}})
+	if _, err := ParsePrivateKeyPEM(b); err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
@@ -0,0 +1,156 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/httpx"
+)
+
+// contextKey is the type of the context keys.
+type contextKey struct {
+	name string
+}
+
+var claimsContextKey = &contextKey{"claims"}
+
+// ClaimsFromContext returns the claims of the request authenticated by
+// BearerAuth or DPoPAuth, T must be the claims type of the middleware.
+func ClaimsFromContext[T any](ctx context.Context) (*T, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*T)
+	return claims, ok
+}
+
+// BearerAuth is a middleware that authenticates the requests with the bearer
+// access token of the Authorization header, see RFC 6750. The claims are
+// decoded into a new T, which the next handlers get with ClaimsFromContext.
+//
+// The tokens bound to a DPoP key are rejected, so a stolen bound token can
+// not be presented as a bearer token. The failures are written as 401
+// problem responses with a WWW-Authenticate challenge, see httpx.WriteProblem.
+func BearerAuth[T any, P claimsPointer[T]](t Tokenizer) httpx.Middleware {
+	return func(h httpx.Handler) httpx.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			token, ok := authorization(r, "Bearer")
+			if !ok {
+				return unauthorized(w, r, "Bearer", nil, "", "missing bearer token")
+			}
+
+			claims := P(new(T))
+			confirmed := &confirmedClaims{Claims: claims}
+			if err := t.Detokenize(token, confirmed); err != nil {
+				return unauthorized(w, r, "Bearer", nil, "invalid_token", err.Error())
+			}
+
+			if confirmed.jkt != "" {
+				return unauthorized(w, r, "Bearer", nil, "invalid_token", "the token is bound to a DPoP key")
+			}
+
+			return h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, (*T)(claims))))
+		}
+	}
+}
+
+// DPoPAuth is a middleware that authenticates the requests with a DPoP-bound
+// access token, see RFC 9449. The token is presented with the DPoP scheme of
+// the Authorization header, and the request carries a proof signed by the
+// key whose thumbprint is the cnf.jkt claim of the token. The cnf claim is
+// read from the verified payload, so T does not need to decode it.
+//
+// Like BearerAuth, the claims are available with ClaimsFromContext and the
+// failures are written as 401 problem responses. Both middlewares can be
+// combined per route during the migration of the clients.
+func DPoPAuth[T any, P claimsPointer[T]](t Tokenizer, v *DPoPVerifier) httpx.Middleware {
+	algs := []string{`algs="` + strings.Join(v.Algorithms(), " ") + `"`}
+	return func(h httpx.Handler) httpx.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			token, ok := authorization(r, "DPoP")
+			if !ok {
+				return unauthorized(w, r, "DPoP", algs, "", "missing DPoP token")
+			}
+
+			claims := P(new(T))
+			confirmed := &confirmedClaims{Claims: claims}
+			if err := t.Detokenize(token, confirmed); err != nil {
+				return unauthorized(w, r, "DPoP", algs, "invalid_token", err.Error())
+			}
+
+			jkt, err := v.VerifyRequest(r, token)
+			if err != nil {
+				return unauthorized(w, r, "DPoP", algs, "invalid_dpop_proof", err.Error())
+			}
+
+			if confirmed.jkt == "" || confirmed.jkt != jkt {
+				return unauthorized(w, r, "DPoP", algs, "invalid_token", ErrDPoPKeyMismatch.Error())
+			}
+
+			return h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, (*T)(claims))))
+		}
+	}
+}
+
+// authorization returns the token of the Authorization header with the
+// given scheme.
+func authorization(r *http.Request, scheme string) (string, bool) {
+	s, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(s, scheme) {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized sets the WWW-Authenticate challenge and writes the 401
+// problem.
+func unauthorized(w http.ResponseWriter, r *http.Request, scheme string, params []string, code, detail string) error {
+	all := make([]string, 0, len(params)+1)
+	all = append(all, params...)
+	if code != "" {
+		all = append(all, `error="`+code+`"`)
+	}
+
+	challenge := scheme
+	if len(all) > 0 {
+		challenge += " " + strings.Join(all, ", ")
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	p := httpx.NewProblem(http.StatusUnauthorized, detail)
+	p.Instance = r.URL.Path
+	return httpx.WriteProblem(w, p)
+}
+
+// confirmedClaims decodes the token payload into the claims and reads the
+// cnf.jkt claim on the side, so the binding of the token is known whatever
+// the claims type is.
+type confirmedClaims struct {
+	jwt.Claims
+	jkt string
+}
+
+func (c *confirmedClaims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, c.Claims); err != nil {
+		return err
+	}
+
+	var cnf struct {
+		Confirmation *Confirmation `json:"cnf"`
+	}
+
+	if err := json.Unmarshal(b, &cnf); err != nil {
+		return err
+	}
+
+	c.jkt = ""
+	if cnf.Confirmation != nil {
+		c.jkt = cnf.Confirmation.JKT
+	}
+
+	return nil
+}
+
+func (c *confirmedClaims) MarshalJSON() ([]byte, error) { return json.Marshal(c.Claims) }
@@ -0,0 +1,254 @@
+package jwtx
+
+import (
+	"crypto/elliptic"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/httpx"
+)
+
+func TestBearerAuth(t *testing.T) {
+	tokenizer := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")})
+	handler := BearerAuth[userClaims](tokenizer)(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		claims, ok := ClaimsFromContext[userClaims](r.Context())
+		if !ok {
+			t.Fatalf("expected the claims in the context")
+		}
+
+		w.Header().Set("X-Role", claims.Role)
+		return nil
+	}))
+
+	token, _, err := Issue(tokenizer, userClaims{Role: "admin"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	bound := userClaims{Role: "admin"}
+	bound.Confirmation = &Confirmation{JKT: "thumbprint"}
+	boundToken, _, err := Issue(tokenizer, bound)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"valid", "Bearer " + token, http.StatusOK, ""},
+		{"lowercase scheme", "bearer " + token, http.StatusOK, ""},
+		{"missing", "", http.StatusUnauthorized, "Bearer"},
+		{"other scheme", "DPoP " + token, http.StatusUnauthorized, "Bearer"},
+		{"invalid", "Bearer " + token + "x", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"bound", "Bearer " + boundToken, http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			if err := handler.ServeHTTP(rec, req); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but got %d", tt.status, rec.Code)
+			}
+
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("expected challenge %q but got %q", tt.challenge, got)
+			}
+
+			if tt.status == http.StatusOK && rec.Header().Get("X-Role") != "admin" {
+				t.Errorf("expected role admin but got %q", rec.Header().Get("X-Role"))
+			}
+		})
+	}
+}
+
+func TestDPoPAuth(t *testing.T) {
+	const url = "http://example.com/orders"
+	tokenizer := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")})
+	verifier := mustDPoPVerifier(t, NewMemoryReplayCache(clock.UTC), WithDPoPAlgorithms("ES256"))
+	handler := DPoPAuth[userClaims](tokenizer, verifier)(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if _, ok := ClaimsFromContext[userClaims](r.Context()); !ok {
+			t.Fatalf("expected the claims in the context")
+		}
+
+		return nil
+	}))
+
+	prover, err := NewDPoPProver(mustECDSAKey(t, elliptic.P256()))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	other, err := NewDPoPProver(mustECDSAKey(t, elliptic.P256()))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	bound := userClaims{Role: "admin"}
+	bound.Confirmation = &Confirmation{JKT: prover.Thumbprint()}
+	token, _, err := Issue(tokenizer, bound)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	unbound, _, err := Issue(tokenizer, userClaims{Role: "admin"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		proof         func() string
+		status        int
+		challenge     string
+	}{
+		{
+			name:          "valid",
+			authorization: "DPoP " + token,
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, url, token) },
+			status:        http.StatusOK,
+		},
+		{
+			name:          "missing token",
+			authorization: "",
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, url, token) },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256"`,
+		},
+		{
+			name:          "bearer scheme",
+			authorization: "Bearer " + token,
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, url, token) },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256"`,
+		},
+		{
+			name:          "invalid token",
+			authorization: "DPoP " + token + "x",
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, url, token+"x") },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256", error="invalid_token"`,
+		},
+		{
+			name:          "missing proof",
+			authorization: "DPoP " + token,
+			proof:         func() string { return "" },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256", error="invalid_dpop_proof"`,
+		},
+		{
+			name:          "wrong url",
+			authorization: "DPoP " + token,
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, "http://example.com/users", token) },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256", error="invalid_dpop_proof"`,
+		},
+		{
+			name:          "other key",
+			authorization: "DPoP " + token,
+			proof:         func() string { return mustProof(t, other, http.MethodGet, url, token) },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256", error="invalid_token"`,
+		},
+		{
+			name:          "unbound token",
+			authorization: "DPoP " + unbound,
+			proof:         func() string { return mustProof(t, prover, http.MethodGet, url, unbound) },
+			status:        http.StatusUnauthorized,
+			challenge:     `DPoP algs="ES256", error="invalid_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			if proof := tt.proof(); proof != "" {
+				req.Header.Set(DPoPHeader, proof)
+			}
+
+			rec := httptest.NewRecorder()
+			if err := handler.ServeHTTP(rec, req); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("expected challenge %q but got %q", tt.challenge, got)
+			}
+		})
+	}
+}
+
+func TestAuth_RegisteredClaims(t *testing.T) {
+	const url = "http://example.com/orders"
+	tokenizer := NewRevocationTokenizer(NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")}), NewMemoryRevocationStore(clock.UTC))
+	verifier := mustDPoPVerifier(t, NewMemoryReplayCache(clock.UTC), WithDPoPAlgorithms("ES256"))
+	next := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		claims, ok := ClaimsFromContext[jwt.RegisteredClaims](r.Context())
+		if !ok || claims.Subject != "user" {
+			t.Errorf("expected the claims of user in the context but got %v", claims)
+		}
+
+		return nil
+	})
+
+	prover, err := NewDPoPProver(mustECDSAKey(t, elliptic.P256()))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	bound := Claims{Confirmation: &Confirmation{JKT: prover.Thumbprint()}}
+	bound.Subject = "user"
+	token, _, err := Issue(tokenizer, bound)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// T does not decode the cnf claim, the binding is still enforced.
+	bearer := BearerAuth[jwt.RegisteredClaims](tokenizer)(next)
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	if err := bearer.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d but got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	dpop := DPoPAuth[jwt.RegisteredClaims](tokenizer, verifier)(next)
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "DPoP "+token)
+	req.Header.Set(DPoPHeader, mustProof(t, prover, http.MethodGet, url, token))
+	rec = httptest.NewRecorder()
+	if err := dpop.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
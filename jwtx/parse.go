@@ -0,0 +1,205 @@
+package jwtx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+// DefaultMaxTokenLength is the default maximum length of a token accepted by
+// the tokenizers.
+const DefaultMaxTokenLength = 8 << 10
+
+var (
+	// ErrMalformed is returned when the token can not be decoded.
+	ErrMalformed = errors.New("jwtx: malformed token")
+
+	// ErrTokenTooLong is returned when the token exceeds the maximum length.
+	ErrTokenTooLong = errors.New("jwtx: token too long")
+
+	// ErrBadSignature is returned when the token signature is invalid.
+	ErrBadSignature = errors.New("jwtx: bad signature")
+
+	// ErrUnknownKID is returned when the token key id is missing or unknown.
+	ErrUnknownKID = errors.New("jwtx: unknown key id")
+
+	// ErrWrongAlg is returned when the token algorithm is not expected.
+	ErrWrongAlg = errors.New("jwtx: wrong algorithm")
+
+	// ErrWrongType is returned when the token typ header is not expected.
+	ErrWrongType = errors.New("jwtx: wrong token type")
+
+	// ErrExpired is returned when the token is expired.
+	ErrExpired = errors.New("jwtx: token expired")
+
+	// ErrNotValidYet is returned when the token is used before its nbf claim.
+	ErrNotValidYet = errors.New("jwtx: token not valid yet")
+
+	// ErrInvalidClaims is returned when the token claims are invalid.
+	ErrInvalidClaims = errors.New("jwtx: invalid claims")
+)
+
+// tokenError classifies the cause as one of the exported errors.
+// errors.Is matches both the kind and the cause.
+type tokenError struct {
+	kind  error
+	cause error
+}
+
+func (e *tokenError) Error() string {
+	if e.cause == nil {
+		return e.kind.Error()
+	}
+
+	return e.kind.Error() + ": " + e.cause.Error()
+}
+
+func (e *tokenError) Is(target error) bool { return target == e.kind }
+
+func (e *tokenError) Unwrap() error { return e.cause }
+
+// Option is an option to configure how a tokenizer verifies tokens.
+type Option func(*parser)
+
+// WithAlgorithms configures the algorithms accepted by the tokenizer.
+// The "none" algorithm is always rejected.
+func WithAlgorithms(algs ...string) Option {
+	return func(p *parser) {
+		p.algs = algs
+	}
+}
+
+// WithType configures the expected typ header, an empty type accepts tokens
+// without typ header. The default is "JWT".
+func WithType(typ string) Option {
+	return func(p *parser) {
+		p.typ = typ
+	}
+}
+
+// WithMaxLength configures the maximum length of the accepted tokens.
+func WithMaxLength(n int) Option {
+	return func(p *parser) {
+		p.maxLength = n
+	}
+}
+
+// parser parses and verifies tokens strictly.
+type parser struct {
+	algs      []string
+	typ       string
+	maxLength int
+	clock     clock.Clock
+	leeway    time.Duration
+	issuers   []string
+	audience  string
+	required  []string
+	maxAge    time.Duration
+}
+
+func newParser(algs []string, opts ...Option) *parser {
+	p := &parser{
+		algs:      algs,
+		typ:       "JWT",
+		maxLength: DefaultMaxTokenLength,
+		clock:     clock.UTC,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// parse verifies the token header before the keyFunc is called to look up
+// the verification key, then validates the registered claims once the
+// signature is verified, and classifies the errors. The Valid method of the
+// claims is called last, see validClaims.
+func (p *parser) parse(token string, claims jwt.Claims, keyFunc jwt.Keyfunc) error {
+	if p.maxLength > 0 && len(token) > p.maxLength {
+		return ErrTokenTooLong
+	}
+
+	parsed, err := jwtParser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if err := p.verifyHeader(t); err != nil {
+			return nil, err
+		}
+
+		return keyFunc(t)
+	})
+
+	if err != nil {
+		return classify(err)
+	}
+
+	if err := p.validateClaims(parsed); err != nil {
+		return err
+	}
+
+	return validClaims(claims)
+}
+
+func (p *parser) verifyHeader(t *jwt.Token) error {
+	alg, _ := t.Header["alg"].(string)
+	if alg == "" || alg == jwt.SigningMethodNone.Alg() || alg != t.Method.Alg() || !p.accepts(alg) {
+		return &tokenError{kind: ErrWrongAlg, cause: fmt.Errorf("unexpected algorithm %q", alg)}
+	}
+
+	typ, _ := t.Header["typ"].(string)
+	if p.typ != "" && !strings.EqualFold(typ, p.typ) {
+		return &tokenError{kind: ErrWrongType, cause: fmt.Errorf("unexpected type %q", typ)}
+	}
+
+	return nil
+}
+
+func (p *parser) accepts(alg string) bool { return contains(p.algs, alg) }
+
+// jwtParser only verifies the signature, the claims are validated by the
+// parser.
+var jwtParser = jwt.NewParser(jwt.WithoutClaimsValidation())
+
+// unknownKID is returned by the keyFuncs when the key id is missing or unknown.
+var unknownKID = &tokenError{kind: ErrUnknownKID, cause: jwt.ErrInvalidKey}
+
+// classify maps the errors returned by jwt.ParseWithClaims to the exported
+// errors.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	var te *tokenError
+	if errors.As(ve.Inner, &te) {
+		return te
+	}
+
+	var kind error
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		kind = ErrMalformed
+	case ve.Errors&jwt.ValidationErrorUnverifiable != 0:
+		// the only unverifiable tokens left are those with an unknown alg.
+		kind = ErrWrongAlg
+	case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		kind = ErrBadSignature
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		kind = ErrExpired
+	case ve.Errors&jwt.ValidationErrorNotValidYet != 0:
+		kind = ErrNotValidYet
+	default:
+		kind = ErrInvalidClaims
+	}
+
+	return &tokenError{kind: kind, cause: err}
+}
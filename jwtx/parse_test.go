@@ -0,0 +1,174 @@
+package jwtx
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, header map[string]interface{}, claims jwt.Claims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	for k, v := range header {
+		if v == nil {
+			delete(token.Header, k)
+			continue
+		}
+
+		token.Header[k] = v
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return s
+}
+
+func TestDetokenize_Errors(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	rsaTokenizer := NewRSA256RoundRobinTokenizer(map[string]*rsa.PrivateKey{"key-1": privateKey})
+	hmacTokenizer := SHA256Tokenizer("my-key")
+
+	valid := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	expired := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}
+	notYet := jwt.RegisteredClaims{NotBefore: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
+	})
+
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+		token     string
+		expected  error
+	}{
+		{
+			name:      "malformed",
+			tokenizer: hmacTokenizer,
+			token:     "not.a.token",
+			expected:  ErrMalformed,
+		},
+		{
+			name:      "too long",
+			tokenizer: hmacTokenizer,
+			token:     strings.Repeat("a", DefaultMaxTokenLength+1),
+			expected:  ErrTokenTooLong,
+		},
+		{
+			name:      "none algorithm",
+			tokenizer: hmacTokenizer,
+			token:     signToken(t, jwt.SigningMethodNone, nil, valid, jwt.UnsafeAllowNoneSignatureType),
+			expected:  ErrWrongAlg,
+		},
+		{
+			name:      "unknown algorithm",
+			tokenizer: hmacTokenizer,
+			token:     "eyJhbGciOiJIUzk5OSIsInR5cCI6IkpXVCJ9.e30.c2ln",
+			expected:  ErrWrongAlg,
+		},
+		{
+			name:      "hmac signed with the rsa public key",
+			tokenizer: rsaTokenizer,
+			token:     signToken(t, jwt.SigningMethodHS256, map[string]interface{}{"kid": "key-1"}, valid, publicPEM),
+			expected:  ErrWrongAlg,
+		},
+		{
+			name:      "rs512 is not accepted by default",
+			tokenizer: rsaTokenizer,
+			token:     signToken(t, jwt.SigningMethodRS512, map[string]interface{}{"kid": "key-1"}, valid, privateKey),
+			expected:  ErrWrongAlg,
+		},
+		{
+			name:      "wrong type",
+			tokenizer: hmacTokenizer,
+			token:     signToken(t, jwt.SigningMethodHS256, map[string]interface{}{"typ": "at+jwt"}, valid, []byte("my-key")),
+			expected:  ErrWrongType,
+		},
+		{
+			name:      "missing type",
+			tokenizer: hmacTokenizer,
+			token:     signToken(t, jwt.SigningMethodHS256, map[string]interface{}{"typ": nil}, valid, []byte("my-key")),
+			expected:  ErrWrongType,
+		},
+		{
+			name:      "bad signature",
+			tokenizer: rsaTokenizer,
+			token:     signToken(t, jwt.SigningMethodRS256, map[string]interface{}{"kid": "key-1"}, valid, otherKey),
+			expected:  ErrBadSignature,
+		},
+		{
+			name:      "unknown kid",
+			tokenizer: rsaTokenizer,
+			token:     signToken(t, jwt.SigningMethodRS256, map[string]interface{}{"kid": "key-2"}, valid, privateKey),
+			expected:  ErrUnknownKID,
+		},
+		{
+			name:      "expired",
+			tokenizer: hmacTokenizer,
+			token:     signToken(t, jwt.SigningMethodHS256, nil, expired, []byte("my-key")),
+			expected:  ErrExpired,
+		},
+		{
+			name:      "not valid yet",
+			tokenizer: hmacTokenizer,
+			token:     signToken(t, jwt.SigningMethodHS256, nil, notYet, []byte("my-key")),
+			expected:  ErrNotValidYet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims jwt.RegisteredClaims
+			err := tt.tokenizer.Detokenize(tt.token, &claims)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRSA256RoundRobinTokenizer_Options(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	tokenizer := NewRSA256RoundRobinTokenizer(
+		map[string]*rsa.PrivateKey{"key-1": privateKey},
+		WithAlgorithms(jwt.SigningMethodRS256.Alg(), jwt.SigningMethodRS512.Alg()),
+		WithType(""),
+		WithMaxLength(1024),
+	)
+
+	claims := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	token := signToken(t, jwt.SigningMethodRS512, map[string]interface{}{"kid": "key-1", "typ": nil}, claims, privateKey)
+
+	var decoded jwt.RegisteredClaims
+	if err := tokenizer.Detokenize(token, &decoded); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(token+strings.Repeat("a", 1024), &decoded); !errors.Is(err, ErrTokenTooLong) {
+		t.Errorf("expected %v but got %v", ErrTokenTooLong, err)
+	}
+}
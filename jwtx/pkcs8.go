@@ -0,0 +1,144 @@
+package jwtx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is the PKCS#8 EncryptedPrivateKeyInfo structure.
+type encryptedPrivateKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+// pbes2Params are the PBES2 parameters of RFC 8018.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the PBKDF2 parameters of RFC 8018, the PRF defaults to
+// HMAC-SHA1.
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts the DER of an ENCRYPTED PRIVATE KEY block into the
+// DER of a PKCS#8 private key. Only PBES2 with PBKDF2 and AES-CBC is
+// supported, which is what OpenSSL produces by default.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w: invalid encrypted private key", ErrUnsupportedKey)
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: encryption %s", ErrUnsupportedKey, info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("%w: invalid PBES2 parameters", ErrUnsupportedKey)
+	}
+
+	key, err := pbes2Key(params, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("%w: invalid AES-CBC parameters", ErrUnsupportedKey)
+	}
+
+	if len(info.Data) == 0 || len(info.Data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid encrypted data length", ErrUnsupportedKey)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(info.Data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.Data)
+	return unpad(plain)
+}
+
+// pbes2Key derives the AES key from the passphrase.
+func pbes2Key(params pbes2Params, passphrase []byte) ([]byte, error) {
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("%w: key derivation %s", ErrUnsupportedKey, params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil || kdf.Iterations < 1 {
+		return nil, fmt.Errorf("%w: invalid PBKDF2 parameters", ErrUnsupportedKey)
+	}
+
+	var prf func() hash.Hash
+	switch alg := kdf.PRF.Algorithm; {
+	case len(alg) == 0, alg.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case alg.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("%w: PBKDF2 PRF %s", ErrUnsupportedKey, alg)
+	}
+
+	var size int
+	switch alg := params.EncryptionScheme.Algorithm; {
+	case alg.Equal(oidAES128CBC):
+		size = 16
+	case alg.Equal(oidAES192CBC):
+		size = 24
+	case alg.Equal(oidAES256CBC):
+		size = 32
+	default:
+		return nil, fmt.Errorf("%w: encryption scheme %s", ErrUnsupportedKey, alg)
+	}
+
+	if kdf.KeyLength != 0 && kdf.KeyLength != size {
+		return nil, fmt.Errorf("%w: PBKDF2 key length %d", ErrUnsupportedKey, kdf.KeyLength)
+	}
+
+	return pbkdf2.Key(passphrase, kdf.Salt, kdf.Iterations, size, prf), nil
+}
+
+// unpad removes the PKCS#7 padding, an invalid padding means that the
+// passphrase is wrong.
+func unpad(b []byte) ([]byte, error) {
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, ErrWrongPassphrase
+	}
+
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, ErrWrongPassphrase
+		}
+	}
+
+	return b[:len(b)-n], nil
+}
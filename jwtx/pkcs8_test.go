@@ -0,0 +1,70 @@
+package jwtx
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestDecryptPKCS8(t *testing.T) {
+	block, _ := pem.Decode([]byte(encryptedRSAKey))
+
+	t.Run("unsupported encryption", func(t *testing.T) {
+		der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}},
+			Data:      make([]byte, 16),
+		})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if _, err := decryptPKCS8(der, []byte("correct-horse")); !errors.Is(err, ErrUnsupportedKey) {
+			t.Errorf("expected error %v but got %v", ErrUnsupportedKey, err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := decryptPKCS8(block.Bytes[:100], []byte("correct-horse")); !errors.Is(err, ErrUnsupportedKey) {
+			t.Errorf("expected error %v but got %v", ErrUnsupportedKey, err)
+		}
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		der, err := decryptPKCS8(block.Bytes, []byte("correct-horse"))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if _, err := parsePrivateKeyBlock(&pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	})
+}
+
+func TestUnpad(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want int
+		err  error
+	}{
+		{append(make([]byte, 12), 4, 4, 4, 4), 12, nil},
+		{append(make([]byte, 15), 1), 15, nil},
+		{append(make([]byte, 15), 0), 0, ErrWrongPassphrase},
+		{append(make([]byte, 15), 17), 0, ErrWrongPassphrase},
+		{append(make([]byte, 13), 1, 3, 3), 0, ErrWrongPassphrase},
+	}
+
+	for _, tt := range tests {
+		got, err := unpad(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("expected error %v but got %v", tt.err, err)
+		}
+
+		if err == nil && len(got) != tt.want {
+			t.Errorf("expected %d bytes but got %d", tt.want, len(got))
+		}
+	}
+}
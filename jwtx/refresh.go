@@ -0,0 +1,271 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+	"github.com/josestg/gokit/uniq"
+)
+
+// DefaultRefreshTTL is the default lifetime of the refresh tokens.
+const DefaultRefreshTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrRefreshTokenNotFound is returned when the refresh token is unknown
+	// to the store.
+	ErrRefreshTokenNotFound = errors.New("jwtx: refresh token not found")
+
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is used again. The whole token family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("jwtx: refresh token reused")
+
+	// ErrRefreshTokenRevoked is returned when the family of the refresh token
+	// is revoked.
+	ErrRefreshTokenRevoked = errors.New("jwtx: refresh token revoked")
+)
+
+// RefreshRecord is the stored state of a refresh token.
+type RefreshRecord struct {
+	// ID is the jti of the refresh token.
+	ID string
+	// Family is the id shared by the refresh tokens rotated from the same
+	// login.
+	Family string
+	// Claims are the claims copied into the access tokens.
+	Claims Claims
+	// ExpiresAt is the expiration time of the refresh token.
+	ExpiresAt time.Time
+	// RotatedAt is the time the refresh token was exchanged, zero if it is
+	// still usable.
+	RotatedAt time.Time
+	// Revoked reports whether the family of the token is revoked.
+	Revoked bool
+}
+
+// RefreshStore persists the refresh tokens.
+type RefreshStore interface {
+	// Save stores a new refresh token.
+	Save(ctx context.Context, r RefreshRecord) error
+	// Find returns the refresh token with the given id, or
+	// ErrRefreshTokenNotFound.
+	Find(ctx context.Context, id string) (RefreshRecord, error)
+	// Rotate atomically marks the refresh token as rotated at next's creation
+	// and stores next. It returns ErrRefreshTokenReused when the token is
+	// already rotated, and ErrRefreshTokenRevoked when its family is revoked.
+	Rotate(ctx context.Context, id string, rotatedAt time.Time, next RefreshRecord) error
+	// RevokeFamily revokes every refresh token of the family.
+	RevokeFamily(ctx context.Context, family string) error
+}
+
+// TokenPair is an access token with its refresh token, encoded as an
+// OAuth 2.0 token response.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshOption is an option to configure the Refresher.
+type RefreshOption func(*Refresher)
+
+// WithAccessTTL configures the lifetime of the access tokens. The default is
+// DefaultTTL.
+func WithAccessTTL(ttl time.Duration) RefreshOption {
+	return func(r *Refresher) {
+		r.accessTTL = ttl
+	}
+}
+
+// WithRefreshTTL configures the lifetime of the refresh tokens. The default
+// is DefaultRefreshTTL.
+func WithRefreshTTL(ttl time.Duration) RefreshOption {
+	return func(r *Refresher) {
+		r.refreshTTL = ttl
+	}
+}
+
+// WithRefreshClock configures the clock used to issue the tokens.
+func WithRefreshClock(c clock.Clock) RefreshOption {
+	return func(r *Refresher) {
+		r.clock = c
+	}
+}
+
+// WithRefreshIDGenerator configures the generator of the token and family ids.
+func WithRefreshIDGenerator(ids uniq.Stringer) RefreshOption {
+	return func(r *Refresher) {
+		r.ids = ids
+	}
+}
+
+// refreshClaims are the claims of the refresh tokens.
+type refreshClaims struct {
+	Claims
+	Family string `json:"fam"`
+}
+
+// Refresher issues access and refresh token pairs, and rotates the refresh
+// token every time it is exchanged for a new pair.
+//
+// The refresh tokens rotated from the same login form a family. When a
+// rotated refresh token is used again, which means it has leaked, the whole
+// family is revoked, so neither the attacker nor the legitimate client can
+// keep refreshing.
+//
+// The access and refresh tokenizers should use different keys, so that an
+// access token is never accepted as a refresh token.
+type Refresher struct {
+	access     Tokenizer
+	refresh    Tokenizer
+	store      RefreshStore
+	clock      clock.Clock
+	ids        uniq.Stringer
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewRefresher creates a new Refresher.
+func NewRefresher(access, refresh Tokenizer, store RefreshStore, opts ...RefreshOption) *Refresher {
+	r := &Refresher{
+		access:     access,
+		refresh:    refresh,
+		store:      store,
+		clock:      clock.UTC,
+		ids:        uniq.NewUUID(uniq.RandomReader),
+		accessTTL:  DefaultTTL,
+		refreshTTL: DefaultRefreshTTL,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Login issues the first token pair of a new family. The time based claims
+// and the jti of the given claims are ignored.
+func (r *Refresher) Login(ctx context.Context, claims Claims) (TokenPair, error) {
+	family, err := r.ids.NextString()
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	pair, record, err := r.issue(family, claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := r.store.Save(ctx, record); err != nil {
+		return TokenPair{}, err
+	}
+
+	return pair, nil
+}
+
+// Refresh exchanges the refresh token for a new token pair. A refresh token
+// can only be exchanged once, using it again revokes its family and returns
+// ErrRefreshTokenReused.
+func (r *Refresher) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	current, err := r.find(ctx, refreshToken)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if current.Revoked {
+		return TokenPair{}, ErrRefreshTokenRevoked
+	}
+
+	pair, next, err := r.issue(current.Family, current.Claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = r.store.Rotate(ctx, current.ID, r.clock.Now(), next)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := r.store.RevokeFamily(ctx, current.Family); revokeErr != nil {
+			return TokenPair{}, revokeErr
+		}
+
+		return TokenPair{}, err
+	}
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return pair, nil
+}
+
+// Revoke revokes the family of the refresh token, for example on logout.
+func (r *Refresher) Revoke(ctx context.Context, refreshToken string) error {
+	current, err := r.find(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return r.store.RevokeFamily(ctx, current.Family)
+}
+
+// find verifies the refresh token and returns its record.
+func (r *Refresher) find(ctx context.Context, refreshToken string) (RefreshRecord, error) {
+	claims, err := Verify[refreshClaims](r.refresh, refreshToken)
+	if err != nil {
+		return RefreshRecord{}, err
+	}
+
+	record, err := r.store.Find(ctx, claims.ID)
+	if err != nil {
+		return RefreshRecord{}, err
+	}
+
+	if record.Family != claims.Family {
+		return RefreshRecord{}, ErrRefreshTokenNotFound
+	}
+
+	return record, nil
+}
+
+// issue creates a token pair of the family and the record of its refresh
+// token.
+func (r *Refresher) issue(family string, claims Claims) (TokenPair, RefreshRecord, error) {
+	now := r.clock.Now()
+	claims.IssuedAt, claims.NotBefore, claims.ExpiresAt, claims.ID = nil, nil, nil, ""
+
+	access, _, err := Issue(r.access, claims,
+		WithIssueClock(r.clock),
+		WithTTL(r.accessTTL),
+		WithIDGenerator(r.ids),
+	)
+	if err != nil {
+		return TokenPair{}, RefreshRecord{}, err
+	}
+
+	refresh, issued, err := Issue(r.refresh, refreshClaims{Claims: claims, Family: family},
+		WithIssueClock(r.clock),
+		WithTTL(r.refreshTTL),
+		WithIDGenerator(r.ids),
+	)
+	if err != nil {
+		return TokenPair{}, RefreshRecord{}, err
+	}
+
+	pair := TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(r.accessTTL / time.Second),
+		RefreshToken: refresh,
+	}
+
+	record := RefreshRecord{
+		ID:        issued.ID,
+		Family:    family,
+		Claims:    claims,
+		ExpiresAt: now.Add(r.refreshTTL),
+	}
+
+	return pair, record, nil
+}
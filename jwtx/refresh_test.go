@@ -0,0 +1,152 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestRefresher(c *fakeClock) (*Refresher, *MemoryRefreshStore) {
+	access := NewHS256Tokenizer(map[string][]byte{"access": []byte("access-key")}, WithClock(c))
+	refresh := NewHS256Tokenizer(map[string][]byte{"refresh": []byte("refresh-key")}, WithClock(c))
+	store := NewMemoryRefreshStore(c)
+	return NewRefresher(access, refresh, store, WithRefreshClock(c), WithRefreshTTL(time.Hour)), store
+}
+
+func TestRefresher_Rotation(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	r, _ := newTestRefresher(c)
+
+	claims := Claims{Scope: "read"}
+	claims.Subject = "user"
+
+	first, err := r.Login(ctx, claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if first.TokenType != "Bearer" || first.ExpiresIn != int64(DefaultTTL/time.Second) {
+		t.Errorf("unexpected token pair %+v", first)
+	}
+
+	if _, err := r.Refresh(ctx, first.AccessToken); !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected the access token to be rejected but got %v", err)
+	}
+
+	c.Add(time.Minute)
+	second, err := r.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	access, err := Verify[Claims](r.access, second.AccessToken)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if access.Subject != "user" || access.Scope != "read" || !access.IssuedAt.Equal(c.Now()) {
+		t.Errorf("unexpected access claims %+v", access)
+	}
+
+	third, err := r.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// reusing the rotated token revokes the family.
+	if _, err := r.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected %v but got %v", ErrRefreshTokenReused, err)
+	}
+
+	if _, err := r.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected %v but got %v", ErrRefreshTokenRevoked, err)
+	}
+
+	// other families are not affected.
+	other, err := r.Login(ctx, claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if _, err := r.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+}
+
+func TestRefresher_Revoke(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRefresher(newFakeClock())
+
+	pair, err := r.Login(ctx, Claims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := r.Revoke(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if _, err := r.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected %v but got %v", ErrRefreshTokenRevoked, err)
+	}
+}
+
+func TestRefresher_Expired(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	r, store := newTestRefresher(c)
+
+	pair, err := r.Login(ctx, Claims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Hour)
+	if _, err := r.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected %v but got %v", ErrExpired, err)
+	}
+
+	if _, err := r.Login(ctx, Claims{}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if n := len(store.families); n != 1 {
+		t.Errorf("expected the expired family to be pruned but got %d families", n)
+	}
+}
+
+func TestRefresher_ConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRefresher(newFakeClock())
+
+	pair, err := r.Login(ctx, Claims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Refresh(ctx, pair.RefreshToken); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	if succeeded != 1 {
+		t.Errorf("expected exactly one refresh to succeed but got %d", succeeded)
+	}
+}
@@ -0,0 +1,115 @@
+package jwtx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+// MemoryRefreshStore is an in-memory RefreshStore. The expired refresh
+// tokens are removed when new ones are saved.
+type MemoryRefreshStore struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	records  map[string]RefreshRecord
+	families map[string][]string
+}
+
+// NewMemoryRefreshStore creates a new MemoryRefreshStore that expires the
+// refresh tokens with the given clock.
+func NewMemoryRefreshStore(c clock.Clock) *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		clock:    c,
+		records:  make(map[string]RefreshRecord),
+		families: make(map[string][]string),
+	}
+}
+
+func (s *MemoryRefreshStore) Save(_ context.Context, r RefreshRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(s.clock.Now())
+	s.save(r)
+	return nil
+}
+
+func (s *MemoryRefreshStore) Find(_ context.Context, id string) (RefreshRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok || !s.clock.Now().Before(r.ExpiresAt) {
+		return RefreshRecord{}, ErrRefreshTokenNotFound
+	}
+
+	return r, nil
+}
+
+func (s *MemoryRefreshStore) Rotate(_ context.Context, id string, rotatedAt time.Time, next RefreshRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	switch {
+	case !ok:
+		return ErrRefreshTokenNotFound
+	case r.Revoked:
+		return ErrRefreshTokenRevoked
+	case !r.RotatedAt.IsZero():
+		return ErrRefreshTokenReused
+	}
+
+	r.RotatedAt = rotatedAt
+	s.records[id] = r
+	s.save(next)
+	return nil
+}
+
+func (s *MemoryRefreshStore) RevokeFamily(_ context.Context, family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.families[family] {
+		r := s.records[id]
+		r.Revoked = true
+		s.records[id] = r
+	}
+
+	return nil
+}
+
+func (s *MemoryRefreshStore) save(r RefreshRecord) {
+	if _, exists := s.records[r.ID]; !exists {
+		s.families[r.Family] = append(s.families[r.Family], r.ID)
+	}
+
+	s.records[r.ID] = r
+}
+
+// prune removes the families whose refresh tokens are all expired, the
+// rotated tokens of a live family are kept to detect their reuse.
+func (s *MemoryRefreshStore) prune(now time.Time) {
+	for family, ids := range s.families {
+		expired := true
+		for _, id := range ids {
+			if now.Before(s.records[id].ExpiresAt) {
+				expired = false
+				break
+			}
+		}
+
+		if !expired {
+			continue
+		}
+
+		for _, id := range ids {
+			delete(s.records, id)
+		}
+
+		delete(s.families, family)
+	}
+}
@@ -0,0 +1,361 @@
+package jwtx
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+// ErrVerifyOnly is returned when a verify-only tokenizer is asked to create a token.
+var ErrVerifyOnly = errors.New("jwtx: tokenizer can only verify tokens")
+
+// asymmetricAlgorithms are the algorithms that can be verified with a public JWK.
+var asymmetricAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// RemoteOption is an option to configure the RemoteJWKSTokenizer.
+type RemoteOption func(*RemoteJWKSTokenizer)
+
+// WithHTTPClient configures the client used to fetch the key set.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.client = client
+	}
+}
+
+// WithRemoteClock configures the clock used to schedule the refreshes.
+func WithRemoteClock(c clock.Clock) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.clock = c
+	}
+}
+
+// WithRefreshInterval configures how long the keys are cached when the
+// response has no Cache-Control max-age.
+func WithRefreshInterval(interval time.Duration) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.refreshInterval = interval
+	}
+}
+
+// WithMinRefreshInterval configures the minimum time between two fetches,
+// which prevents refresh storms caused by tokens with unknown kids or by an
+// unavailable endpoint.
+func WithMinRefreshInterval(interval time.Duration) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.minRefreshInterval = interval
+	}
+}
+
+// WithFetchTimeout configures how long a verification waits for the key set
+// to be fetched when the kid of its token is not cached.
+func WithFetchTimeout(timeout time.Duration) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.fetchTimeout = timeout
+	}
+}
+
+// WithParserOptions configures how the tokens are verified. By default every
+// asymmetric algorithm is accepted and the claims are validated with the
+// clock of the tokenizer.
+func WithParserOptions(opts ...Option) RemoteOption {
+	return func(t *RemoteJWKSTokenizer) {
+		t.parserOptions = opts
+	}
+}
+
+// RemoteJWKSTokenizer is a verify-only tokenizer that verifies tokens with
+// the keys of a remote JSON Web Key Set.
+//
+// The keys are cached by kid. When the cache expires, following the
+// Cache-Control max-age of the response, the stale keys keep being used while
+// the key set is refreshed in the background, so a slow or unavailable
+// endpoint does not delay the verifications. Only a token with an unknown kid
+// waits for the key set, at most for the fetch timeout.
+type RemoteJWKSTokenizer struct {
+	url                string
+	client             *http.Client
+	clock              clock.Clock
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	fetchTimeout       time.Duration
+	parserOptions      []Option
+	parser             *parser
+
+	// fetching serializes the fetches, unlike a mutex its waiters can give up
+	// when their context is done.
+	fetching chan struct{}
+	// background tracks the background refreshes.
+	background sync.WaitGroup
+
+	mu          sync.RWMutex
+	keys        map[string]remoteKey
+	expiresAt   time.Time
+	lastAttempt time.Time
+}
+
+// remoteKey is a cached public key with its declared algorithm.
+type remoteKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// verify returns the key to verify the token, if the token algorithm is the
+// declared algorithm.
+func (k remoteKey) verify(kid string, token *jwt.Token) (interface{}, error) {
+	if k.alg != "" && k.alg != token.Method.Alg() {
+		return nil, &tokenError{kind: ErrWrongAlg, cause: fmt.Errorf("key %s is for %s", kid, k.alg)}
+	}
+
+	return k.key, nil
+}
+
+// verificationKeys returns the signature verification keys of the set by kid,
+// the keys that are not supported are skipped.
+func verificationKeys(set JWKSet) map[string]remoteKey {
+	keys := make(map[string]remoteKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kid == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = remoteKey{alg: jwk.Alg, key: key}
+	}
+
+	return keys
+}
+
+// NewRemoteJWKSTokenizer creates a new RemoteJWKSTokenizer for the key set
+// served at url. The key set is fetched lazily on first use.
+func NewRemoteJWKSTokenizer(url string, opts ...RemoteOption) *RemoteJWKSTokenizer {
+	t := &RemoteJWKSTokenizer{
+		url:                url,
+		client:             &http.Client{Timeout: 10 * time.Second},
+		clock:              clock.UTC,
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+		fetchTimeout:       3 * time.Second,
+		fetching:           make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.parser = newParser(asymmetricAlgorithms, append([]Option{WithClock(t.clock)}, t.parserOptions...)...)
+	return t
+}
+
+// Tokenize always returns ErrVerifyOnly.
+func (t *RemoteJWKSTokenizer) Tokenize(_ jwt.Claims) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (t *RemoteJWKSTokenizer) Detokenize(token string, claims jwt.Claims) error {
+	return t.parser.parse(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, unknownKID
+		}
+
+		key, ok := t.lookup(kid)
+		if !ok {
+			ctx, cancel := context.WithTimeout(context.Background(), t.fetchTimeout)
+			defer cancel()
+
+			_ = t.refresh(ctx, true)
+			if key, ok = t.lookup(kid); !ok {
+				return nil, unknownKID
+			}
+		} else if t.expired() {
+			t.refreshInBackground()
+		}
+
+		return key.verify(kid, token)
+	})
+}
+
+// Refresh fetches the key set, regardless of the cache state.
+func (t *RemoteJWKSTokenizer) Refresh(ctx context.Context) error {
+	if err := t.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer t.release()
+	return t.fetch(ctx)
+}
+
+// acquire waits for the other fetches to complete, or for ctx to be done.
+func (t *RemoteJWKSTokenizer) acquire(ctx context.Context) error {
+	select {
+	case t.fetching <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *RemoteJWKSTokenizer) release() { <-t.fetching }
+
+// refreshInBackground refreshes the expired key set in the background, unless
+// a fetch is already in progress. The errors are ignored, the stale keys are
+// still used.
+func (t *RemoteJWKSTokenizer) refreshInBackground() {
+	select {
+	case t.fetching <- struct{}{}:
+	default:
+		return
+	}
+
+	t.background.Add(1)
+	go func() {
+		defer t.background.Done()
+		defer t.release()
+		_ = t.refreshAcquired(context.Background(), false)
+	}()
+}
+
+func (t *RemoteJWKSTokenizer) lookup(kid string) (remoteKey, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	key, ok := t.keys[kid]
+	return key, ok
+}
+
+func (t *RemoteJWKSTokenizer) expired() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.clock.Now().Before(t.expiresAt)
+}
+
+// refresh fetches the key set unless the last fetch attempt is more recent
+// than the minimum refresh interval. When force is false, the key set is
+// only fetched if the cache is expired.
+func (t *RemoteJWKSTokenizer) refresh(ctx context.Context, force bool) error {
+	if err := t.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer t.release()
+	return t.refreshAcquired(ctx, force)
+}
+
+// refreshAcquired is refresh once the other fetches are completed.
+func (t *RemoteJWKSTokenizer) refreshAcquired(ctx context.Context, force bool) error {
+	t.mu.RLock()
+	now := t.clock.Now()
+	throttled := !t.lastAttempt.IsZero() && now.Sub(t.lastAttempt) < t.minRefreshInterval
+	fresh := now.Before(t.expiresAt)
+	t.mu.RUnlock()
+
+	// another caller may have refreshed while we were waiting for the lock.
+	if throttled || (!force && fresh) {
+		return nil
+	}
+
+	return t.fetch(ctx)
+}
+
+func (t *RemoteJWKSTokenizer) fetch(ctx context.Context) error {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	t.lastAttempt = now
+	t.mu.Unlock()
+
+	set, maxAge, err := t.download(ctx)
+	if err != nil {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// retry once the minimum interval has passed, keep the stale keys.
+		t.expiresAt = now.Add(t.minRefreshInterval)
+		return err
+	}
+
+	keys := verificationKeys(set)
+
+	ttl := t.refreshInterval
+	if maxAge >= 0 {
+		ttl = maxAge
+	}
+
+	if ttl < t.minRefreshInterval {
+		ttl = t.minRefreshInterval
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = keys
+	t.expiresAt = now.Add(ttl)
+	return nil
+}
+
+// download fetches the key set and the max-age of its Cache-Control header,
+// the max-age is negative when the header has none.
+func (t *RemoteJWKSTokenizer) download(ctx context.Context) (JWKSet, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return JWKSet{}, 0, err
+	}
+
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return JWKSet{}, 0, fmt.Errorf("fetching jwks: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return JWKSet{}, 0, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return JWKSet{}, 0, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	return set, maxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// maxAge returns the max-age directive of the Cache-Control header, no-cache
+// and no-store are treated as a zero max-age. It returns -1 when the header
+// has no such directive.
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	return -1
+}
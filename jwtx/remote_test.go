@@ -0,0 +1,284 @@
+package jwtx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock { return &fakeClock{now: clock.StaticTime} }
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// jwksServer serves the public keys of the current tokenizer.
+type jwksServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	source       PublicKeySource
+	cacheControl string
+	down         bool
+	block        chan struct{}
+	hits         int32
+}
+
+func newJWKSServer(t *testing.T, source PublicKeySource) *jwksServer {
+	s := &jwksServer{source: source}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.hits, 1)
+		s.mu.Lock()
+		block := s.block
+		s.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+
+		set, err := NewJWKSet(s.source)
+		if err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(fn func(s *jwksServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func (s *jwksServer) Hits() int32 { return atomic.LoadInt32(&s.hits) }
+
+func TestRemoteJWKSTokenizer(t *testing.T) {
+	signer := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	srv := newJWKSServer(t, signer)
+
+	c := newFakeClock()
+	verifier := NewRemoteJWKSTokenizer(srv.URL, WithRemoteClock(c), WithRefreshInterval(time.Hour), WithMinRefreshInterval(time.Minute))
+
+	if _, err := verifier.Tokenize(jwt.RegisteredClaims{}); !errors.Is(err, ErrVerifyOnly) {
+		t.Errorf("expected %v but got %v", ErrVerifyOnly, err)
+	}
+
+	token, err := signer.Tokenize(jwt.RegisteredClaims{Subject: "gopher"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		var claims jwt.RegisteredClaims
+		if err := verifier.Detokenize(token, &claims); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if claims.Subject != "gopher" {
+			t.Errorf("expected subject gopher but got %q", claims.Subject)
+		}
+	}
+
+	if hits := srv.Hits(); hits != 1 {
+		t.Errorf("expected the keys to be cached, got %d fetches", hits)
+	}
+
+	c.Add(time.Hour)
+	var claims jwt.RegisteredClaims
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	verifier.background.Wait()
+	if hits := srv.Hits(); hits != 2 {
+		t.Errorf("expected the keys to be refreshed after the interval, got %d fetches", hits)
+	}
+}
+
+func TestRemoteJWKSTokenizer_UnknownKID(t *testing.T) {
+	oldSigner := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	newSigner := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-2": mustECDSAKey(t, elliptic.P256())})
+	srv := newJWKSServer(t, oldSigner)
+
+	c := newFakeClock()
+	verifier := NewRemoteJWKSTokenizer(srv.URL, WithRemoteClock(c), WithMinRefreshInterval(time.Minute))
+	oldToken, _ := oldSigner.Tokenize(jwt.RegisteredClaims{})
+	newToken, _ := newSigner.Tokenize(jwt.RegisteredClaims{})
+
+	var claims jwt.RegisteredClaims
+	if err := verifier.Detokenize(oldToken, &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	srv.set(func(s *jwksServer) { s.source = newSigner })
+
+	// the refresh is rate limited, so a burst of unknown kids fetches nothing.
+	for i := 0; i < 5; i++ {
+		if err := verifier.Detokenize(newToken, &claims); !errors.Is(err, ErrUnknownKID) {
+			t.Fatalf("expected %v but got %v", ErrUnknownKID, err)
+		}
+	}
+
+	if hits := srv.Hits(); hits != 1 {
+		t.Errorf("expected unknown kid refreshes to be rate limited, got %d fetches", hits)
+	}
+
+	c.Add(time.Minute)
+	if err := verifier.Detokenize(newToken, &claims); err != nil {
+		t.Fatalf("expected the unknown kid to trigger a refresh but got %v", err)
+	}
+
+	if hits := srv.Hits(); hits != 2 {
+		t.Errorf("expected 2 fetches but got %d", hits)
+	}
+}
+
+func TestRemoteJWKSTokenizer_StaleKeys(t *testing.T) {
+	signer := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	srv := newJWKSServer(t, signer)
+	srv.set(func(s *jwksServer) { s.cacheControl = "public, max-age=120" })
+
+	c := newFakeClock()
+	verifier := NewRemoteJWKSTokenizer(srv.URL, WithRemoteClock(c), WithMinRefreshInterval(time.Second))
+
+	token, _ := signer.Tokenize(jwt.RegisteredClaims{})
+
+	var claims jwt.RegisteredClaims
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Minute)
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if hits := srv.Hits(); hits != 1 {
+		t.Errorf("expected the Cache-Control max-age to be honored, got %d fetches", hits)
+	}
+
+	srv.set(func(s *jwksServer) { s.down = true })
+	c.Add(time.Minute)
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected the stale keys to be used but got %v", err)
+	}
+
+	verifier.background.Wait()
+	if hits := srv.Hits(); hits != 2 {
+		t.Errorf("expected a refresh attempt after max-age, got %d fetches", hits)
+	}
+}
+
+func TestRemoteJWKSTokenizer_SlowEndpoint(t *testing.T) {
+	signer := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	other := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-2": mustECDSAKey(t, elliptic.P256())})
+	srv := newJWKSServer(t, signer)
+
+	c := newFakeClock()
+	verifier := NewRemoteJWKSTokenizer(srv.URL, WithRemoteClock(c), WithFetchTimeout(50*time.Millisecond))
+	token, _ := signer.Tokenize(jwt.RegisteredClaims{})
+	unknown, _ := other.Tokenize(jwt.RegisteredClaims{})
+
+	var claims jwt.RegisteredClaims
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	block := make(chan struct{})
+	srv.set(func(s *jwksServer) { s.block = block })
+	c.Add(time.Hour)
+
+	start := time.Now()
+	if err := verifier.Detokenize(token, &claims); err != nil {
+		t.Fatalf("expected the stale keys to be used but got %v", err)
+	}
+
+	// the background refresh is still blocked, the unknown kid gives up.
+	c.Add(time.Hour)
+	if err := verifier.Detokenize(unknown, &claims); !errors.Is(err, ErrUnknownKID) {
+		t.Fatalf("expected %v but got %v", ErrUnknownKID, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the verifications not to wait for the endpoint, took %v", elapsed)
+	}
+
+	close(block)
+	verifier.background.Wait()
+	if hits := srv.Hits(); hits != 2 {
+		t.Errorf("expected 2 fetches but got %d", hits)
+	}
+}
+
+func TestRemoteJWKSTokenizer_WrongAlg(t *testing.T) {
+	signer := NewES256Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P256())})
+	srv := newJWKSServer(t, signer)
+	verifier := NewRemoteJWKSTokenizer(srv.URL, WithRemoteClock(newFakeClock()))
+
+	hmac := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("secret")})
+	token, _ := hmac.Tokenize(jwt.RegisteredClaims{})
+
+	var claims jwt.RegisteredClaims
+	if err := verifier.Detokenize(token, &claims); !errors.Is(err, ErrWrongAlg) {
+		t.Errorf("expected %v but got %v", ErrWrongAlg, err)
+	}
+
+	es384 := NewES384Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P384())})
+	token, _ = es384.Tokenize(jwt.RegisteredClaims{})
+	if err := verifier.Detokenize(token, &claims); !errors.Is(err, ErrWrongAlg) {
+		t.Errorf("expected %v but got %v", ErrWrongAlg, err)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                         -1,
+		"public":                   -1,
+		"public, max-age=60":       time.Minute,
+		"max-age=\"30\"":           30 * time.Second,
+		"no-store":                 0,
+		"private, max-age=invalid": -1,
+	}
+
+	for header, expected := range tests {
+		if got := maxAge(header); got != expected {
+			t.Errorf("%q: expected %v but got %v", header, expected, got)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+// ErrTokenRevoked is returned when the token is revoked.
+var ErrTokenRevoked = errors.New("jwtx: token revoked")
+
+// RevocationStore stores the revoked tokens.
+type RevocationStore interface {
+	// RevokeID revokes the token with the given jti. The entry is only needed
+	// until the token expires.
+	RevokeID(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeSubject revokes the tokens of the subject issued before the given
+	// time. The entry is only needed until the given expiration time.
+	RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error
+	// IsRevoked reports whether the token with the given jti, subject and
+	// issue time is revoked.
+	IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error)
+}
+
+// RevocationLister is implemented by the revocation stores that can list
+// their revocations, see BloomRevocationStore.Rebuild.
+type RevocationLister interface {
+	// Revocations returns the revoked jtis and subjects that are not expired.
+	Revocations(ctx context.Context) (jtis, subjects []string, err error)
+}
+
+// RevocationOption is an option to configure the RevocationTokenizer.
+type RevocationOption func(*RevocationTokenizer)
+
+// WithMaxTTL configures the maximum lifetime of the tokens, which bounds how
+// long a subject revocation is kept. The default is DefaultRefreshTTL.
+func WithMaxTTL(ttl time.Duration) RevocationOption {
+	return func(t *RevocationTokenizer) {
+		t.maxTTL = ttl
+	}
+}
+
+// WithRevocationClock configures the clock used to revoke tokens by subject.
+func WithRevocationClock(c clock.Clock) RevocationOption {
+	return func(t *RevocationTokenizer) {
+		t.clock = c
+	}
+}
+
+// RevocationTokenizer is a tokenizer decorator that rejects the revoked
+// tokens with ErrTokenRevoked.
+//
+// A token is revoked by its jti, or with every other token of its subject
+// issued before a given time. A token without iat is considered revoked when
+// its subject is revoked.
+type RevocationTokenizer struct {
+	Tokenizer
+	store  RevocationStore
+	clock  clock.Clock
+	maxTTL time.Duration
+}
+
+// NewRevocationTokenizer creates a new RevocationTokenizer that checks the
+// tokens verified by the given tokenizer against the store.
+func NewRevocationTokenizer(t Tokenizer, store RevocationStore, opts ...RevocationOption) *RevocationTokenizer {
+	r := &RevocationTokenizer{
+		Tokenizer: t,
+		store:     store,
+		clock:     clock.UTC,
+		maxTTL:    DefaultRefreshTTL,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (t *RevocationTokenizer) Detokenize(token string, claims jwt.Claims) error {
+	if err := t.Tokenizer.Detokenize(token, claims); err != nil {
+		return err
+	}
+
+	rc, err := registeredOf(claims)
+	if err != nil {
+		return err
+	}
+
+	var issuedAt time.Time
+	if rc.IssuedAt != nil {
+		issuedAt = rc.IssuedAt.Time
+	}
+
+	revoked, err := t.store.IsRevoked(context.Background(), rc.ID, rc.Subject, issuedAt)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// Revoke revokes the verified token by its jti. Tokens without jti or exp
+// can not be revoked individually and return ErrMissingClaim.
+func (t *RevocationTokenizer) Revoke(ctx context.Context, token string) error {
+	claims := jwt.MapClaims{}
+	if err := t.Tokenizer.Detokenize(token, claims); err != nil {
+		return err
+	}
+
+	rc, err := registeredOf(claims)
+	if err != nil {
+		return err
+	}
+
+	if rc.ID == "" || rc.ExpiresAt == nil {
+		return ErrMissingClaim
+	}
+
+	return t.store.RevokeID(ctx, rc.ID, rc.ExpiresAt.Time)
+}
+
+// RevokeSubject revokes every token of the subject issued until now.
+func (t *RevocationTokenizer) RevokeSubject(ctx context.Context, subject string) error {
+	now := t.clock.Now()
+	return t.store.RevokeSubject(ctx, subject, now, now.Add(t.maxTTL))
+}
+
+// registeredOf returns the registered claims of the decoded claims, whatever
+// their type and the token format.
+func registeredOf(claims jwt.Claims) (registeredClaims, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return registeredClaims{}, err
+	}
+
+	rc, _, err := parseClaims(b)
+	return rc, err
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore. The entries are
+// removed once the revoked tokens are expired.
+type MemoryRevocationStore struct {
+	clock clock.Clock
+
+	mu       sync.RWMutex
+	ids      map[string]time.Time
+	subjects map[string]subjectRevocation
+}
+
+// subjectRevocation revokes the tokens issued before issuedBefore.
+type subjectRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// NewMemoryRevocationStore creates a new MemoryRevocationStore that expires
+// the entries with the given clock.
+func NewMemoryRevocationStore(c clock.Clock) *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		clock:    c,
+		ids:      make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeID(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(s.clock.Now())
+	s.ids[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeSubject(_ context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(s.clock.Now())
+	// a revocation never shortens the previous one.
+	if current, ok := s.subjects[subject]; ok {
+		if current.issuedBefore.After(issuedBefore) {
+			issuedBefore = current.issuedBefore
+		}
+
+		if current.expiresAt.After(expiresAt) {
+			expiresAt = current.expiresAt
+		}
+	}
+
+	s.subjects[subject] = subjectRevocation{issuedBefore: issuedBefore, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	if expiresAt, ok := s.ids[jti]; ok && jti != "" && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	r, ok := s.subjects[subject]
+	if !ok || subject == "" || !now.Before(r.expiresAt) {
+		return false, nil
+	}
+
+	return issuedAt.IsZero() || issuedAt.Before(r.issuedBefore), nil
+}
+
+// Revocations returns the revoked jtis and subjects that are not expired.
+func (s *MemoryRevocationStore) Revocations(_ context.Context) ([]string, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	jtis := make([]string, 0, len(s.ids))
+	for jti, expiresAt := range s.ids {
+		if now.Before(expiresAt) {
+			jtis = append(jtis, jti)
+		}
+	}
+
+	subjects := make([]string, 0, len(s.subjects))
+	for subject, r := range s.subjects {
+		if now.Before(r.expiresAt) {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return jtis, subjects, nil
+}
+
+// Len returns the number of the stored entries, including the expired
+// entries that are not removed yet.
+func (s *MemoryRevocationStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids) + len(s.subjects)
+}
+
+func (s *MemoryRevocationStore) prune(now time.Time) {
+	for jti, expiresAt := range s.ids {
+		if !now.Before(expiresAt) {
+			delete(s.ids, jti)
+		}
+	}
+
+	for subject, r := range s.subjects {
+		if !now.Before(r.expiresAt) {
+			delete(s.subjects, subject)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRevocationTokenizer(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	store := NewMemoryRevocationStore(c)
+	inner := NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")}, WithClock(c))
+	tokenizer := NewRevocationTokenizer(inner, store, WithRevocationClock(c), WithMaxTTL(time.Hour))
+
+	issue := func(subject string) string {
+		t.Helper()
+		claims := Claims{}
+		claims.Subject = subject
+		token, _, err := Issue(tokenizer, claims, WithIssueClock(c), WithTTL(time.Minute))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		return token
+	}
+
+	a, b := issue("alice"), issue("alice")
+	if err := tokenizer.Revoke(ctx, a); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(a, &Claims{}); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected %v but got %v", ErrTokenRevoked, err)
+	}
+
+	if err := tokenizer.Detokenize(b, &Claims{}); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	c.Add(time.Second)
+	if err := tokenizer.RevokeSubject(ctx, "alice"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	bob := issue("bob")
+	c.Add(time.Second)
+	after := issue("alice")
+
+	if err := tokenizer.Detokenize(b, &Claims{}); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected %v but got %v", ErrTokenRevoked, err)
+	}
+
+	for _, token := range []string{bob, after} {
+		if err := tokenizer.Detokenize(token, &Claims{}); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	}
+
+	if err := tokenizer.Detokenize("not.a.token", &Claims{}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+
+	noID, err := inner.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Revoke(ctx, noID); !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("expected %v but got %v", ErrMissingClaim, err)
+	}
+}
+
+func TestMemoryRevocationStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClock()
+	store := NewMemoryRevocationStore(c)
+
+	_ = store.RevokeID(ctx, "a", c.Now().Add(time.Minute))
+	_ = store.RevokeSubject(ctx, "alice", c.Now(), c.Now().Add(time.Hour))
+	_ = store.RevokeSubject(ctx, "alice", c.Now().Add(-time.Hour), c.Now().Add(time.Minute))
+
+	if revoked, _ := store.IsRevoked(ctx, "a", "", time.Time{}); !revoked {
+		t.Errorf("expected the jti to be revoked")
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, "", "alice", c.Now().Add(-time.Minute)); !revoked {
+		t.Errorf("expected the later subject revocation to be kept")
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, "", "alice", time.Time{}); !revoked {
+		t.Errorf("expected the token without iat to be revoked")
+	}
+
+	c.Add(time.Minute)
+	if revoked, _ := store.IsRevoked(ctx, "a", "", time.Time{}); revoked {
+		t.Errorf("expected the expired jti not to be revoked")
+	}
+
+	_ = store.RevokeID(ctx, "b", c.Now().Add(time.Minute))
+	if n := store.Len(); n != 2 {
+		t.Errorf("expected 2 entries but got %d", n)
+	}
+
+	c.Add(time.Hour)
+	_ = store.RevokeID(ctx, "c", c.Now().Add(time.Minute))
+	if n := store.Len(); n != 1 {
+		t.Errorf("expected 1 entry but got %d", n)
+	}
+}
@@ -0,0 +1,100 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ECDSATokenizer is a tokenizer that uses ECDSA as the signing algorithm.
+// The tokenizer uses a round-robin algorithm to select the key to use.
+type ECDSATokenizer struct {
+	*keyring[*ecdsa.PrivateKey]
+}
+
+// NewES256Tokenizer creates a new ECDSATokenizer that signs with ES256.
+// The keys must use the P-256 curve.
+func NewES256Tokenizer(keys map[string]*ecdsa.PrivateKey, opts ...Option) *ECDSATokenizer {
+	return newECDSATokenizer(jwt.SigningMethodES256, keys, opts)
+}
+
+// NewES384Tokenizer creates a new ECDSATokenizer that signs with ES384.
+// The keys must use the P-384 curve.
+func NewES384Tokenizer(keys map[string]*ecdsa.PrivateKey, opts ...Option) *ECDSATokenizer {
+	return newECDSATokenizer(jwt.SigningMethodES384, keys, opts)
+}
+
+// NewES512Tokenizer creates a new ECDSATokenizer that signs with ES512.
+// The keys must use the P-521 curve.
+func NewES512Tokenizer(keys map[string]*ecdsa.PrivateKey, opts ...Option) *ECDSATokenizer {
+	return newECDSATokenizer(jwt.SigningMethodES512, keys, opts)
+}
+
+func newECDSATokenizer(method jwt.SigningMethod, keys map[string]*ecdsa.PrivateKey, opts []Option) *ECDSATokenizer {
+	public := func(key *ecdsa.PrivateKey) interface{} { return &key.PublicKey }
+	return &ECDSATokenizer{newKeyring(method, keys, public, opts)}
+}
+
+// PublicKeys returns the public keys addressed by kid.
+func (t *ECDSATokenizer) PublicKeys() map[string]crypto.PublicKey { return t.publicKeys() }
+
+// EdDSATokenizer is a tokenizer that uses EdDSA with Ed25519 keys as the
+// signing algorithm. The tokenizer uses a round-robin algorithm to select the
+// key to use.
+type EdDSATokenizer struct {
+	*keyring[ed25519.PrivateKey]
+}
+
+// NewEdDSATokenizer creates a new EdDSATokenizer.
+func NewEdDSATokenizer(keys map[string]ed25519.PrivateKey, opts ...Option) *EdDSATokenizer {
+	public := func(key ed25519.PrivateKey) interface{} { return key.Public() }
+	return &EdDSATokenizer{newKeyring(jwt.SigningMethodEdDSA, keys, public, opts)}
+}
+
+// PublicKeys returns the public keys addressed by kid.
+func (t *EdDSATokenizer) PublicKeys() map[string]crypto.PublicKey { return t.publicKeys() }
+
+// RSAPSSTokenizer is a tokenizer that uses RSA-PSS as the signing algorithm.
+// The tokenizer uses a round-robin algorithm to select the key to use.
+type RSAPSSTokenizer struct {
+	*keyring[*rsa.PrivateKey]
+}
+
+// NewPS256Tokenizer creates a new RSAPSSTokenizer that signs with PS256.
+func NewPS256Tokenizer(keys map[string]*rsa.PrivateKey, opts ...Option) *RSAPSSTokenizer {
+	public := func(key *rsa.PrivateKey) interface{} { return &key.PublicKey }
+	return &RSAPSSTokenizer{newKeyring(jwt.SigningMethodPS256, keys, public, opts)}
+}
+
+// PublicKeys returns the public keys addressed by kid.
+func (t *RSAPSSTokenizer) PublicKeys() map[string]crypto.PublicKey { return t.publicKeys() }
+
+// HMACTokenizer is a tokenizer that uses HMAC as the signing algorithm.
+// Unlike SHA256Tokenizer it supports multiple secrets addressed by kid.
+// The tokenizer uses a round-robin algorithm to select the secret to use.
+type HMACTokenizer struct {
+	*keyring[[]byte]
+}
+
+// NewHS256Tokenizer creates a new HMACTokenizer that signs with HS256.
+func NewHS256Tokenizer(secrets map[string][]byte, opts ...Option) *HMACTokenizer {
+	return newHMACTokenizer(jwt.SigningMethodHS256, secrets, opts)
+}
+
+// NewHS384Tokenizer creates a new HMACTokenizer that signs with HS384.
+func NewHS384Tokenizer(secrets map[string][]byte, opts ...Option) *HMACTokenizer {
+	return newHMACTokenizer(jwt.SigningMethodHS384, secrets, opts)
+}
+
+// NewHS512Tokenizer creates a new HMACTokenizer that signs with HS512.
+func NewHS512Tokenizer(secrets map[string][]byte, opts ...Option) *HMACTokenizer {
+	return newHMACTokenizer(jwt.SigningMethodHS512, secrets, opts)
+}
+
+func newHMACTokenizer(method jwt.SigningMethod, secrets map[string][]byte, opts []Option) *HMACTokenizer {
+	public := func(secret []byte) interface{} { return secret }
+	return &HMACTokenizer{newKeyring(method, secrets, public, opts)}
+}
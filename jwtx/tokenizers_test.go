@@ -0,0 +1,144 @@
+package jwtx
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func mustECDSAKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return key
+}
+
+func mustEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return key
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return key
+}
+
+func TestKeyedTokenizers(t *testing.T) {
+	rsaKey := mustRSAKey(t)
+
+	tests := []struct {
+		name      string
+		alg       string
+		tokenizer interface {
+			Tokenizer
+			Algorithm() string
+		}
+	}{
+		{
+			name: "ES256",
+			alg:  "ES256",
+			tokenizer: NewES256Tokenizer(map[string]*ecdsa.PrivateKey{
+				"key-1": mustECDSAKey(t, elliptic.P256()),
+				"key-2": mustECDSAKey(t, elliptic.P256()),
+			}),
+		},
+		{
+			name:      "ES384",
+			alg:       "ES384",
+			tokenizer: NewES384Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P384())}),
+		},
+		{
+			name:      "ES512",
+			alg:       "ES512",
+			tokenizer: NewES512Tokenizer(map[string]*ecdsa.PrivateKey{"key-1": mustECDSAKey(t, elliptic.P521())}),
+		},
+		{
+			name: "EdDSA",
+			alg:  "EdDSA",
+			tokenizer: NewEdDSATokenizer(map[string]ed25519.PrivateKey{
+				"key-1": mustEd25519Key(t),
+				"key-2": mustEd25519Key(t),
+			}),
+		},
+		{
+			name:      "PS256",
+			alg:       "PS256",
+			tokenizer: NewPS256Tokenizer(map[string]*rsa.PrivateKey{"key-1": rsaKey}),
+		},
+		{
+			name:      "HS256",
+			alg:       "HS256",
+			tokenizer: NewHS256Tokenizer(map[string][]byte{"key-1": []byte("secret-1"), "key-2": []byte("secret-2")}),
+		},
+		{
+			name:      "HS384",
+			alg:       "HS384",
+			tokenizer: NewHS384Tokenizer(map[string][]byte{"key-1": []byte("secret-1")}),
+		},
+		{
+			name:      "HS512",
+			alg:       "HS512",
+			tokenizer: NewHS512Tokenizer(map[string][]byte{"key-1": []byte("secret-1")}),
+		},
+		{
+			name:      "RS256",
+			alg:       "RS256",
+			tokenizer: NewRSA256RoundRobinTokenizer(map[string]*rsa.PrivateKey{"key-1": rsaKey}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tokenizer.Algorithm(); got != tt.alg {
+				t.Errorf("expected algorithm %q but got %q", tt.alg, got)
+			}
+
+			// sign twice, so every key of the round-robin is used.
+			testTokenizeAndDetokenize(t, tt.tokenizer)
+			testTokenizeAndDetokenize(t, tt.tokenizer)
+			testInvalidToken(t, tt.tokenizer)
+		})
+	}
+}
+
+func TestKeyedTokenizers_WrongAlg(t *testing.T) {
+	rsaKey := mustRSAKey(t)
+	rs256 := NewRSA256RoundRobinTokenizer(map[string]*rsa.PrivateKey{"key-1": rsaKey})
+	ps256 := NewPS256Tokenizer(map[string]*rsa.PrivateKey{"key-1": rsaKey})
+
+	token, err := rs256.Tokenize(jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var claims jwt.RegisteredClaims
+	if err := ps256.Detokenize(token, &claims); !errors.Is(err, ErrWrongAlg) {
+		t.Errorf("expected %v but got %v", ErrWrongAlg, err)
+	}
+}
+
+func TestKeyedTokenizers_NoSigningKey(t *testing.T) {
+	tokenizer := NewEdDSATokenizer(nil)
+	if _, err := tokenizer.Tokenize(jwt.RegisteredClaims{}); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected %v but got %v", ErrNoSigningKey, err)
+	}
+}
@@ -1,13 +1,10 @@
 package jwtx
 
 import (
+	"crypto"
 	"crypto/rsa"
 	"github.com/golang-jwt/jwt/v4"
-	"io"
 	"io/fs"
-	"path/filepath"
-	"strings"
-	"sync/atomic"
 )
 
 // Tokenizer knows how to create and validate jwtx.
@@ -19,7 +16,8 @@ type Tokenizer interface {
 }
 
 // SHA256Tokenizer is a tokenizer that uses SHA256 as the hashing algorithm.
-// This Tokenizer using its value as the secret key.
+// This Tokenizer using its value as the secret key. It only accepts HS256
+// tokens with the JWT typ header.
 // For example,
 //
 //	var myTokenizer = SHA256Tokenizer("my-secret-key") // using `my-secret-key` as the secret key.
@@ -39,132 +37,73 @@ func (t SHA256Tokenizer) Tokenize(claims jwt.Claims) (string, error) {
 	return token.SignedString([]byte(t))
 }
 
+// sha256Parser is the parser used by SHA256Tokenizer.
+var sha256Parser = newParser([]string{jwt.SigningMethodHS256.Alg()})
+
 func (t SHA256Tokenizer) Detokenize(token string, claims jwt.Claims) error {
-	tkn, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+	return t.detokenize(sha256Parser, token, claims)
+}
+
+func (t SHA256Tokenizer) detokenize(p *parser, token string, claims jwt.Claims) error {
+	return p.parse(token, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(t), nil
 	})
+}
 
-	if err != nil {
-		return err
+// NewSHA256Tokenizer creates a tokenizer that creates the same tokens as
+// SHA256Tokenizer, but validates them with the given options, for example
+// WithIssuer and WithAudience.
+func NewSHA256Tokenizer(secret string, opts ...Option) Tokenizer {
+	return &configuredSHA256Tokenizer{
+		SHA256Tokenizer: SHA256Tokenizer(secret),
+		parser:          newParser([]string{jwt.SigningMethodHS256.Alg()}, opts...),
 	}
+}
+
+// configuredSHA256Tokenizer is a SHA256Tokenizer with its own parser.
+type configuredSHA256Tokenizer struct {
+	SHA256Tokenizer
+	parser *parser
+}
 
-	return tkn.Claims.Valid()
+func (t *configuredSHA256Tokenizer) Detokenize(token string, claims jwt.Claims) error {
+	return t.detokenize(t.parser, token, claims)
 }
 
 // RSA256RoundRobinTokenizer is a tokenizer that uses RSA256 as the hashing algorithm.
 // The tokenizer uses a round-robin algorithm to select the key to use.
 type RSA256RoundRobinTokenizer struct {
-	keys     map[string]*rsa.PrivateKey
-	indexes  []string
-	position int32
+	*keyring[*rsa.PrivateKey]
 }
 
 // NewRSA256RoundRobinTokenizer creates a new RSA256RoundRobinTokenizer.
-func NewRSA256RoundRobinTokenizer(keys map[string]*rsa.PrivateKey) *RSA256RoundRobinTokenizer {
-
-	indexes := make([]string, 0, len(keys))
-	for kid := range keys {
-		indexes = append(indexes, kid)
-	}
-
-	return &RSA256RoundRobinTokenizer{
-		keys:     keys,
-		indexes:  indexes,
-		position: 0,
-	}
+// By default, it only accepts RS256 tokens with the JWT typ header, use
+// WithAlgorithms to accept RS384 and RS512 tokens as well.
+func NewRSA256RoundRobinTokenizer(keys map[string]*rsa.PrivateKey, opts ...Option) *RSA256RoundRobinTokenizer {
+	public := func(key *rsa.PrivateKey) interface{} { return key.Public() }
+	return &RSA256RoundRobinTokenizer{newKeyring(jwt.SigningMethodRS256, keys, public, opts)}
 }
 
-func (t *RSA256RoundRobinTokenizer) Tokenize(claims jwt.Claims) (string, error) {
-
-	kid := t.indexes[t.nextIndex()]
-	key := t.keys[kid]
-
-	method := jwt.SigningMethodRS256
-	token := &jwt.Token{
-		Header: map[string]interface{}{
-			"typ": "JWT",
-			"kid": kid,
-			"alg": method.Alg(),
-		},
-		Claims: claims,
-		Method: method,
-	}
-
-	return token.SignedString(key)
-}
-
-func (t *RSA256RoundRobinTokenizer) Detokenize(token string, claims jwt.Claims) error {
-	tkn, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		kid, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.ErrInvalidKey
-		}
+// PublicKeys returns the public keys addressed by kid.
+func (t *RSA256RoundRobinTokenizer) PublicKeys() map[string]crypto.PublicKey { return t.publicKeys() }
 
-		key, ok := t.keys[kid]
-		if !ok {
-			return nil, jwt.ErrInvalidKey
-		}
-
-		return key.Public(), nil
-	})
-
-	if err != nil {
-		return err
-	}
-
-	return tkn.Claims.Valid()
-}
-
-func (t *RSA256RoundRobinTokenizer) nextIndex() int {
-	return int(atomic.AddInt32(&t.position, 1)) % len(t.indexes)
-}
-
-// LoadRSAPEMKeysFromDir loads RSA PEM keys from the given directory.
-func LoadRSAPEMKeysFromDir(dir fs.FS) (map[string]*rsa.PrivateKey, error) {
+// LoadRSAPEMKeysFromDir loads RSA PEM keys from the given directory. The kid
+// is the file name without extension, unless it is overridden by the
+// manifest of WithKIDManifest. The keys can be PKCS#1 or PKCS#8, and
+// encrypted with the passphrase of WithPassphrase. The public-key-only files
+// and the ECDSA and Ed25519 keys are skipped, see LoadRSAPublicPEMKeysFromDir
+// and LoadPEMKeysFromDir.
+//
+// The files that can not be loaded are reported together in a *LoadError,
+// along with the keys of the other files.
+func LoadRSAPEMKeysFromDir(dir fs.FS, opts ...LoadOption) (map[string]*rsa.PrivateKey, error) {
+	l := newKeyLoader(opts)
 	keys := make(map[string]*rsa.PrivateKey)
-	err := fs.WalkDir(dir, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// skip directories
-		if d.IsDir() {
-			return nil
-		}
-
-		// skip files that don't have .pem extension
-		if !strings.HasSuffix(path, ".pem") {
-			return nil
-		}
-
-		// open the file
-		f, err := dir.Open(path)
-		if err != nil {
-			return err
-		}
-		defer func(f fs.File) {
-			_ = f.Close()
-		}(f)
-
-		// read the file
-		b, err := io.ReadAll(f)
-		if err != nil {
-			return err
+	for _, f := range l.load(dir) {
+		if key, ok := f.private.(*rsa.PrivateKey); ok {
+			addKey(l, keys, f, key)
 		}
+	}
 
-		// parse the file
-		key, err := jwt.ParseRSAPrivateKeyFromPEM(b)
-		if err != nil {
-			return err
-		}
-
-		// get file name without extension
-		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
-
-		// add the key to the map
-		keys[kid] = key
-		return nil
-	})
-
-	return keys, err
+	return keys, l.err()
 }
@@ -21,6 +21,27 @@ func TestSHA256Tokenizer(t *testing.T) {
 		tokenizer := SHA256Tokenizer("my-key")
 		testInvalidToken(t, tokenizer)
 	})
+
+	t.Run("other algorithm", func(t *testing.T) {
+		token := signToken(t, jwt.SigningMethodHS384, nil, jwt.RegisteredClaims{}, []byte("my-key"))
+		if err := SHA256Tokenizer("my-key").Detokenize(token, &jwt.RegisteredClaims{}); !errors.Is(err, ErrWrongAlg) {
+			t.Errorf("expected error %v but got %v", ErrWrongAlg, err)
+		}
+	})
+}
+
+func TestNewSHA256Tokenizer(t *testing.T) {
+	tokenizer := NewSHA256Tokenizer("my-key", WithIssuer("test-issuer"))
+	testTokenizeAndDetokenize(t, tokenizer)
+
+	token, err := SHA256Tokenizer("my-key").Tokenize(jwt.RegisteredClaims{Issuer: "other-issuer"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := tokenizer.Detokenize(token, &jwt.RegisteredClaims{}); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("expected error %v but got %v", ErrWrongIssuer, err)
+	}
 }
 
 func TestNewRSA256RoundRobinTokenizer(t *testing.T) {
@@ -60,6 +81,10 @@ func testInvalidKey(t *testing.T, tokenizer Tokenizer, token string) {
 	if !errors.Is(err, jwt.ErrInvalidKey) {
 		t.Errorf("expected invalid key error but got %v", err)
 	}
+
+	if !errors.Is(err, ErrUnknownKID) {
+		t.Errorf("expected unknown key id error but got %v", err)
+	}
 }
 
 func testInvalidToken(t *testing.T, tokenizer Tokenizer) {
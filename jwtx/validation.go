@@ -0,0 +1,208 @@
+package jwtx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+var (
+	// ErrWrongIssuer is returned when the token iss claim is not expected.
+	ErrWrongIssuer = errors.New("jwtx: wrong issuer")
+
+	// ErrWrongAudience is returned when the token aud claim does not contain
+	// the expected audience.
+	ErrWrongAudience = errors.New("jwtx: wrong audience")
+
+	// ErrMissingClaim is returned when a required claim is missing.
+	ErrMissingClaim = errors.New("jwtx: missing claim")
+
+	// ErrTokenTooOld is returned when the token was issued longer ago than the
+	// maximum age.
+	ErrTokenTooOld = errors.New("jwtx: token too old")
+)
+
+// WithClock configures the clock used to validate the time based claims.
+// The default is clock.UTC.
+func WithClock(c clock.Clock) Option {
+	return func(p *parser) {
+		p.clock = c
+	}
+}
+
+// WithLeeway configures the clock skew tolerated when validating the time
+// based claims.
+func WithLeeway(leeway time.Duration) Option {
+	return func(p *parser) {
+		p.leeway = leeway
+	}
+}
+
+// WithIssuer configures the accepted issuers, the iss claim must be one of them.
+func WithIssuer(issuers ...string) Option {
+	return func(p *parser) {
+		p.issuers = issuers
+	}
+}
+
+// WithAudience configures the audience that the aud claim must contain.
+func WithAudience(audience string) Option {
+	return func(p *parser) {
+		p.audience = audience
+	}
+}
+
+// WithRequiredClaims configures the claims that must be present and not null.
+func WithRequiredClaims(names ...string) Option {
+	return func(p *parser) {
+		p.required = names
+	}
+}
+
+// WithMaxAge configures the maximum age of the tokens, measured from their
+// iat claim, which becomes required.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(p *parser) {
+		p.maxAge = maxAge
+	}
+}
+
+// registeredClaims are the registered claims checked by the parser.
+type registeredClaims struct {
+	Issuer    string           `json:"iss"`
+	Subject   string           `json:"sub"`
+	Audience  jwt.ClaimStrings `json:"aud"`
+	ExpiresAt *jwt.NumericDate `json:"exp"`
+	NotBefore *jwt.NumericDate `json:"nbf"`
+	IssuedAt  *jwt.NumericDate `json:"iat"`
+	ID        string           `json:"jti"`
+}
+
+// decodeClaims decodes the registered claims of the signed token payload,
+// see parseClaims.
+func decodeClaims(token string) (registeredClaims, map[string]json.RawMessage, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return registeredClaims{}, nil, &tokenError{kind: ErrMalformed}
+	}
+
+	payload, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return registeredClaims{}, nil, &tokenError{kind: ErrMalformed, cause: err}
+	}
+
+	return parseClaims(payload)
+}
+
+// parseClaims parses the registered claims of the JSON payload, along with
+// the raw value of every claim.
+func parseClaims(payload []byte) (registeredClaims, map[string]json.RawMessage, error) {
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &present); err != nil {
+		return registeredClaims{}, nil, &tokenError{kind: ErrMalformed, cause: err}
+	}
+
+	var claims registeredClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return registeredClaims{}, nil, &tokenError{kind: ErrMalformed, cause: err}
+	}
+
+	return claims, present, nil
+}
+
+// validateClaims validates the registered claims of the verified token
+// payload. It replaces jwt.Claims.Valid, so that the time based claims are
+// validated with the configured clock.
+func (p *parser) validateClaims(token *jwt.Token) error {
+	claims, present, err := decodeClaims(token.Raw)
+	if err != nil {
+		return err
+	}
+
+	return p.validate(claims, present)
+}
+
+// timeErrors are the errors of the time based checks of the jwt claims types.
+const timeErrors = jwt.ValidationErrorExpired | jwt.ValidationErrorNotValidYet | jwt.ValidationErrorIssuedAt
+
+// validClaims calls the Valid method of the claims, so the custom checks of
+// the claims types still run. The time based errors of the jwt claims types
+// are ignored, the time based claims are validated by the parser with the
+// configured clock and leeway instead. A Valid method that wraps the embedded
+// jwt checks should run its own checks first, since the embedded checks use
+// the wall clock.
+func validClaims(claims jwt.Claims) error {
+	err := claims.Valid()
+	if err == nil {
+		return nil
+	}
+
+	var ve *jwt.ValidationError
+	if errors.As(err, &ve) && ve.Errors != 0 && ve.Errors&^timeErrors == 0 {
+		return nil
+	}
+
+	var te *tokenError
+	if errors.As(err, &te) {
+		return err
+	}
+
+	return &tokenError{kind: ErrInvalidClaims, cause: err}
+}
+
+// validate validates the registered claims.
+func (p *parser) validate(claims registeredClaims, present map[string]json.RawMessage) error {
+	for _, name := range p.required {
+		if v, ok := present[name]; !ok || string(v) == "null" {
+			return &tokenError{kind: ErrMissingClaim, cause: fmt.Errorf("claim %q is required", name)}
+		}
+	}
+
+	now := p.clock.Now()
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(p.leeway)) {
+		return &tokenError{kind: ErrExpired, cause: jwt.ErrTokenExpired}
+	}
+
+	if claims.NotBefore != nil && now.Add(p.leeway).Before(claims.NotBefore.Time) {
+		return &tokenError{kind: ErrNotValidYet, cause: jwt.ErrTokenNotValidYet}
+	}
+
+	if claims.IssuedAt != nil && now.Add(p.leeway).Before(claims.IssuedAt.Time) {
+		return &tokenError{kind: ErrInvalidClaims, cause: jwt.ErrTokenUsedBeforeIssued}
+	}
+
+	if p.maxAge > 0 {
+		if claims.IssuedAt == nil {
+			return &tokenError{kind: ErrMissingClaim, cause: errors.New(`claim "iat" is required`)}
+		}
+
+		if now.Sub(claims.IssuedAt.Time) > p.maxAge+p.leeway {
+			return &tokenError{kind: ErrTokenTooOld, cause: fmt.Errorf("issued at %s", claims.IssuedAt.Format(time.RFC3339))}
+		}
+	}
+
+	if len(p.issuers) > 0 && !contains(p.issuers, claims.Issuer) {
+		return &tokenError{kind: ErrWrongIssuer, cause: jwt.ErrTokenInvalidIssuer}
+	}
+
+	if p.audience != "" && !contains(claims.Audience, p.audience) {
+		return &tokenError{kind: ErrWrongAudience, cause: jwt.ErrTokenInvalidAudience}
+	}
+
+	return nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,193 @@
+package jwtx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/josestg/gokit/clock"
+)
+
+func TestDetokenize_ClaimsValidation(t *testing.T) {
+	now := clock.StaticTime
+	secrets := map[string][]byte{"key-1": []byte("my-key")}
+	at := func(d time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(now.Add(d)) }
+
+	strict := []Option{
+		WithClock(clock.Static),
+		WithIssuer("https://a.example.com", "https://b.example.com"),
+		WithAudience("api"),
+		WithRequiredClaims("sub"),
+		WithMaxAge(time.Hour),
+		WithLeeway(time.Minute),
+	}
+
+	valid := jwt.RegisteredClaims{
+		Issuer:    "https://b.example.com",
+		Subject:   "user",
+		Audience:  jwt.ClaimStrings{"web", "api"},
+		ExpiresAt: at(time.Hour),
+		IssuedAt:  at(-30 * time.Minute),
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		claims   func(c *jwt.RegisteredClaims)
+		expected error
+	}{
+		{
+			name:     "valid",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) {},
+			expected: nil,
+		},
+		{
+			name:     "expired in the past of the wall clock is valid with the static clock",
+			opts:     []Option{WithClock(clock.Static)},
+			claims:   func(c *jwt.RegisteredClaims) { c.ExpiresAt = at(time.Second) },
+			expected: nil,
+		},
+		{
+			name:     "expired",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.ExpiresAt = at(-time.Minute) },
+			expected: ErrExpired,
+		},
+		{
+			name:     "expired within leeway",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.ExpiresAt = at(-30 * time.Second) },
+			expected: nil,
+		},
+		{
+			name:     "not valid yet",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.NotBefore = at(2 * time.Minute) },
+			expected: ErrNotValidYet,
+		},
+		{
+			name:     "not valid yet within leeway",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.NotBefore = at(30 * time.Second) },
+			expected: nil,
+		},
+		{
+			name:     "issued in the future",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.IssuedAt = at(2 * time.Minute) },
+			expected: ErrInvalidClaims,
+		},
+		{
+			name:     "too old",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.IssuedAt = at(-2 * time.Hour) },
+			expected: ErrTokenTooOld,
+		},
+		{
+			name:     "max age requires iat",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.IssuedAt = nil },
+			expected: ErrMissingClaim,
+		},
+		{
+			name:     "wrong issuer",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.Issuer = "https://c.example.com" },
+			expected: ErrWrongIssuer,
+		},
+		{
+			name:     "wrong audience",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"web"} },
+			expected: ErrWrongAudience,
+		},
+		{
+			name:     "missing required claim",
+			opts:     strict,
+			claims:   func(c *jwt.RegisteredClaims) { c.Subject = "" },
+			expected: ErrMissingClaim,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := NewHS256Tokenizer(secrets, tt.opts...)
+
+			claims := valid
+			tt.claims(&claims)
+			token, err := tokenizer.Tokenize(claims)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			var decoded jwt.RegisteredClaims
+			if err := tokenizer.Detokenize(token, &decoded); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestDetokenize_CompatibleErrors(t *testing.T) {
+	tokenizer := SHA256Tokenizer("my-key")
+	claims := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}
+	token, err := tokenizer.Tokenize(claims)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	err = tokenizer.Detokenize(token, &jwt.RegisteredClaims{})
+	if !errors.Is(err, ErrExpired) || !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("expected %v and %v but got %v", ErrExpired, jwt.ErrTokenExpired, err)
+	}
+}
+
+// roleClaims are claims with a custom check in their Valid method.
+type roleClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+func (c roleClaims) Valid() error {
+	if c.Role != "admin" && c.Role != "member" {
+		return errors.New("unknown role")
+	}
+
+	return c.RegisteredClaims.Valid()
+}
+
+func TestDetokenize_ClaimsValid(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+		expiresAt time.Time
+	}{
+		{name: "sha256", tokenizer: SHA256Tokenizer("my-key"), expiresAt: time.Now().Add(time.Hour)},
+		{name: "dir", tokenizer: NewDirTokenizer(map[string][]byte{"key-1": make([]byte, 32)}), expiresAt: time.Now().Add(time.Hour)},
+		{
+			// the wall clock errors of the embedded Valid are left to the parser.
+			name:      "static clock",
+			tokenizer: NewHS256Tokenizer(map[string][]byte{"key-1": []byte("my-key")}, WithClock(clock.Static)),
+			expiresAt: clock.StaticTime.Add(time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := roleClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(tt.expiresAt)}}
+			for role, expected := range map[string]error{"admin": nil, "guest": ErrInvalidClaims} {
+				claims.Role = role
+				token, err := tt.tokenizer.Tokenize(claims)
+				if err != nil {
+					t.Fatalf("expected no error but got %v", err)
+				}
+
+				if err := tt.tokenizer.Detokenize(token, &roleClaims{}); !errors.Is(err, expected) {
+					t.Errorf("expected %v for role %q but got %v", expected, role, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package paseto
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// localHeader is the header of the v4.local tokens.
+const localHeader = "v4.local."
+
+// LocalKeySize is the size of the v4.local keys.
+const LocalKeySize = 32
+
+const (
+	nonceSize = 32
+	tagSize   = 32
+)
+
+// LocalTokenizer creates and verifies v4.local tokens, which are encrypted
+// with XChaCha20 and authenticated with BLAKE2b-MAC. It encrypts with its
+// keys in round-robin order, and decrypts with the key addressed by the
+// footer kid, which is authenticated but not encrypted.
+type LocalTokenizer struct {
+	keys *keyset[[]byte]
+	cfg  config
+}
+
+// NewLocalTokenizer creates a new LocalTokenizer, the keys must have
+// LocalKeySize bytes.
+func NewLocalTokenizer(keys map[string][]byte, opts ...Option) *LocalTokenizer {
+	return &LocalTokenizer{
+		keys: newKeyset(keys),
+		cfg:  newConfig(opts),
+	}
+}
+
+// Tokenize creates an encrypted v4.local token from the claims, bound to the
+// implicit assertion of WithImplicitAssertion.
+func (t *LocalTokenizer) Tokenize(claims interface{}) (string, error) {
+	return t.TokenizeWithAssertion(claims, t.cfg.implicit)
+}
+
+// Detokenize decrypts the token, validates its registered claims and decodes
+// the claims. The tokens without an exp claim are accepted unless
+// WithRequiredExpiration is given.
+func (t *LocalTokenizer) Detokenize(token string, claims interface{}) error {
+	return t.DetokenizeWithAssertion(token, claims, t.cfg.implicit)
+}
+
+// TokenizeWithAssertion creates a token bound to the implicit assertion.
+func (t *LocalTokenizer) TokenizeWithAssertion(claims interface{}, implicit []byte) (string, error) {
+	kid, key, err := t.keys.next()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(t.cfg.rand, nonce); err != nil {
+		return "", err
+	}
+
+	return encrypt(key, nonce, payload, newFooter(kid), implicit)
+}
+
+// DetokenizeWithAssertion verifies the token bound to the implicit assertion.
+func (t *LocalTokenizer) DetokenizeWithAssertion(token string, claims interface{}, implicit []byte) error {
+	body, rawFooter, err := decodeToken(&t.cfg, localHeader, token)
+	if err != nil {
+		return err
+	}
+
+	key, err := t.keys.lookup(rawFooter)
+	if err != nil {
+		return err
+	}
+
+	payload, err := decrypt(key, body, rawFooter, implicit)
+	if err != nil {
+		return err
+	}
+
+	return t.cfg.validate(payload, claims)
+}
+
+// encrypt encrypts the payload as a v4.local token.
+func encrypt(key, nonce, payload, footer, implicit []byte) (string, error) {
+	ek, n2, ak, err := splitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(payload))
+	c.XORKeyStream(ciphertext, payload)
+
+	tag, err := mac(ak, pae([]byte(localHeader), nonce, ciphertext, footer, implicit))
+	if err != nil {
+		return "", err
+	}
+
+	body := make([]byte, 0, nonceSize+len(ciphertext)+tagSize)
+	body = append(append(append(body, nonce...), ciphertext...), tag...)
+	return encodeToken(localHeader, body, footer), nil
+}
+
+// decrypt authenticates and decrypts the body of a v4.local token.
+func decrypt(key, body, footer, implicit []byte) ([]byte, error) {
+	if len(body) < nonceSize+tagSize {
+		return nil, ErrMalformed
+	}
+
+	nonce := body[:nonceSize]
+	ciphertext := body[nonceSize : len(body)-tagSize]
+	tag := body[len(body)-tagSize:]
+
+	ek, n2, ak, err := splitKey(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := mac(ak, pae([]byte(localHeader), nonce, ciphertext, footer, implicit))
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, len(ciphertext))
+	c.XORKeyStream(payload, ciphertext)
+	return payload, nil
+}
+
+// splitKey derives the encryption key, the XChaCha20 nonce and the
+// authentication key from the key and the nonce.
+func splitKey(key, nonce []byte) (ek, n2, ak []byte, err error) {
+	if len(key) != LocalKeySize {
+		return nil, nil, nil, fmt.Errorf("paseto: v4.local key must have %d bytes", LocalKeySize)
+	}
+
+	tmp, err := mac(key, append([]byte("paseto-encryption-key"), nonce...), 56)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ak, err = mac(key, append([]byte("paseto-auth-key-for-aead"), nonce...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return tmp[:32], tmp[32:], ak, nil
+}
+
+// mac computes the keyed BLAKE2b hash of msg, 32 bytes long unless a size is
+// given.
+func mac(key, msg []byte, size ...int) ([]byte, error) {
+	n := 32
+	if len(size) > 0 {
+		n = size[0]
+	}
+
+	h, err := blake2b.New(n, key)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
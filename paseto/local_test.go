@@ -0,0 +1,194 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+const (
+	vectorLocalKey = "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f"
+	vectorSecret   = `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+)
+
+func mustLocalKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, LocalKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return key
+}
+
+func TestLocalVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string
+		footer   string
+		implicit string
+		token    string
+	}{
+		{
+			name:  "4-E-1",
+			nonce: "0000000000000000000000000000000000000000000000000000000000000000",
+			token: "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+		},
+		{
+			name:     "4-E-7",
+			nonce:    "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8",
+			footer:   vectorFooter,
+			implicit: `{"test-vector":"4-E-7"}`,
+			token:    "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t40KCCWLA7GYL9KFHzKlwY9_RnIfRrMQpueydLEAZGGcA.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+	}
+
+	key := mustHex(t, vectorLocalKey)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := encrypt(key, mustHex(t, tt.nonce), []byte(vectorSecret), []byte(tt.footer), []byte(tt.implicit))
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if token != tt.token {
+				t.Errorf("expected token %s but got %s", tt.token, token)
+			}
+
+			cfg := newConfig(nil)
+			body, footer, err := decodeToken(&cfg, localHeader, tt.token)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			payload, err := decrypt(key, body, footer, []byte(tt.implicit))
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if string(payload) != vectorSecret {
+				t.Errorf("expected payload %s but got %s", vectorSecret, payload)
+			}
+
+			if _, err := decrypt(key, body, footer, []byte("other")); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+			}
+		})
+	}
+}
+
+func TestLocalTokenizer(t *testing.T) {
+	keys := map[string][]byte{"key-1": mustLocalKey(t), "key-2": mustLocalKey(t)}
+	tokenizer := NewLocalTokenizer(keys, WithClock(clock.Static), WithAudience("api"))
+
+	t.Run("tokenize and detokenize", func(t *testing.T) {
+		exp := clock.StaticTime.Add(time.Hour)
+		for i := 0; i < len(keys); i++ {
+			token, err := tokenizer.Tokenize(Claims{Audience: "api", Subject: "user", Expiration: &exp})
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if !strings.HasPrefix(token, localHeader) {
+				t.Errorf("expected prefix %s but got %s", localHeader, token)
+			}
+
+			if strings.Contains(token, "user") {
+				t.Errorf("expected the claims to be encrypted but got %s", token)
+			}
+
+			var claims Claims
+			if err := tokenizer.Detokenize(token, &claims); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if claims.Subject != "user" || !claims.Expiration.Equal(exp) {
+				t.Errorf("expected the tokenized claims but got %+v", claims)
+			}
+		}
+	})
+
+	t.Run("implicit assertion", func(t *testing.T) {
+		token, err := tokenizer.TokenizeWithAssertion(Claims{Audience: "api"}, []byte("tenant-1"))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		var claims Claims
+		if err := tokenizer.DetokenizeWithAssertion(token, &claims, []byte("tenant-1")); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		if err := tokenizer.DetokenizeWithAssertion(token, &claims, []byte("tenant-2")); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+	})
+
+	t.Run("configured implicit assertion", func(t *testing.T) {
+		bound := NewLocalTokenizer(keys, WithClock(clock.Static), WithImplicitAssertion([]byte("tenant-1")))
+		token, err := bound.Tokenize(Claims{})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		var claims Claims
+		if err := bound.Detokenize(token, &claims); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		if err := tokenizer.Detokenize(token, &claims); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		token, err := tokenizer.Tokenize(Claims{Audience: "api"})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		var claims Claims
+		if err := tokenizer.Detokenize(tamper(t, token), &claims); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+
+		other := NewLocalTokenizer(map[string][]byte{"key-3": mustLocalKey(t), "key-4": mustLocalKey(t)})
+		if err := other.Detokenize(token, &claims); !errors.Is(err, ErrUnknownKID) {
+			t.Errorf("expected error %v but got %v", ErrUnknownKID, err)
+		}
+
+		single := NewLocalTokenizer(map[string][]byte{"key-1": keys["key-1"]})
+		if err := single.Detokenize("v4.local.AAAA", &claims); !errors.Is(err, ErrMalformed) {
+			t.Errorf("expected error %v but got %v", ErrMalformed, err)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		short := NewLocalTokenizer(map[string][]byte{"key-1": []byte("short")})
+		if _, err := short.Tokenize(Claims{}); err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+
+	t.Run("nonce", func(t *testing.T) {
+		static := NewLocalTokenizer(map[string][]byte{"key-1": keys["key-1"]})
+		static.cfg.rand = bytes.NewReader(make([]byte, nonceSize))
+		token, err := static.Tokenize(Claims{})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if !strings.HasPrefix(token, localHeader+"AAAAAAAA") {
+			t.Errorf("expected the nonce from the reader but got %s", token)
+		}
+
+		if _, err := static.Tokenize(Claims{}); err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+}
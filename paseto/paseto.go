@@ -0,0 +1,340 @@
+// Package paseto implements PASETO v4 tokens, an alternative to JWT without
+// algorithm negotiation.
+package paseto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+// DefaultMaxTokenLength is the default maximum length of a token accepted by
+// the tokenizers.
+const DefaultMaxTokenLength = 8 << 10
+
+var (
+	// ErrMalformed is returned when the token can not be decoded.
+	ErrMalformed = errors.New("paseto: malformed token")
+
+	// ErrTokenTooLong is returned when the token exceeds the maximum length.
+	ErrTokenTooLong = errors.New("paseto: token too long")
+
+	// ErrWrongPurpose is returned when the token version or purpose is not
+	// the one of the tokenizer.
+	ErrWrongPurpose = errors.New("paseto: wrong version or purpose")
+
+	// ErrInvalidToken is returned when the token signature or authentication
+	// tag is invalid.
+	ErrInvalidToken = errors.New("paseto: invalid token")
+
+	// ErrUnknownKID is returned when the footer kid is missing or unknown.
+	ErrUnknownKID = errors.New("paseto: unknown key id")
+
+	// ErrNoKey is returned when a tokenizer has no key to create tokens with.
+	ErrNoKey = errors.New("paseto: no key")
+
+	// ErrVerifyOnly is returned when a verify-only tokenizer is asked to
+	// create a token.
+	ErrVerifyOnly = errors.New("paseto: tokenizer can only verify tokens")
+
+	// ErrExpired is returned when the token is expired.
+	ErrExpired = errors.New("paseto: token expired")
+
+	// ErrMissingExpiration is returned when the expiration is required but
+	// the token has no exp claim.
+	ErrMissingExpiration = errors.New("paseto: missing exp claim")
+
+	// ErrNotValidYet is returned when the token is used before its nbf claim.
+	ErrNotValidYet = errors.New("paseto: token not valid yet")
+
+	// ErrWrongIssuer is returned when the token iss claim is not expected.
+	ErrWrongIssuer = errors.New("paseto: wrong issuer")
+
+	// ErrWrongAudience is returned when the token aud claim is not expected.
+	ErrWrongAudience = errors.New("paseto: wrong audience")
+
+	// ErrInvalidClaims is returned when the token claims are invalid.
+	ErrInvalidClaims = errors.New("paseto: invalid claims")
+)
+
+// Tokenizer knows how to create and validate PASETO tokens. It mirrors
+// jwtx.Tokenizer, the claims can be any value encoded as a JSON object.
+type Tokenizer interface {
+	// Tokenize creates a token from the given claims.
+	Tokenize(claims interface{}) (token string, err error)
+	// Detokenize validates the given token and decodes the claims.
+	Detokenize(token string, claims interface{}) error
+}
+
+// Claims are the registered claims of PASETO, the times are encoded in
+// ISO 8601 format.
+type Claims struct {
+	Issuer     string     `json:"iss,omitempty"`
+	Subject    string     `json:"sub,omitempty"`
+	Audience   string     `json:"aud,omitempty"`
+	Expiration *time.Time `json:"exp,omitempty"`
+	NotBefore  *time.Time `json:"nbf,omitempty"`
+	IssuedAt   *time.Time `json:"iat,omitempty"`
+	TokenID    string     `json:"jti,omitempty"`
+}
+
+// Option is an option to configure how a tokenizer creates and verifies
+// tokens.
+type Option func(*config)
+
+// WithClock configures the clock used to validate the time based claims.
+// The default is clock.UTC.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// WithLeeway configures the clock skew tolerated when validating the time
+// based claims.
+func WithLeeway(leeway time.Duration) Option {
+	return func(cfg *config) {
+		cfg.leeway = leeway
+	}
+}
+
+// WithRequiredExpiration rejects the tokens without an exp claim, which are
+// otherwise accepted forever.
+func WithRequiredExpiration() Option {
+	return func(cfg *config) {
+		cfg.requireExp = true
+	}
+}
+
+// WithIssuer configures the accepted issuers, the iss claim must be one of them.
+func WithIssuer(issuers ...string) Option {
+	return func(cfg *config) {
+		cfg.issuers = issuers
+	}
+}
+
+// WithAudience configures the expected aud claim.
+func WithAudience(audience string) Option {
+	return func(cfg *config) {
+		cfg.audience = audience
+	}
+}
+
+// WithImplicitAssertion configures the implicit assertion bound to every
+// token. The assertion is authenticated but not part of the token, so the
+// same assertion must be given to verify the token.
+func WithImplicitAssertion(assertion []byte) Option {
+	return func(cfg *config) {
+		cfg.implicit = assertion
+	}
+}
+
+// WithMaxLength configures the maximum length of the accepted tokens.
+func WithMaxLength(n int) Option {
+	return func(cfg *config) {
+		cfg.maxLength = n
+	}
+}
+
+// config is the configuration shared by the tokenizers.
+type config struct {
+	clock      clock.Clock
+	leeway     time.Duration
+	requireExp bool
+	issuers    []string
+	audience   string
+	implicit   []byte
+	maxLength  int
+	rand       io.Reader
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		clock:     clock.UTC,
+		maxLength: DefaultMaxTokenLength,
+		rand:      rand.Reader,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// footer is the JSON footer of the tokens.
+type footer struct {
+	Kid string `json:"kid"`
+}
+
+// keyset selects the keys in round-robin order to create tokens, and by kid
+// to verify them.
+type keyset[K any] struct {
+	keys     map[string]K
+	indexes  []string
+	position uint32
+}
+
+func newKeyset[K any](keys map[string]K) *keyset[K] {
+	indexes := make([]string, 0, len(keys))
+	for kid := range keys {
+		indexes = append(indexes, kid)
+	}
+
+	sort.Strings(indexes)
+	return &keyset[K]{keys: keys, indexes: indexes}
+}
+
+// next returns the next key and its kid.
+func (s *keyset[K]) next() (string, K, error) {
+	if len(s.indexes) == 0 {
+		var zero K
+		return "", zero, ErrNoKey
+	}
+
+	kid := s.indexes[int(atomic.AddUint32(&s.position, 1)%uint32(len(s.indexes)))]
+	return kid, s.keys[kid], nil
+}
+
+// lookup returns the key addressed by the footer. A token without footer kid
+// is only accepted when there is a single key.
+func (s *keyset[K]) lookup(rawFooter []byte) (K, error) {
+	var zero K
+	kid := ""
+	if len(rawFooter) > 0 {
+		var f footer
+		if err := json.Unmarshal(rawFooter, &f); err != nil {
+			return zero, fmt.Errorf("%w: %v", ErrUnknownKID, err)
+		}
+
+		kid = f.Kid
+	}
+
+	if kid == "" && len(s.indexes) == 1 {
+		kid = s.indexes[0]
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return zero, ErrUnknownKID
+	}
+
+	return key, nil
+}
+
+// pae is the pre-authentication encoding of the pieces.
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, uint64(len(pieces)))
+	for _, p := range pieces {
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(len(p)))
+		buf.Write(p)
+	}
+
+	return buf.Bytes()
+}
+
+// encodeToken encodes the token with its optional footer.
+func encodeToken(header string, body, footer []byte) string {
+	token := header + base64.RawURLEncoding.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+
+	return token
+}
+
+// decodeToken decodes the body and footer of the token with the given header.
+func decodeToken(cfg *config, header, token string) (body, footer []byte, err error) {
+	if cfg.maxLength > 0 && len(token) > cfg.maxLength {
+		return nil, nil, ErrTokenTooLong
+	}
+
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, ErrWrongPurpose
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, header), ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrMalformed
+	}
+
+	if body, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	if len(parts) == 2 {
+		if footer, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+
+	return body, footer, nil
+}
+
+// newFooter encodes the footer of the key.
+func newFooter(kid string) []byte {
+	b, _ := json.Marshal(footer{Kid: kid})
+	return b
+}
+
+// validate decodes the verified payload into the claims and validates its
+// registered claims.
+func (cfg *config) validate(payload []byte, claims interface{}) error {
+	var registered Claims
+	if err := json.Unmarshal(payload, &registered); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+	}
+
+	if cfg.requireExp && registered.Expiration == nil {
+		return ErrMissingExpiration
+	}
+
+	now := cfg.clock.Now()
+	if registered.Expiration != nil && !now.Before(registered.Expiration.Add(cfg.leeway)) {
+		return ErrExpired
+	}
+
+	if registered.NotBefore != nil && now.Add(cfg.leeway).Before(*registered.NotBefore) {
+		return ErrNotValidYet
+	}
+
+	if registered.IssuedAt != nil && now.Add(cfg.leeway).Before(*registered.IssuedAt) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidClaims)
+	}
+
+	if len(cfg.issuers) > 0 && !contains(cfg.issuers, registered.Issuer) {
+		return ErrWrongIssuer
+	}
+
+	if cfg.audience != "" && registered.Audience != cfg.audience {
+		return ErrWrongAudience
+	}
+
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+	}
+
+	return nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
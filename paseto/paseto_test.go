@@ -0,0 +1,182 @@
+package paseto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return b
+}
+
+// tamper flips the first byte of the token body.
+func tamper(t *testing.T, token string) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	body, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	body[0] ^= 0xff
+	parts[2] = base64.RawURLEncoding.EncodeToString(body)
+	return strings.Join(parts, ".")
+}
+
+func TestPAE(t *testing.T) {
+	tests := []struct {
+		pieces [][]byte
+		want   string
+	}{
+		{nil, "0000000000000000"},
+		{[][]byte{{}}, "01000000000000000000000000000000"},
+		{[][]byte{{}, {}}, "020000000000000000000000000000000000000000000000"},
+		{[][]byte{[]byte("Paragon")}, "0100000000000000070000000000000050617261676f6e"},
+	}
+
+	for _, tt := range tests {
+		if got := hex.EncodeToString(pae(tt.pieces...)); got != tt.want {
+			t.Errorf("expected %s but got %s", tt.want, got)
+		}
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	t.Run("round-robin", func(t *testing.T) {
+		s := newKeyset(map[string]int{"a": 1, "b": 2})
+		seen := map[string]int{}
+		for i := 0; i < 4; i++ {
+			kid, _, err := s.next()
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			seen[kid]++
+		}
+
+		if seen["a"] != 2 || seen["b"] != 2 {
+			t.Errorf("expected each key used twice but got %v", seen)
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		s := newKeyset(map[string]int{})
+		if _, _, err := s.next(); !errors.Is(err, ErrNoKey) {
+			t.Errorf("expected error %v but got %v", ErrNoKey, err)
+		}
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		single := newKeyset(map[string]int{"a": 1})
+		if key, err := single.lookup(nil); err != nil || key != 1 {
+			t.Errorf("expected the single key but got %v, %v", key, err)
+		}
+
+		multiple := newKeyset(map[string]int{"a": 1, "b": 2})
+		if key, err := multiple.lookup(newFooter("b")); err != nil || key != 2 {
+			t.Errorf("expected key b but got %v, %v", key, err)
+		}
+
+		if _, err := multiple.lookup(nil); !errors.Is(err, ErrUnknownKID) {
+			t.Errorf("expected error %v but got %v", ErrUnknownKID, err)
+		}
+
+		if _, err := multiple.lookup(newFooter("c")); !errors.Is(err, ErrUnknownKID) {
+			t.Errorf("expected error %v but got %v", ErrUnknownKID, err)
+		}
+
+		if _, err := multiple.lookup([]byte("not json")); !errors.Is(err, ErrUnknownKID) {
+			t.Errorf("expected error %v but got %v", ErrUnknownKID, err)
+		}
+	})
+}
+
+func TestDecodeToken(t *testing.T) {
+	cfg := newConfig(nil)
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"wrong purpose", "v4.public.AAAA", ErrWrongPurpose},
+		{"wrong version", "v2.local.AAAA", ErrWrongPurpose},
+		{"too many parts", "v4.local.AAAA.AAAA.AAAA", ErrMalformed},
+		{"bad body", "v4.local.!!!!", ErrMalformed},
+		{"bad footer", "v4.local.AAAA.!!!!", ErrMalformed},
+		{"too long", "v4.local." + strings.Repeat("A", DefaultMaxTokenLength), ErrTokenTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeToken(&cfg, localHeader, tt.token); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+
+	body, footer, err := decodeToken(&cfg, localHeader, encodeToken(localHeader, []byte("body"), []byte("footer")))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !bytes.Equal(body, []byte("body")) || !bytes.Equal(footer, []byte("footer")) {
+		t.Errorf("expected body and footer but got %q, %q", body, footer)
+	}
+}
+
+func TestConfig_validate(t *testing.T) {
+	now := clock.StaticTime
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		claims Claims
+		err    error
+	}{
+		{"valid", nil, Claims{Expiration: at(time.Minute), IssuedAt: at(0)}, nil},
+		{"expired", nil, Claims{Expiration: at(-time.Second)}, ErrExpired},
+		{"expired within leeway", []Option{WithLeeway(time.Minute)}, Claims{Expiration: at(-time.Second)}, nil},
+		{"missing expiration", nil, Claims{}, nil},
+		{"required expiration", []Option{WithRequiredExpiration()}, Claims{Expiration: at(time.Minute)}, nil},
+		{"missing required expiration", []Option{WithRequiredExpiration()}, Claims{}, ErrMissingExpiration},
+		{"not valid yet", nil, Claims{NotBefore: at(time.Minute)}, ErrNotValidYet},
+		{"issued in the future", nil, Claims{IssuedAt: at(time.Minute)}, ErrInvalidClaims},
+		{"issuer", []Option{WithIssuer("a", "b")}, Claims{Issuer: "b"}, nil},
+		{"wrong issuer", []Option{WithIssuer("a")}, Claims{Issuer: "b"}, ErrWrongIssuer},
+		{"audience", []Option{WithAudience("api")}, Claims{Audience: "api"}, nil},
+		{"wrong audience", []Option{WithAudience("api")}, Claims{Audience: "web"}, ErrWrongAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig(append([]Option{WithClock(clock.Static)}, tt.opts...))
+			tokenizer := &LocalTokenizer{keys: newKeyset(map[string][]byte{"k": bytes.Repeat([]byte{1}, LocalKeySize)}), cfg: cfg}
+			token, err := tokenizer.Tokenize(tt.claims)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			var got Claims
+			if err := tokenizer.Detokenize(token, &got); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+}
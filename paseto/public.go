@@ -0,0 +1,123 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+)
+
+// publicHeader is the header of the v4.public tokens.
+const publicHeader = "v4.public."
+
+// PublicTokenizer creates and verifies v4.public tokens, which are signed
+// with Ed25519 but not encrypted. It signs with its keys in round-robin
+// order, and verifies with the key addressed by the footer kid.
+type PublicTokenizer struct {
+	signers   *keyset[ed25519.PrivateKey]
+	verifiers *keyset[ed25519.PublicKey]
+	cfg       config
+}
+
+// NewPublicTokenizer creates a new PublicTokenizer.
+func NewPublicTokenizer(keys map[string]ed25519.PrivateKey, opts ...Option) *PublicTokenizer {
+	public := make(map[string]ed25519.PublicKey, len(keys))
+	for kid, key := range keys {
+		public[kid] = key.Public().(ed25519.PublicKey)
+	}
+
+	return &PublicTokenizer{
+		signers:   newKeyset(keys),
+		verifiers: newKeyset(public),
+		cfg:       newConfig(opts),
+	}
+}
+
+// NewPublicVerifier creates a new verify-only PublicTokenizer.
+func NewPublicVerifier(keys map[string]ed25519.PublicKey, opts ...Option) *PublicTokenizer {
+	return &PublicTokenizer{
+		verifiers: newKeyset(keys),
+		cfg:       newConfig(opts),
+	}
+}
+
+// PublicKeys returns the verification keys addressed by kid.
+func (t *PublicTokenizer) PublicKeys() map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey, len(t.verifiers.keys))
+	for kid, key := range t.verifiers.keys {
+		keys[kid] = key
+	}
+
+	return keys
+}
+
+// Tokenize creates a signed v4.public token from the claims, bound to the
+// implicit assertion of WithImplicitAssertion. It returns ErrVerifyOnly for
+// the verifiers created by NewPublicVerifier.
+func (t *PublicTokenizer) Tokenize(claims interface{}) (string, error) {
+	return t.TokenizeWithAssertion(claims, t.cfg.implicit)
+}
+
+// Detokenize verifies the token, validates its registered claims and decodes
+// the claims. The tokens without an exp claim are accepted unless
+// WithRequiredExpiration is given.
+func (t *PublicTokenizer) Detokenize(token string, claims interface{}) error {
+	return t.DetokenizeWithAssertion(token, claims, t.cfg.implicit)
+}
+
+// TokenizeWithAssertion creates a token bound to the implicit assertion.
+func (t *PublicTokenizer) TokenizeWithAssertion(claims interface{}, implicit []byte) (string, error) {
+	if t.signers == nil {
+		return "", ErrVerifyOnly
+	}
+
+	kid, key, err := t.signers.next()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return sign(key, payload, newFooter(kid), implicit), nil
+}
+
+// DetokenizeWithAssertion verifies the token bound to the implicit assertion.
+func (t *PublicTokenizer) DetokenizeWithAssertion(token string, claims interface{}, implicit []byte) error {
+	body, rawFooter, err := decodeToken(&t.cfg, publicHeader, token)
+	if err != nil {
+		return err
+	}
+
+	key, err := t.verifiers.lookup(rawFooter)
+	if err != nil {
+		return err
+	}
+
+	payload, err := verify(key, body, rawFooter, implicit)
+	if err != nil {
+		return err
+	}
+
+	return t.cfg.validate(payload, claims)
+}
+
+// sign signs the payload as a v4.public token.
+func sign(key ed25519.PrivateKey, payload, footer, implicit []byte) string {
+	sig := ed25519.Sign(key, pae([]byte(publicHeader), payload, footer, implicit))
+	return encodeToken(publicHeader, append(payload, sig...), footer)
+}
+
+// verify verifies the body of a v4.public token and returns its payload.
+func verify(key ed25519.PublicKey, body, footer, implicit []byte) ([]byte, error) {
+	if len(body) < ed25519.SignatureSize {
+		return nil, ErrMalformed
+	}
+
+	payload, sig := body[:len(body)-ed25519.SignatureSize], body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(key, pae([]byte(publicHeader), payload, footer, implicit), sig) {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,181 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/gokit/clock"
+)
+
+const (
+	vectorPublicKey = "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	vectorSecretKey = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" + vectorPublicKey
+	vectorSigned    = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	vectorFooter    = `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`
+)
+
+func mustEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return key
+}
+
+func TestPublicVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		footer   string
+		implicit string
+		token    string
+	}{
+		{
+			name:  "4-S-1",
+			token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+		},
+		{
+			name:     "4-S-3",
+			footer:   vectorFooter,
+			implicit: `{"test-vector":"4-S-3"}`,
+			token:    "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+	}
+
+	secret := ed25519.PrivateKey(mustHex(t, vectorSecretKey))
+	public := ed25519.PublicKey(mustHex(t, vectorPublicKey))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := sign(secret, []byte(vectorSigned), []byte(tt.footer), []byte(tt.implicit))
+			if token != tt.token {
+				t.Errorf("expected token %s but got %s", tt.token, token)
+			}
+
+			cfg := newConfig(nil)
+			body, footer, err := decodeToken(&cfg, publicHeader, tt.token)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			payload, err := verify(public, body, footer, []byte(tt.implicit))
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if string(payload) != vectorSigned {
+				t.Errorf("expected payload %s but got %s", vectorSigned, payload)
+			}
+
+			if _, err := verify(public, body, footer, []byte("other")); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+			}
+		})
+	}
+}
+
+func TestPublicTokenizer(t *testing.T) {
+	keys := map[string]ed25519.PrivateKey{"key-1": mustEd25519Key(t), "key-2": mustEd25519Key(t)}
+	tokenizer := NewPublicTokenizer(keys, WithClock(clock.Static), WithIssuer("gokit"))
+
+	t.Run("tokenize and detokenize", func(t *testing.T) {
+		exp := clock.StaticTime.Add(time.Hour)
+		for i := 0; i < len(keys); i++ {
+			token, err := tokenizer.Tokenize(Claims{Issuer: "gokit", Subject: "user", Expiration: &exp})
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if !strings.HasPrefix(token, publicHeader) {
+				t.Errorf("expected prefix %s but got %s", publicHeader, token)
+			}
+
+			var claims Claims
+			if err := tokenizer.Detokenize(token, &claims); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if claims.Subject != "user" || !claims.Expiration.Equal(exp) {
+				t.Errorf("expected the tokenized claims but got %+v", claims)
+			}
+		}
+	})
+
+	t.Run("verifier", func(t *testing.T) {
+		token, err := tokenizer.Tokenize(Claims{Issuer: "gokit"})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		verifier := NewPublicVerifier(tokenizer.PublicKeys(), WithClock(clock.Static))
+		var claims Claims
+		if err := verifier.Detokenize(token, &claims); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		if _, err := verifier.Tokenize(claims); !errors.Is(err, ErrVerifyOnly) {
+			t.Errorf("expected error %v but got %v", ErrVerifyOnly, err)
+		}
+	})
+
+	t.Run("implicit assertion", func(t *testing.T) {
+		token, err := tokenizer.TokenizeWithAssertion(Claims{Issuer: "gokit"}, []byte("tenant-1"))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		var claims Claims
+		if err := tokenizer.DetokenizeWithAssertion(token, &claims, []byte("tenant-1")); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		if err := tokenizer.DetokenizeWithAssertion(token, &claims, []byte("tenant-2")); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+
+		if err := tokenizer.Detokenize(token, &claims); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		token, err := tokenizer.Tokenize(Claims{Issuer: "gokit"})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		other := NewPublicTokenizer(map[string]ed25519.PrivateKey{"key-1": mustEd25519Key(t)})
+		var claims Claims
+		if err := other.Detokenize(token, &claims); !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrUnknownKID) {
+			t.Errorf("expected error %v or %v but got %v", ErrInvalidToken, ErrUnknownKID, err)
+		}
+
+		if err := tokenizer.Detokenize(tamper(t, token), &claims); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+		}
+
+		if err := tokenizer.Detokenize(strings.Replace(token, publicHeader, localHeader, 1), &claims); !errors.Is(err, ErrWrongPurpose) {
+			t.Errorf("expected error %v but got %v", ErrWrongPurpose, err)
+		}
+
+		if err := tokenizer.Detokenize(token, &claims); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		token, err := tokenizer.Tokenize(Claims{Issuer: "other"})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		var claims Claims
+		if err := tokenizer.Detokenize(token, &claims); !errors.Is(err, ErrWrongIssuer) {
+			t.Errorf("expected error %v but got %v", ErrWrongIssuer, err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package password
+
+import (
+	"crypto/subtle"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams are the parameters of the Argon2id hash.
+type Argon2idParams struct {
+	// Memory is the memory size in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of lanes.
+	Parallelism uint8
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the hash in bytes.
+	KeyLength uint32
+}
+
+// DefaultArgon2idParams are the default Argon2id parameters, the minimum
+// recommended by OWASP.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// The upper bounds of the Argon2id parameters of the stored hashes, so a
+// crafted hash can not make Compare exhaust the memory or the CPU.
+const (
+	maxArgon2idMemory      = 1 << 20 // 1 GiB in KiB.
+	maxArgon2idIterations  = 16
+	maxArgon2idParallelism = 16
+)
+
+// Argon2id is an Argon2id password hash comparer. The hashes are encoded as
+// PHC strings, like $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>, which can
+// be verified by the Argon2 libraries of the other languages.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+// NewArgon2id creates a new Argon2id hash comparer that hashes with the given
+// parameters. The zero parameters are replaced by DefaultArgon2idParams.
+// Compare rejects the hashes using more than 1 GiB of memory, 16 iterations
+// or 16 threads, so do the parameters with ErrInvalidParams.
+func NewArgon2id(params Argon2idParams) (*Argon2id, error) {
+	d := DefaultArgon2idParams
+	if params.Memory == 0 {
+		params.Memory = d.Memory
+	}
+
+	if params.Iterations == 0 {
+		params.Iterations = d.Iterations
+	}
+
+	if params.Parallelism == 0 {
+		params.Parallelism = d.Parallelism
+	}
+
+	if params.SaltLength == 0 {
+		params.SaltLength = d.SaltLength
+	}
+
+	if params.KeyLength == 0 {
+		params.KeyLength = d.KeyLength
+	}
+
+	if !validArgon2idParams(uint64(params.Memory), uint64(params.Iterations), uint64(params.Parallelism)) {
+		return nil, ErrInvalidParams
+	}
+
+	return &Argon2id{params: params}, nil
+}
+
+// Params returns the parameters of the new hashes.
+func (a *Argon2id) Params() Argon2idParams { return a.params }
+
+// NeedsRehash reports whether the hashed password is not an Argon2id hash
+// or uses weaker parameters than the new hashes.
+func (a *Argon2id) NeedsRehash(hashedPassword string) bool {
+	params, _, err := parseArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory < a.params.Memory ||
+		params.Iterations < a.params.Iterations ||
+		params.Parallelism < a.params.Parallelism ||
+		params.SaltLength < a.params.SaltLength ||
+		params.KeyLength < a.params.KeyLength
+}
+
+// Compare compares the hashed password with the plain password, using the
+// parameters encoded in the hash.
+func (a *Argon2id) Compare(hashedPassword, plainPassword string) error {
+	params, p, err := parseArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(plainPassword), p.salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// Hash hashes the plain password with a new random salt.
+func (a *Argon2id) Hash(plainPassword string) (string, error) {
+	salt, err := newSalt(a.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(plainPassword), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+	p := phc{
+		id:      "argon2id",
+		version: strconv.Itoa(argon2.Version),
+		params: []phcParam{
+			uintParam("m", uint64(a.params.Memory)),
+			uintParam("t", uint64(a.params.Iterations)),
+			uintParam("p", uint64(a.params.Parallelism)),
+		},
+		salt: salt,
+		hash: key,
+	}
+
+	return p.String(), nil
+}
+
+// parseArgon2id decodes an Argon2id PHC string.
+func parseArgon2id(hashedPassword string) (Argon2idParams, phc, error) {
+	p, err := parsePHC(hashedPassword, "argon2id")
+	if err != nil {
+		return Argon2idParams{}, phc{}, err
+	}
+
+	if p.version != strconv.Itoa(argon2.Version) {
+		return Argon2idParams{}, phc{}, ErrInvalidHash
+	}
+
+	m, err := p.uint("m", 32)
+	if err != nil {
+		return Argon2idParams{}, phc{}, err
+	}
+
+	t, err := p.uint("t", 32)
+	if err != nil {
+		return Argon2idParams{}, phc{}, err
+	}
+
+	par, err := p.uint("p", 8)
+	if err != nil {
+		return Argon2idParams{}, phc{}, err
+	}
+
+	if !validArgon2idParams(m, t, par) {
+		return Argon2idParams{}, phc{}, ErrInvalidHash
+	}
+
+	params := Argon2idParams{
+		Memory:      uint32(m),
+		Iterations:  uint32(t),
+		Parallelism: uint8(par),
+		SaltLength:  uint32(len(p.salt)),
+		KeyLength:   uint32(len(p.hash)),
+	}
+
+	return params, p, nil
+}
+
+// validArgon2idParams reports whether the memory, iterations and parallelism
+// are within the bounds of the stored hashes.
+func validArgon2idParams(m, t, p uint64) bool {
+	return t >= 1 && t <= maxArgon2idIterations &&
+		p >= 1 && p <= maxArgon2idParallelism &&
+		m >= 8*p && m <= maxArgon2idMemory
+}
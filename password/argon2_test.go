@@ -0,0 +1,110 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustArgon2id(t *testing.T, params Argon2idParams) *Argon2id {
+	t.Helper()
+	h, err := NewArgon2id(params)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return h
+}
+
+func TestArgon2id(t *testing.T) {
+	a := mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 1})
+	if got := a.Params(); got.Parallelism != 1 || got.SaltLength != 16 || got.KeyLength != 32 {
+		t.Errorf("expected the default parameters to fill the zero parameters but got %+v", got)
+	}
+
+	h1, err := a.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h1, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("expected a PHC string but got %q", h1)
+	}
+
+	h2, err := a.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if h1 == h2 {
+		t.Errorf("expected a new salt for every hash")
+	}
+
+	if err := a.Compare(h1, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := a.Compare(h1, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+}
+
+func TestArgon2id_Interop(t *testing.T) {
+	// generated by the reference implementation:
+	// echo -n password | argon2 somesalt -id -t 2 -k 65536 -p 1 -l 32
+	const hash = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	a := mustArgon2id(t, DefaultArgon2idParams)
+	if err := a.Compare(hash, "password"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	params, _, err := parseArgon2id(hash)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	want := Argon2idParams{Memory: 65536, Iterations: 2, Parallelism: 1, SaltLength: 8, KeyLength: 32}
+	if params != want {
+		t.Errorf("expected params %+v but got %+v", want, params)
+	}
+}
+
+func TestArgon2id_InvalidHash(t *testing.T) {
+	a := mustArgon2id(t, DefaultArgon2idParams)
+	hashes := []string{
+		"",
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=16$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=0,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=4,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=2,p=256$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=2$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ",
+		"$argon2id$v=19$m=4294967295,t=4294967295,p=255$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=2097152,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=17,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=65536,t=2,p=17$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+	}
+
+	for _, h := range hashes {
+		if err := a.Compare(h, "password"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
+
+func TestNewArgon2id_Bounds(t *testing.T) {
+	for params, want := range map[Argon2idParams]error{
+		Argon2idParams{Memory: 1 << 20, Iterations: 16, Parallelism: 16}: nil,
+		Argon2idParams{Memory: 1<<20 + 1}:                                ErrInvalidParams,
+		Argon2idParams{Iterations: 17}:                                   ErrInvalidParams,
+		Argon2idParams{Parallelism: 17}:                                  ErrInvalidParams,
+		Argon2idParams{Memory: 63, Parallelism: 8}:                       ErrInvalidParams,
+	} {
+		if _, err := NewArgon2id(params); !errors.Is(err, want) {
+			t.Errorf("expected error %v for %+v but got %v", want, params, err)
+		}
+	}
+}
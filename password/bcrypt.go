@@ -0,0 +1,150 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxLength is the number of password bytes used by bcrypt, the rest
+// is ignored.
+const bcryptMaxLength = 72
+
+// bcryptPrehashPrefix tags the hashes of the pre-hashed passwords.
+const bcryptPrehashPrefix = "$bcrypt-sha256$"
+
+// ErrPasswordTooLong is returned when the password is longer than the 72
+// bytes bcrypt uses and the pre-hash is disabled.
+var ErrPasswordTooLong = errors.New("password is longer than 72 bytes")
+
+// BcryptOption is an option to configure the BcryptComparer.
+type BcryptOption func(*BcryptComparer)
+
+// WithBcryptPrehash enables the pre-hash of the passwords with HMAC-SHA256
+// under the given key, which can be empty, and base64, so the passwords
+// longer than 72 bytes are not truncated. The pre-hashed hashes are tagged
+// as $bcrypt-sha256$2a$..., the untagged hashes are still compared but need
+// a rehash.
+func WithBcryptPrehash(key []byte) BcryptOption {
+	return func(b *BcryptComparer) {
+		b.prehash = true
+		b.key = key
+	}
+}
+
+// BcryptComparer is a bcrypt password hash comparer with a configurable cost.
+//
+// Unlike Bcrypt, it rejects the passwords longer than 72 bytes with
+// ErrPasswordTooLong instead of silently ignoring their end, unless the
+// pre-hash is enabled.
+type BcryptComparer struct {
+	cost    int
+	prehash bool
+	key     []byte
+}
+
+// NewBcrypt creates a new BcryptComparer that hashes with the given cost,
+// between bcrypt.MinCost and bcrypt.MaxCost. See CalibrateBcrypt to choose
+// the cost.
+func NewBcrypt(cost int, opts ...BcryptOption) (*BcryptComparer, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, bcrypt.InvalidCostError(cost)
+	}
+
+	b := &BcryptComparer{cost: cost}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b, nil
+}
+
+// Cost returns the cost of the new hashes.
+func (b *BcryptComparer) Cost() int { return b.cost }
+
+// Compare compares the hashed password with the plain password. It returns
+// ErrMismatchedPassword when the password does not match.
+func (b *BcryptComparer) Compare(hashedPassword, plainPassword string) error {
+	password := []byte(plainPassword)
+	hashed := hashedPassword
+	if strings.HasPrefix(hashedPassword, bcryptPrehashPrefix) {
+		password = []byte(hmacBase64(b.key, plainPassword))
+		hashed = "$" + strings.TrimPrefix(hashedPassword, bcryptPrehashPrefix)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), password)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+
+	if err != nil {
+		return ErrInvalidHash
+	}
+
+	return nil
+}
+
+// Hash hashes the plain password with a new random salt.
+func (b *BcryptComparer) Hash(plainPassword string) (string, error) {
+	if !b.prehash {
+		if len(plainPassword) > bcryptMaxLength {
+			return "", ErrPasswordTooLong
+		}
+
+		hashed, err := bcrypt.GenerateFromPassword([]byte(plainPassword), b.cost)
+		return string(hashed), err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(hmacBase64(b.key, plainPassword)), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return bcryptPrehashPrefix + strings.TrimPrefix(string(hashed), "$"), nil
+}
+
+// NeedsRehash reports whether the hashed password is not a bcrypt hash of
+// the current pre-hash mode or uses a lower cost.
+func (b *BcryptComparer) NeedsRehash(hashedPassword string) bool {
+	hashed := hashedPassword
+	if strings.HasPrefix(hashedPassword, bcryptPrehashPrefix) != b.prehash {
+		return true
+	}
+
+	if b.prehash {
+		hashed = "$" + strings.TrimPrefix(hashedPassword, bcryptPrehashPrefix)
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost < b.cost
+}
+
+// CalibrateBcrypt returns the highest bcrypt cost whose hash takes less than
+// the target duration on this host, or bcrypt.MinCost if even the lowest
+// cost is slower. It measures the increasing costs until the next one, which
+// is twice as slow, is expected to exceed the target, so it takes about the
+// target duration.
+func CalibrateBcrypt(target time.Duration) (int, error) {
+	password := []byte("calibration password")
+	best := bcrypt.MinCost
+	for cost := bcrypt.MinCost; cost <= bcrypt.MaxCost; cost++ {
+		start := time.Now()
+		if _, err := bcrypt.GenerateFromPassword(password, cost); err != nil {
+			return 0, err
+		}
+
+		elapsed := time.Since(start)
+		if elapsed > target {
+			return best, nil
+		}
+
+		best = cost
+		if 2*elapsed > target {
+			break
+		}
+	}
+
+	return best, nil
+}
@@ -0,0 +1,140 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewBcrypt(t *testing.T) {
+	for _, cost := range []int{bcrypt.MinCost - 1, bcrypt.MaxCost + 1} {
+		if _, err := NewBcrypt(cost); !errors.As(err, new(bcrypt.InvalidCostError)) {
+			t.Errorf("expected an invalid cost error but got %v", err)
+		}
+	}
+
+	b, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	h, err := b.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if cost, _ := bcrypt.Cost([]byte(h)); cost != bcrypt.MinCost || b.Cost() != bcrypt.MinCost {
+		t.Errorf("expected cost %d but got %d", bcrypt.MinCost, cost)
+	}
+
+	if err := b.Compare(h, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := b.Compare(h, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+
+	if err := b.Compare("$2a$", "correct horse"); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected error %v but got %v", ErrInvalidHash, err)
+	}
+
+	if _, err := b.Hash(strings.Repeat("a", 73)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("expected error %v but got %v", ErrPasswordTooLong, err)
+	}
+}
+
+func TestBcryptComparer_Prehash(t *testing.T) {
+	b, err := NewBcrypt(bcrypt.MinCost, WithBcryptPrehash([]byte("key")))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	long := strings.Repeat("a", 72)
+	h, err := b.Hash(long + "1")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h, "$bcrypt-sha256$2a$04$") {
+		t.Errorf("expected a tagged hash but got %q", h)
+	}
+
+	if err := b.Compare(h, long+"1"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := b.Compare(h, long+"2"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected the bytes after 72 to matter but got %v", err)
+	}
+
+	other, _ := NewBcrypt(bcrypt.MinCost, WithBcryptPrehash([]byte("other")))
+	if err := other.Compare(h, long+"1"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+
+	// the hashes created before the pre-hash was enabled are still valid.
+	plain, _ := NewBcrypt(bcrypt.MinCost)
+	legacy, err := plain.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := b.Compare(legacy, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if !b.NeedsRehash(legacy) || b.NeedsRehash(h) || !plain.NeedsRehash(h) {
+		t.Errorf("expected the hashes of the other mode to need a rehash")
+	}
+}
+
+func TestBcryptComparer_NeedsRehash(t *testing.T) {
+	weak, _ := NewBcrypt(bcrypt.MinCost)
+	strong, _ := NewBcrypt(bcrypt.MinCost + 1)
+
+	h, err := weak.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strong.NeedsRehash(h) || weak.NeedsRehash(h) {
+		t.Errorf("expected only the lower cost to need a rehash")
+	}
+
+	if !weak.NeedsRehash("$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA") {
+		t.Errorf("expected a foreign hash to need a rehash")
+	}
+}
+
+func TestCalibrateBcrypt(t *testing.T) {
+	cost, err := CalibrateBcrypt(time.Nanosecond)
+	if err != nil || cost != bcrypt.MinCost {
+		t.Errorf("expected cost %d but got %d, %v", bcrypt.MinCost, cost, err)
+	}
+
+	target := 50 * time.Millisecond
+	cost, err = CalibrateBcrypt(target)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		t.Fatalf("expected a valid cost but got %d", cost)
+	}
+
+	if cost > bcrypt.MinCost {
+		start := time.Now()
+		if _, err := bcrypt.GenerateFromPassword([]byte("password"), cost); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		// the host is noisy, only catch a grossly wrong cost.
+		if elapsed := time.Since(start); elapsed > 4*target {
+			t.Errorf("expected a hash under %v but took %v", target, elapsed)
+		}
+	}
+}
@@ -22,7 +22,8 @@ type hashComparer int
 const (
 	// Unimplemented is an unimplemented password hash comparer.
 	Unimplemented hashComparer = iota
-	// Bcrypt is a bcrypt password hash comparer.
+	// Bcrypt is a bcrypt password hash comparer with bcrypt.DefaultCost, see
+	// NewBcrypt for a configurable cost.
 	Bcrypt
 )
 
@@ -55,3 +56,12 @@ func (p hashComparer) Hash(plainPassword string) (string, error) {
 
 	return "", ErrUnsupported
 }
+
+func (p hashComparer) NeedsRehash(hashedPassword string) bool {
+	if p != Bcrypt {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost < bcrypt.DefaultCost
+}
@@ -1,6 +1,10 @@
 package password
 
-import "testing"
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func TestPasswordHashComparer(t *testing.T) {
 	h1, err := Bcrypt.Hash("test")
@@ -26,3 +30,27 @@ func TestPasswordHashComparer_NotFound(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPasswordHashComparer_NeedsRehash(t *testing.T) {
+	weak, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !Bcrypt.NeedsRehash(string(weak)) || !Bcrypt.NeedsRehash("$argon2id$v=19") {
+		t.Fatal("expected the weaker and foreign hashes to need a rehash")
+	}
+
+	h, err := Bcrypt.Hash("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if Bcrypt.NeedsRehash(h) {
+		t.Fatal("expected the current hash not to need a rehash")
+	}
+
+	if !Unimplemented.NeedsRehash(h) {
+		t.Fatal("expected every hash to need a rehash")
+	}
+}
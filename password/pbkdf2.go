@@ -0,0 +1,137 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PBKDF2Params are the parameters of the PBKDF2-SHA256 hash.
+type PBKDF2Params struct {
+	// Iterations is the number of iterations.
+	Iterations uint32
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the hash in bytes.
+	KeyLength uint32
+}
+
+// DefaultPBKDF2Params are the default PBKDF2-SHA256 parameters, the minimum
+// recommended by OWASP.
+var DefaultPBKDF2Params = PBKDF2Params{
+	Iterations: 600000,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// maxPBKDF2Iterations is the upper bound of the iterations of the stored
+// hashes, so a crafted hash can not make Compare exhaust the CPU.
+const maxPBKDF2Iterations = 10_000_000
+
+// PBKDF2 is a PBKDF2-SHA256 password hash comparer, for the environments
+// that require a FIPS-140 approved algorithm. The hashes are encoded as PHC
+// strings, like $pbkdf2-sha256$i=600000$<salt>$<hash>.
+type PBKDF2 struct {
+	params PBKDF2Params
+}
+
+// NewPBKDF2 creates a new PBKDF2 hash comparer that hashes with the given
+// parameters. The zero parameters are replaced by DefaultPBKDF2Params.
+// Compare rejects the hashes using more than 10,000,000 iterations, so do
+// the parameters with ErrInvalidParams.
+func NewPBKDF2(params PBKDF2Params) (*PBKDF2, error) {
+	d := DefaultPBKDF2Params
+	if params.Iterations == 0 {
+		params.Iterations = d.Iterations
+	}
+
+	if params.SaltLength == 0 {
+		params.SaltLength = d.SaltLength
+	}
+
+	if params.KeyLength == 0 {
+		params.KeyLength = d.KeyLength
+	}
+
+	if params.Iterations > maxPBKDF2Iterations {
+		return nil, ErrInvalidParams
+	}
+
+	return &PBKDF2{params: params}, nil
+}
+
+// Params returns the parameters of the new hashes.
+func (k *PBKDF2) Params() PBKDF2Params { return k.params }
+
+// NeedsRehash reports whether the hashed password is not a PBKDF2-SHA256
+// hash or uses weaker parameters than the new hashes.
+func (k *PBKDF2) NeedsRehash(hashedPassword string) bool {
+	params, _, err := parsePBKDF2(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.Iterations < k.params.Iterations ||
+		params.SaltLength < k.params.SaltLength ||
+		params.KeyLength < k.params.KeyLength
+}
+
+// Compare compares the hashed password with the plain password, using the
+// parameters encoded in the hash.
+func (k *PBKDF2) Compare(hashedPassword, plainPassword string) error {
+	params, p, err := parsePBKDF2(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key := pbkdf2.Key([]byte(plainPassword), p.salt, int(params.Iterations), int(params.KeyLength), sha256.New)
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// Hash hashes the plain password with a new random salt.
+func (k *PBKDF2) Hash(plainPassword string) (string, error) {
+	salt, err := newSalt(k.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	p := phc{
+		id:     "pbkdf2-sha256",
+		params: []phcParam{uintParam("i", uint64(k.params.Iterations))},
+		salt:   salt,
+		hash:   pbkdf2.Key([]byte(plainPassword), salt, int(k.params.Iterations), int(k.params.KeyLength), sha256.New),
+	}
+
+	return p.String(), nil
+}
+
+// parsePBKDF2 decodes a PBKDF2-SHA256 PHC string.
+func parsePBKDF2(hashedPassword string) (PBKDF2Params, phc, error) {
+	p, err := parsePHC(hashedPassword, "pbkdf2-sha256")
+	if err != nil {
+		return PBKDF2Params{}, phc{}, err
+	}
+
+	// the iterations must fit in an int on every platform.
+	i, err := p.uint("i", 31)
+	if err != nil {
+		return PBKDF2Params{}, phc{}, err
+	}
+
+	if i < 1 || i > maxPBKDF2Iterations {
+		return PBKDF2Params{}, phc{}, ErrInvalidHash
+	}
+
+	params := PBKDF2Params{
+		Iterations: uint32(i),
+		SaltLength: uint32(len(p.salt)),
+		KeyLength:  uint32(len(p.hash)),
+	}
+
+	return params, p, nil
+}
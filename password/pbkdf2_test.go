@@ -0,0 +1,102 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustPBKDF2(t *testing.T, params PBKDF2Params) *PBKDF2 {
+	t.Helper()
+	h, err := NewPBKDF2(params)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return h
+}
+
+func TestPBKDF2(t *testing.T) {
+	k := mustPBKDF2(t, PBKDF2Params{Iterations: 1000})
+	if got := k.Params(); got.SaltLength != 16 || got.KeyLength != 32 {
+		t.Errorf("expected the default parameters to fill the zero parameters but got %+v", got)
+	}
+
+	h, err := k.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h, "$pbkdf2-sha256$i=1000$") {
+		t.Errorf("expected a PHC string but got %q", h)
+	}
+
+	if err := k.Compare(h, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := k.Compare(h, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+}
+
+func TestPBKDF2_Interop(t *testing.T) {
+	// generated with Python's hashlib.pbkdf2_hmac.
+	const hash = "$pbkdf2-sha256$i=1000$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ"
+
+	if err := mustPBKDF2(t, DefaultPBKDF2Params).Compare(hash, "password"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+}
+
+func TestPBKDF2_InvalidHash(t *testing.T) {
+	k := mustPBKDF2(t, DefaultPBKDF2Params)
+	hashes := []string{
+		"",
+		"$pbkdf2-sha256$i=0$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+		"$pbkdf2-sha256$i=4294967295$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+		"$pbkdf2-sha256$i=2147483647$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+		"$pbkdf2-sha256$i=10000001$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+		"$pbkdf2-sha512$i=1000$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+		"$pbkdf2-sha256$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+	}
+
+	for _, h := range hashes {
+		if err := k.Compare(h, "password"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
+
+func TestPBKDF2_NeedsRehash(t *testing.T) {
+	current := mustPBKDF2(t, PBKDF2Params{Iterations: 1000})
+	for params, want := range map[PBKDF2Params]bool{
+		current.Params():                               false,
+		PBKDF2Params{Iterations: 999}:                  true,
+		PBKDF2Params{Iterations: 2000, SaltLength: 32}: false,
+	} {
+		h, err := mustPBKDF2(t, params).Hash("password")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if got := current.NeedsRehash(h); got != want {
+			t.Errorf("expected NeedsRehash %v for %+v but got %v", want, params, got)
+		}
+	}
+
+	if !current.NeedsRehash("$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA") {
+		t.Errorf("expected a foreign hash to need a rehash")
+	}
+}
+
+func TestNewPBKDF2_Bounds(t *testing.T) {
+	for params, want := range map[PBKDF2Params]error{
+		PBKDF2Params{Iterations: 10_000_000}: nil,
+		PBKDF2Params{Iterations: 10_000_001}: ErrInvalidParams,
+	} {
+		if _, err := NewPBKDF2(params); !errors.Is(err, want) {
+			t.Errorf("expected error %v for %+v but got %v", want, params, err)
+		}
+	}
+}
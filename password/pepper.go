@@ -0,0 +1,179 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// pepperPrefix tags the peppered hashes, followed by the pepper version and
+// the inner hash: $pepper$v=<version><inner hash>.
+const pepperPrefix = "$pepper$v="
+
+var (
+	// ErrInvalidPepper is returned when the pepper keys are malformed.
+	ErrInvalidPepper = errors.New("invalid password pepper")
+
+	// ErrUnknownPepper is returned when the hash is peppered with a version
+	// whose key is not configured anymore.
+	ErrUnknownPepper = errors.New("unknown password pepper version")
+)
+
+// PepperKeys are the pepper keys addressed by version.
+type PepperKeys struct {
+	// Current is the version of the key used for the new hashes.
+	Current string
+	// Keys are the keys addressed by version.
+	Keys map[string][]byte
+}
+
+// ParsePepperKeys parses the comma-separated list of version:base64-key
+// pepper keys, the first key is the current one. It can be used with the env
+// package to load the keys:
+//
+//	keys := env.Parse("PASSWORD_PEPPERS", password.PepperKeys{}, password.ParsePepperKeys)
+func ParsePepperKeys(s string) (PepperKeys, error) {
+	keys := PepperKeys{Keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(s, ",") {
+		version, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return PepperKeys{}, ErrInvalidPepper
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return PepperKeys{}, ErrInvalidPepper
+		}
+
+		if _, ok := keys.Keys[version]; ok {
+			return PepperKeys{}, ErrInvalidPepper
+		}
+
+		if keys.Current == "" {
+			keys.Current = version
+		}
+
+		keys.Keys[version] = key
+	}
+
+	return keys, keys.validate()
+}
+
+// validate checks the current key exists and the versions can be encoded in
+// the hashes.
+func (k PepperKeys) validate() error {
+	if _, ok := k.Keys[k.Current]; !ok {
+		return ErrInvalidPepper
+	}
+
+	for version, key := range k.Keys {
+		if version == "" || strings.ContainsAny(version, "$,:") || len(key) == 0 {
+			return ErrInvalidPepper
+		}
+	}
+
+	return nil
+}
+
+// Pepper is a HashComparer decorator that applies a server-side secret, the
+// pepper, to the passwords before delegating to the inner HashComparer. A
+// leaked hash can not be cracked without the pepper, which is not stored
+// with the hashes.
+//
+// The password is replaced by the base64 encoded HMAC-SHA256 of the pepper,
+// and the hashes are tagged with the pepper version, like
+// $pepper$v=1$argon2id$v=19$..., so the pepper can be rotated: the hashes of
+// the previous versions are verified while their key is configured, and
+// NeedsRepepper reports them. Register the Pepper with
+// WithComparer("pepper", ...) to use it with a Verifier.
+type Pepper struct {
+	hc   HashComparer
+	keys PepperKeys
+}
+
+// NewPepper creates a new Pepper that delegates to the given comparer. It
+// returns ErrInvalidPepper when the keys are malformed.
+func NewPepper(hc HashComparer, keys PepperKeys) (*Pepper, error) {
+	if err := keys.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Pepper{hc: hc, keys: keys}, nil
+}
+
+// Compare compares the hashed password with the plain password peppered with
+// the key of the hash version. It returns ErrUnknownPepper when the version
+// is not configured.
+func (p *Pepper) Compare(hashedPassword, plainPassword string) error {
+	version, inner, err := parsePeppered(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key, ok := p.keys.Keys[version]
+	if !ok {
+		return ErrUnknownPepper
+	}
+
+	return p.hc.Compare(inner, hmacBase64(key, plainPassword))
+}
+
+// Hash hashes the plain password peppered with the current key.
+func (p *Pepper) Hash(plainPassword string) (string, error) {
+	inner, err := p.hc.Hash(hmacBase64(p.keys.Keys[p.keys.Current], plainPassword))
+	if err != nil {
+		return "", err
+	}
+
+	return pepperPrefix + p.keys.Current + inner, nil
+}
+
+// NeedsRepepper reports whether the hashed password is not peppered with the
+// current key.
+func (p *Pepper) NeedsRepepper(hashedPassword string) bool {
+	version, _, err := parsePeppered(hashedPassword)
+	return err != nil || version != p.keys.Current
+}
+
+// NeedsRehash reports whether the hashed password needs a new pepper, or
+// whether the inner hash needs a rehash when the inner comparer is a
+// Rehasher.
+func (p *Pepper) NeedsRehash(hashedPassword string) bool {
+	if p.NeedsRepepper(hashedPassword) {
+		return true
+	}
+
+	r, ok := p.hc.(Rehasher)
+	if !ok {
+		return false
+	}
+
+	_, inner, _ := parsePeppered(hashedPassword)
+	return r.NeedsRehash(inner)
+}
+
+// parsePeppered returns the pepper version and the inner hash of a peppered
+// hash.
+func parsePeppered(hashedPassword string) (string, string, error) {
+	if !strings.HasPrefix(hashedPassword, pepperPrefix) {
+		return "", "", ErrInvalidHash
+	}
+
+	rest := hashedPassword[len(pepperPrefix):]
+	i := strings.IndexByte(rest, '$')
+	if i <= 0 {
+		return "", "", ErrInvalidHash
+	}
+
+	return rest[:i], rest[i:], nil
+}
+
+// hmacBase64 returns the base64 encoded HMAC-SHA256 of the password, 44
+// bytes without any NUL byte.
+func hmacBase64(key []byte, plainPassword string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plainPassword))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,138 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/josestg/gokit/env"
+)
+
+func mustPepper(t *testing.T, hc HashComparer, s string) *Pepper {
+	t.Helper()
+	keys, err := ParsePepperKeys(s)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	p, err := NewPepper(hc, keys)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return p
+}
+
+func TestPepper(t *testing.T) {
+	inner := mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 1})
+	k1 := base64.StdEncoding.EncodeToString([]byte("pepper-1"))
+	k2 := base64.StdEncoding.EncodeToString([]byte("pepper-2"))
+
+	v1 := mustPepper(t, inner, "1:"+k1)
+	h1, err := v1.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h1, "$pepper$v=1$argon2id$v=19$") {
+		t.Errorf("expected a tagged hash but got %q", h1)
+	}
+
+	// the inner hash alone does not verify the password.
+	if err := inner.Compare(h1[len("$pepper$v=1"):], "correct horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+
+	if err := v1.Compare(h1, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+
+	// rotate to the second pepper, the first one is kept to verify the old
+	// hashes.
+	v2 := mustPepper(t, inner, "2:"+k2+", 1:"+k1)
+	if err := v2.Compare(h1, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if !v2.NeedsRepepper(h1) || !v2.NeedsRehash(h1) || v1.NeedsRepepper(h1) || v1.NeedsRehash(h1) {
+		t.Errorf("expected only the hashes of the previous pepper to need a new pepper")
+	}
+
+	h2, err := v2.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h2, "$pepper$v=2$") || v2.NeedsRehash(h2) {
+		t.Errorf("expected a current hash but got %q", h2)
+	}
+
+	if err := v1.Compare(h2, "correct horse"); !errors.Is(err, ErrUnknownPepper) {
+		t.Errorf("expected error %v but got %v", ErrUnknownPepper, err)
+	}
+
+	// the inner comparer decides whether the inner hash is outdated.
+	stronger := mustPepper(t, mustArgon2id(t, Argon2idParams{Memory: 2048, Iterations: 1}), "2:"+k2)
+	if stronger.NeedsRepepper(h2) || !stronger.NeedsRehash(h2) {
+		t.Errorf("expected only the inner hash to need a rehash")
+	}
+
+	for _, h := range []string{"", "$argon2id$v=19$m=1024", "$pepper$v=$argon2id", "$pepper$v=2"} {
+		if err := v2.Compare(h, "correct horse"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+
+		if !v2.NeedsRehash(h) {
+			t.Errorf("expected %q to need a rehash", h)
+		}
+	}
+}
+
+func TestPepper_Verifier(t *testing.T) {
+	p := mustPepper(t, mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 1}), "1:"+base64.StdEncoding.EncodeToString([]byte("pepper")))
+	v := NewVerifier(p, WithComparer("pepper", p))
+
+	legacy, err := mustScrypt(t, ScryptParams{LogN: 4}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	rehashed, err := v.Verify(legacy, "correct horse")
+	if err != nil || !strings.HasPrefix(rehashed, "$pepper$v=1$") {
+		t.Fatalf("expected a peppered rehash but got %q, %v", rehashed, err)
+	}
+
+	if rehashed, err := v.Verify(rehashed, "correct horse"); err != nil || rehashed != "" {
+		t.Errorf("expected no rehash of a current hash but got %q, %v", rehashed, err)
+	}
+}
+
+func TestParsePepperKeys(t *testing.T) {
+	t.Setenv("PASSWORD_PEPPERS", "v2:cGVwcGVyLTI=,v1:cGVwcGVyLTE=")
+
+	keys := env.Parse("PASSWORD_PEPPERS", PepperKeys{}, ParsePepperKeys)
+	if keys.Current != "v2" || string(keys.Keys["v2"]) != "pepper-2" || string(keys.Keys["v1"]) != "pepper-1" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	invalid := []string{
+		"",
+		"v1",
+		"v1:not base64",
+		"v1:",
+		":cGVwcGVy",
+		"v$1:cGVwcGVy",
+		"v1:cGVwcGVy,v1:cGVwcGVy",
+	}
+
+	for _, s := range invalid {
+		if _, err := ParsePepperKeys(s); !errors.Is(err, ErrInvalidPepper) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidPepper, s, err)
+		}
+	}
+
+	if _, err := NewPepper(Bcrypt, PepperKeys{Current: "2", Keys: map[string][]byte{"1": []byte("pepper")}}); !errors.Is(err, ErrInvalidPepper) {
+		t.Errorf("expected error %v but got %v", ErrInvalidPepper, err)
+	}
+}
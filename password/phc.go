@@ -0,0 +1,146 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidHash is returned when the hashed password is malformed or
+	// uses parameters out of the supported bounds.
+	ErrInvalidHash = errors.New("invalid password hash")
+
+	// ErrMismatchedPassword is returned when the plain password does not match
+	// the hashed password.
+	ErrMismatchedPassword = errors.New("hashed password does not match the plain password")
+
+	// ErrInvalidParams is returned when the parameters of a new hash comparer
+	// are out of the bounds Compare accepts, so its hashes could never be
+	// verified.
+	ErrInvalidParams = errors.New("invalid password hash parameters")
+)
+
+// phcEncoding is the B64 encoding of the PHC string format, the standard
+// base64 alphabet without padding.
+var phcEncoding = base64.RawStdEncoding
+
+// phcParam is a name=value parameter of a PHC string.
+type phcParam struct {
+	name  string
+	value string
+}
+
+// phc is a hash in the PHC string format:
+//
+//	$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*]$<salt>$<hash>
+//
+// See https://github.com/P-H-C/phc-string-format.
+type phc struct {
+	id      string
+	version string
+	params  []phcParam
+	salt    []byte
+	hash    []byte
+}
+
+// String encodes the hash as a PHC string.
+func (p phc) String() string {
+	var b strings.Builder
+	b.WriteString("$" + p.id)
+	if p.version != "" {
+		b.WriteString("$v=" + p.version)
+	}
+
+	for i, param := range p.params {
+		if i == 0 {
+			b.WriteString("$")
+		} else {
+			b.WriteString(",")
+		}
+
+		b.WriteString(param.name + "=" + param.value)
+	}
+
+	b.WriteString("$" + phcEncoding.EncodeToString(p.salt))
+	b.WriteString("$" + phcEncoding.EncodeToString(p.hash))
+	return b.String()
+}
+
+// parsePHC decodes a PHC string with the given id.
+func parsePHC(s, id string) (phc, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) < 4 || parts[0] != "" || parts[1] != id {
+		return phc{}, ErrInvalidHash
+	}
+
+	p := phc{id: id}
+	rest := parts[2:]
+	if strings.HasPrefix(rest[0], "v=") {
+		p.version = strings.TrimPrefix(rest[0], "v=")
+		rest = rest[1:]
+	}
+
+	if len(rest) == 3 {
+		for _, field := range strings.Split(rest[0], ",") {
+			name, value, ok := strings.Cut(field, "=")
+			if !ok || name == "" || value == "" {
+				return phc{}, ErrInvalidHash
+			}
+
+			p.params = append(p.params, phcParam{name: name, value: value})
+		}
+
+		rest = rest[1:]
+	}
+
+	if len(rest) != 2 {
+		return phc{}, ErrInvalidHash
+	}
+
+	var err error
+	if p.salt, err = phcEncoding.DecodeString(rest[0]); err != nil {
+		return phc{}, ErrInvalidHash
+	}
+
+	if p.hash, err = phcEncoding.DecodeString(rest[1]); err != nil || len(p.hash) == 0 {
+		return phc{}, ErrInvalidHash
+	}
+
+	return p, nil
+}
+
+// uint returns the unsigned integer parameter with the given name, which
+// must fit in the given bit size.
+func (p phc) uint(name string, bitSize int) (uint64, error) {
+	for _, param := range p.params {
+		if param.name == name {
+			v, err := strconv.ParseUint(param.value, 10, bitSize)
+			if err != nil {
+				return 0, ErrInvalidHash
+			}
+
+			return v, nil
+		}
+	}
+
+	return 0, ErrInvalidHash
+}
+
+// uintParam returns a decimal name=value parameter.
+func uintParam(name string, v uint64) phcParam {
+	return phcParam{name: name, value: strconv.FormatUint(v, 10)}
+}
+
+// newSalt returns a random salt of n bytes.
+func newSalt(n uint32) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
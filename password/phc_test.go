@@ -0,0 +1,69 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPHC(t *testing.T) {
+	p := phc{
+		id:      "argon2id",
+		version: "19",
+		params:  []phcParam{uintParam("m", 65536), uintParam("t", 2), uintParam("p", 1)},
+		salt:    []byte("somesalt"),
+		hash:    []byte("hash"),
+	}
+
+	const want = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$aGFzaA"
+	if got := p.String(); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+
+	got, err := parsePHC(want, "argon2id")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if got.version != "19" || len(got.params) != 3 || !bytes.Equal(got.salt, p.salt) || !bytes.Equal(got.hash, p.hash) {
+		t.Errorf("unexpected decoded hash %+v", got)
+	}
+
+	if m, err := got.uint("m", 32); err != nil || m != 65536 {
+		t.Errorf("expected m=65536 but got %d, %v", m, err)
+	}
+
+	if _, err := got.uint("x", 32); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected error %v but got %v", ErrInvalidHash, err)
+	}
+
+	if _, err := got.uint("m", 8); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected error %v but got %v", ErrInvalidHash, err)
+	}
+
+	// the version and the parameters are optional.
+	got, err = parsePHC("$sha$c29tZXNhbHQ$aGFzaA", "sha")
+	if err != nil || got.version != "" || len(got.params) != 0 {
+		t.Errorf("expected a hash without parameters but got %+v, %v", got, err)
+	}
+}
+
+func TestParsePHC_Invalid(t *testing.T) {
+	hashes := []string{
+		"",
+		"argon2id$v=19$m=1$c29tZXNhbHQ$aGFzaA",
+		"$argon2i$v=19$m=1$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=1$c29tZXNhbHQ",
+		"$argon2id$v=19$m$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=1,$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=1$c29tZXNhbHQ=$aGFzaA",
+		"$argon2id$v=19$m=1$c29tZXNhbHQ$",
+		"$argon2id$v=19$m=1$c29tZXNhbHQ$aGFzaA$x",
+	}
+
+	for _, h := range hashes {
+		if _, err := parsePHC(h, "argon2id"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
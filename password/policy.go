@@ -0,0 +1,250 @@
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/josestg/gokit/validate"
+)
+
+const (
+	// DefaultMinLength is the default minimum length of the passwords.
+	DefaultMinLength = 8
+	// DefaultMaxLength is the default maximum length of the passwords.
+	DefaultMaxLength = 64
+	// DefaultMinScore is the default minimum strength score of the passwords.
+	DefaultMinScore = 3
+)
+
+// CharClass is a class of characters a password can be required to contain.
+type CharClass int
+
+const (
+	// Lowercase are the lowercase letters.
+	Lowercase CharClass = iota
+	// Uppercase are the uppercase letters.
+	Uppercase
+	// Digit are the decimal digits.
+	Digit
+	// Symbol are the other characters.
+	Symbol
+)
+
+// String returns the name of the class.
+func (c CharClass) String() string {
+	switch c {
+	case Lowercase:
+		return "lowercase"
+	case Uppercase:
+		return "uppercase"
+	case Digit:
+		return "digit"
+	case Symbol:
+		return "symbol"
+	}
+
+	return "unknown"
+}
+
+// description returns the description of a character of the class.
+func (c CharClass) description() string {
+	switch c {
+	case Lowercase:
+		return "a lowercase letter"
+	case Uppercase:
+		return "an uppercase letter"
+	case Digit:
+		return "a digit"
+	}
+
+	return "a symbol"
+}
+
+// classOf returns the class of the rune.
+func classOf(r rune) CharClass {
+	switch {
+	case unicode.IsLower(r):
+		return Lowercase
+	case unicode.IsUpper(r):
+		return Uppercase
+	case unicode.IsDigit(r):
+		return Digit
+	}
+
+	return Symbol
+}
+
+// PolicyOption is an option to configure the Policy.
+type PolicyOption func(*Policy)
+
+// WithLength configures the length bounds of the passwords, in characters.
+// The default is DefaultMinLength to DefaultMaxLength, zero disables a bound.
+func WithLength(minLength, maxLength int) PolicyOption {
+	return func(p *Policy) {
+		p.minLength, p.maxLength = minLength, maxLength
+	}
+}
+
+// WithRequiredClasses requires the passwords to contain a character of every
+// given class. No class is required by default, as the composition rules
+// make the passwords harder to remember more than harder to guess.
+func WithRequiredClasses(classes ...CharClass) PolicyOption {
+	return func(p *Policy) {
+		p.classes = classes
+	}
+}
+
+// WithDenylist configures the list of the denied passwords, which is also
+// used to estimate the strength. The default is CommonPasswords, nil
+// disables the denylist.
+func WithDenylist(w *Wordlist) PolicyOption {
+	return func(p *Policy) {
+		p.denylist = w
+	}
+}
+
+// WithMinScore configures the minimum strength score of the passwords, from
+// 0 to 4, see EstimateStrength. The default is DefaultMinScore, zero
+// disables the strength rule.
+func WithMinScore(score int) PolicyOption {
+	return func(p *Policy) {
+		p.minScore = score
+	}
+}
+
+// Policy checks the passwords chosen by the users.
+type Policy struct {
+	minLength int
+	maxLength int
+	classes   []CharClass
+	denylist  *Wordlist
+	minScore  int
+}
+
+// NewPolicy creates a new Policy.
+func NewPolicy(opts ...PolicyOption) *Policy {
+	p := &Policy{
+		minLength: DefaultMinLength,
+		maxLength: DefaultMaxLength,
+		denylist:  CommonPasswords(),
+		minScore:  DefaultMinScore,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Result is the result of a password check.
+type Result struct {
+	// Strength is the estimated strength of the password.
+	Strength Strength
+	// Violations are the violated rules, without field, empty when the
+	// password is accepted.
+	Violations validate.Errors
+}
+
+// OK reports whether the password is accepted.
+func (r Result) OK() bool { return len(r.Violations) == 0 }
+
+// Err returns nil when the password is accepted, or the violations of the
+// given field as validate.Errors. It can be returned by the Validate method
+// of a request, so httpx.Validate lists the violations per field:
+//
+//	func (r SignUp) Validate() error {
+//		return policy.Check(r.Password, r.Username, r.Email).Err("password")
+//	}
+func (r Result) Err(field string) error {
+	if r.OK() {
+		return nil
+	}
+
+	errs := make(validate.Errors, len(r.Violations))
+	for i, fe := range r.Violations {
+		fe.Field = field
+		errs[i] = fe
+	}
+
+	return errs
+}
+
+// Check checks the password against the policy. The user inputs are the
+// personal information of the user, like the username or the email address,
+// which the password must not contain.
+func (p *Policy) Check(password string, userInputs ...string) Result {
+	var r Result
+	violate := func(rule, param, message string) {
+		r.Violations = append(r.Violations, validate.FieldError{Rule: rule, Param: param, Message: message})
+	}
+
+	n := utf8.RuneCountInString(password)
+	if p.minLength > 0 && n < p.minLength {
+		violate("min", strconv.Itoa(p.minLength), "must have at least "+strconv.Itoa(p.minLength)+" characters")
+	}
+
+	if p.maxLength > 0 && n > p.maxLength {
+		violate("max", strconv.Itoa(p.maxLength), "must have at most "+strconv.Itoa(p.maxLength)+" characters")
+	}
+
+	used := make(map[CharClass]bool)
+	for _, c := range password {
+		used[classOf(c)] = true
+	}
+
+	for _, class := range p.classes {
+		if !used[class] {
+			violate(class.String(), "", "must contain "+class.description())
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if p.denylist != nil && (p.denylist.Contains(lower) || p.denylist.Contains(unl33tString(lower))) {
+		violate("denylist", "", "must not be a commonly used password")
+	}
+
+	if containsUserInput(lower, userInputs) {
+		violate("user_input", "", "must not contain your personal information")
+	}
+
+	r.Strength = EstimateStrength(password, p.denylist, userInputs...)
+	if p.minScore > 0 && r.Strength.Score < p.minScore {
+		msg := "is too easy to guess"
+		if r.Strength.Warning != "" {
+			msg += ": " + strings.TrimSuffix(r.Strength.Warning, ".")
+		}
+
+		violate("strength", strconv.Itoa(p.minScore), msg)
+	}
+
+	return r
+}
+
+// containsUserInput reports whether the lowercase password contains a token
+// of the user inputs, ignoring the l33t substitutions.
+func containsUserInput(lower string, userInputs []string) bool {
+	unl33t := unl33tString(lower)
+	for _, input := range userInputs {
+		for _, token := range contextTokens(input) {
+			if utf8.RuneCountInString(token) >= 3 && (strings.Contains(lower, token) || strings.Contains(unl33t, token)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unl33tString reverts the l33t substitutions of the lowercase string.
+func unl33tString(lower string) string {
+	return strings.Map(func(r rune) rune {
+		if sub, ok := l33t[r]; ok {
+			return sub
+		}
+
+		return r
+	}, lower)
+}
@@ -0,0 +1,116 @@
+package password
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/josestg/gokit/httpx"
+	"github.com/josestg/gokit/validate"
+)
+
+func rules(r Result) string {
+	names := make([]string, len(r.Violations))
+	for i, fe := range r.Violations {
+		names[i] = fe.Rule
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := NewPolicy()
+	tests := []struct {
+		password string
+		rules    string
+	}{
+		{"correct horse battery staple", ""},
+		{"kJh3nX9w", ""},
+		{"short", "min,strength"},
+		{strings.Repeat("correct horse ", 5), "max"},
+		{"password", "denylist,strength"},
+		{"P@ssw0rd", "denylist,strength"},
+		{"johnsmith", "user_input,strength"},
+		{"j0hn-sm1th-rocks-42", "user_input"},
+		{"abcdefghijk", "strength"},
+	}
+
+	for _, tt := range tests {
+		r := p.Check(tt.password, "jsmith", "john.smith@example.com")
+		if got := rules(r); got != tt.rules {
+			t.Errorf("expected rules %q for %q but got %q: %v", tt.rules, tt.password, got, r.Violations)
+		}
+
+		if r.OK() != (tt.rules == "") {
+			t.Errorf("expected OK %v for %q", tt.rules == "", tt.password)
+		}
+	}
+}
+
+func TestPolicy_Options(t *testing.T) {
+	p := NewPolicy(
+		WithLength(4, 0),
+		WithRequiredClasses(Lowercase, Uppercase, Digit, Symbol),
+		WithDenylist(NewWordlist("hunter2")),
+		WithMinScore(0),
+	)
+
+	r := p.Check("hunter2")
+	if got := rules(r); got != "uppercase,symbol,denylist" {
+		t.Errorf("expected rules %q but got %q", "uppercase,symbol,denylist", got)
+	}
+
+	if r.Violations[0].Message != "must contain an uppercase letter" {
+		t.Errorf("unexpected message %q", r.Violations[0].Message)
+	}
+
+	if r := p.Check("Aa1!" + strings.Repeat("x", 100)); !r.OK() {
+		t.Errorf("expected no violation but got %v", r.Violations)
+	}
+
+	// the default denylist is not used.
+	if r := p.Check("Passw0rd!"); !r.OK() {
+		t.Errorf("expected no violation but got %v", r.Violations)
+	}
+
+	if r := NewPolicy(WithDenylist(nil), WithMinScore(0)).Check("password"); !r.OK() {
+		t.Errorf("expected no violation but got %v", r.Violations)
+	}
+}
+
+type signUp struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password"`
+}
+
+func (s signUp) Validate() error {
+	return NewPolicy().Check(s.Password, s.Username).Err("password")
+}
+
+func TestResult_Err(t *testing.T) {
+	if err := NewPolicy().Check("correct horse battery staple").Err("password"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	err := httpx.Validate(signUp{Username: "jsmith", Password: "jsmith1"})
+	var p *httpx.Problem
+	if !errors.As(err, &p) || p.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a 422 problem but got %v", err)
+	}
+
+	want := validate.FieldError{Field: "password", Rule: "min", Param: "8", Message: "must have at least 8 characters"}
+	if len(p.InvalidParams) != 3 || p.InvalidParams[0] != want {
+		t.Fatalf("expected the violations of the password field but got %+v", p.InvalidParams)
+	}
+
+	for _, fe := range p.InvalidParams {
+		if fe.Field != "password" {
+			t.Errorf("expected field password but got %q", fe.Field)
+		}
+	}
+
+	if msg := p.InvalidParams[2].Message; msg != "is too easy to guess: Avoid using your personal information" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
@@ -0,0 +1,203 @@
+package password
+
+import (
+	"crypto/subtle"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams are the parameters of the scrypt hash.
+type ScryptParams struct {
+	// LogN is the base 2 logarithm of the CPU/memory cost N.
+	LogN uint8
+	// BlockSize is the block size r.
+	BlockSize uint32
+	// Parallelism is the parallelization p.
+	Parallelism uint32
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the hash in bytes.
+	KeyLength uint32
+}
+
+// DefaultScryptParams are the default scrypt parameters, the minimum
+// recommended by OWASP.
+var DefaultScryptParams = ScryptParams{
+	LogN:        17,
+	BlockSize:   8,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// The upper bounds of the scrypt parameters of the stored hashes, so a
+// crafted hash can not make Compare exhaust the memory or the CPU. The memory
+// used by scrypt is 128 * r * N bytes.
+const (
+	maxScryptMemory      = 1 << 30
+	maxScryptParallelism = 16
+)
+
+// Scrypt is a scrypt password hash comparer. The hashes are encoded as PHC
+// strings, like $scrypt$ln=17,r=8,p=1$<salt>$<hash>, which can be verified
+// by the scrypt libraries of the other languages.
+type Scrypt struct {
+	params ScryptParams
+}
+
+// NewScrypt creates a new Scrypt hash comparer that hashes with the given
+// parameters. The zero parameters are replaced by DefaultScryptParams.
+// Compare rejects the hashes using more than 1 GiB of memory or a
+// parallelism above 16, so do the parameters with ErrInvalidParams.
+func NewScrypt(params ScryptParams) (*Scrypt, error) {
+	d := DefaultScryptParams
+	if params.LogN == 0 {
+		params.LogN = d.LogN
+	}
+
+	if params.BlockSize == 0 {
+		params.BlockSize = d.BlockSize
+	}
+
+	if params.Parallelism == 0 {
+		params.Parallelism = d.Parallelism
+	}
+
+	if params.SaltLength == 0 {
+		params.SaltLength = d.SaltLength
+	}
+
+	if params.KeyLength == 0 {
+		params.KeyLength = d.KeyLength
+	}
+
+	if !validScryptParams(uint64(params.LogN), uint64(params.BlockSize), uint64(params.Parallelism)) {
+		return nil, ErrInvalidParams
+	}
+
+	return &Scrypt{params: params}, nil
+}
+
+// Params returns the parameters of the new hashes.
+func (s *Scrypt) Params() ScryptParams { return s.params }
+
+// NeedsRehash reports whether the hashed password is not a scrypt hash or
+// uses weaker parameters than the new hashes.
+func (s *Scrypt) NeedsRehash(hashedPassword string) bool {
+	params, _, err := parseScrypt(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.LogN < s.params.LogN ||
+		params.BlockSize < s.params.BlockSize ||
+		params.Parallelism < s.params.Parallelism ||
+		params.SaltLength < s.params.SaltLength ||
+		params.KeyLength < s.params.KeyLength
+}
+
+// Compare compares the hashed password with the plain password, using the
+// parameters encoded in the hash.
+func (s *Scrypt) Compare(hashedPassword, plainPassword string) error {
+	params, p, err := parseScrypt(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key, err := scryptKey(plainPassword, p.salt, params)
+	if err != nil {
+		return ErrInvalidHash
+	}
+
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// Hash hashes the plain password with a new random salt.
+func (s *Scrypt) Hash(plainPassword string) (string, error) {
+	salt, err := newSalt(s.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scryptKey(plainPassword, salt, s.params)
+	if err != nil {
+		return "", err
+	}
+
+	p := phc{
+		id: "scrypt",
+		params: []phcParam{
+			uintParam("ln", uint64(s.params.LogN)),
+			uintParam("r", uint64(s.params.BlockSize)),
+			uintParam("p", uint64(s.params.Parallelism)),
+		},
+		salt: salt,
+		hash: key,
+	}
+
+	return p.String(), nil
+}
+
+// scryptKey derives the scrypt key of the password.
+func scryptKey(plainPassword string, salt []byte, params ScryptParams) ([]byte, error) {
+	return scrypt.Key(
+		[]byte(plainPassword),
+		salt,
+		1<<params.LogN,
+		int(params.BlockSize),
+		int(params.Parallelism),
+		int(params.KeyLength),
+	)
+}
+
+// parseScrypt decodes a scrypt PHC string.
+func parseScrypt(hashedPassword string) (ScryptParams, phc, error) {
+	p, err := parsePHC(hashedPassword, "scrypt")
+	if err != nil {
+		return ScryptParams{}, phc{}, err
+	}
+
+	ln, err := p.uint("ln", 8)
+	if err != nil {
+		return ScryptParams{}, phc{}, err
+	}
+
+	r, err := p.uint("r", 32)
+	if err != nil {
+		return ScryptParams{}, phc{}, err
+	}
+
+	par, err := p.uint("p", 32)
+	if err != nil {
+		return ScryptParams{}, phc{}, err
+	}
+
+	if !validScryptParams(ln, r, par) {
+		return ScryptParams{}, phc{}, ErrInvalidHash
+	}
+
+	params := ScryptParams{
+		LogN:        uint8(ln),
+		BlockSize:   uint32(r),
+		Parallelism: uint32(par),
+		SaltLength:  uint32(len(p.salt)),
+		KeyLength:   uint32(len(p.hash)),
+	}
+
+	return params, p, nil
+}
+
+// validScryptParams reports whether the cost, block size and parallelism are
+// within the bounds of the stored hashes.
+func validScryptParams(ln, r, p uint64) bool {
+	// N must be greater than 1 and fit in an int on every platform.
+	if ln < 1 || ln > 30 || r < 1 || p < 1 || p > maxScryptParallelism {
+		return false
+	}
+
+	return r <= maxScryptMemory/128>>ln
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustScrypt(t *testing.T, params ScryptParams) *Scrypt {
+	t.Helper()
+	h, err := NewScrypt(params)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	return h
+}
+
+func TestScrypt(t *testing.T) {
+	s := mustScrypt(t, ScryptParams{LogN: 10})
+	if got := s.Params(); got.BlockSize != 8 || got.Parallelism != 1 || got.SaltLength != 16 || got.KeyLength != 32 {
+		t.Errorf("expected the default parameters to fill the zero parameters but got %+v", got)
+	}
+
+	h, err := s.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if !strings.HasPrefix(h, "$scrypt$ln=10,r=8,p=1$") {
+		t.Errorf("expected a PHC string but got %q", h)
+	}
+
+	if err := s.Compare(h, "correct horse"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if err := s.Compare(h, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+	}
+}
+
+func TestScrypt_Interop(t *testing.T) {
+	// generated with Python's hashlib.scrypt.
+	const hash = "$scrypt$ln=10,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic"
+
+	if err := mustScrypt(t, DefaultScryptParams).Compare(hash, "password"); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+}
+
+func TestScrypt_InvalidHash(t *testing.T) {
+	s := mustScrypt(t, DefaultScryptParams)
+	hashes := []string{
+		"",
+		"$scrypt$ln=0,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=31,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=10,r=0,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=10,r=8$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=10,r=1073741824,p=1073741824$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=30,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=21,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=10,r=16777216,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$scrypt$ln=10,r=8,p=17$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+	}
+
+	for _, h := range hashes {
+		if err := s.Compare(h, "password"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
+
+func TestScrypt_NeedsRehash(t *testing.T) {
+	current := mustScrypt(t, ScryptParams{LogN: 10})
+	for params, want := range map[ScryptParams]bool{
+		current.Params():                      false,
+		ScryptParams{LogN: 9}:                 true,
+		ScryptParams{LogN: 10, KeyLength: 64}: false,
+	} {
+		h, err := mustScrypt(t, params).Hash("password")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if got := current.NeedsRehash(h); got != want {
+			t.Errorf("expected NeedsRehash %v for %+v but got %v", want, params, got)
+		}
+	}
+
+	if !current.NeedsRehash("$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA") {
+		t.Errorf("expected a foreign hash to need a rehash")
+	}
+}
+
+func TestNewScrypt_Bounds(t *testing.T) {
+	for params, want := range map[ScryptParams]error{
+		ScryptParams{LogN: 20, BlockSize: 8, Parallelism: 16}: nil,
+		ScryptParams{LogN: 20, BlockSize: 9}:                  ErrInvalidParams,
+		ScryptParams{LogN: 31, BlockSize: 1}:                  ErrInvalidParams,
+		ScryptParams{Parallelism: 17}:                         ErrInvalidParams,
+	} {
+		if _, err := NewScrypt(params); !errors.Is(err, want) {
+			t.Errorf("expected error %v for %+v but got %v", want, params, err)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// The scores of the estimated number of guesses, like zxcvbn: below 10^3,
+// 10^6, 10^8 and 10^10 guesses, in bits.
+var scoreBits = [...]float64{
+	math.Log2(1e3),
+	math.Log2(1e6),
+	math.Log2(1e8),
+	math.Log2(1e10),
+}
+
+// maxWordLength bounds the length of the words matched in the lists, so the
+// estimate of the long passwords stays linear.
+const maxWordLength = 32
+
+// keyboardRows are the rows of a QWERTY keyboard.
+var keyboardRows = [...]string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// l33t reverts the common character substitutions.
+var l33t = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+}
+
+// Strength is the estimated strength of a password.
+type Strength struct {
+	// Entropy is the base 2 logarithm of the estimated number of guesses.
+	Entropy float64
+	// Score is the strength from 0, too guessable, to 4, very unguessable.
+	Score int
+	// Warning explains what makes the password guessable, if anything.
+	Warning string
+	// Suggestions help to choose a stronger password.
+	Suggestions []string
+}
+
+// matchKind is the kind of a guessable pattern.
+type matchKind int
+
+// The kinds are ordered by the priority of their warning.
+const (
+	userInputMatch matchKind = iota
+	dictionaryMatch
+	keyboardMatch
+	sequenceMatch
+	repeatMatch
+	yearMatch
+)
+
+// match is a guessable pattern of the password runes [i, j).
+type match struct {
+	i, j  int
+	bits  float64
+	kind  matchKind
+	whole bool
+	upper bool
+	l33t  bool
+}
+
+// EstimateStrength estimates the strength of the password in the spirit of
+// zxcvbn: the password is split into the guessable patterns, the words of
+// the list and of the user inputs, keyboard rows, sequences, repeats and
+// years, and into brute forced characters, and the entropy is the cheapest
+// split an attacker would try. The list can be nil.
+//
+// The user inputs are the personal information of the user, like the
+// username or the email address, which an attacker would try first.
+func EstimateStrength(password string, words *Wordlist, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Suggestions: []string{"Use a few words, avoid common phrases."}}
+	}
+
+	matches := make([][]match, len(runes)+1)
+	for _, m := range findMatches(runes, words, userInputs) {
+		matches[m.j] = append(matches[m.j], m)
+	}
+
+	// best[j] is the entropy of the cheapest split of runes[:j], and used[j]
+	// is its last pattern, nil for a brute forced character.
+	perChar := bruteforceBits(runes)
+	best := make([]float64, len(runes)+1)
+	used := make([]*match, len(runes)+1)
+	for j := 1; j <= len(runes); j++ {
+		best[j] = best[j-1] + perChar
+		for k := range matches[j] {
+			m := &matches[j][k]
+			if bits := best[m.i] + m.bits; bits < best[j] {
+				best[j], used[j] = bits, m
+			}
+		}
+	}
+
+	var patterns []*match
+	for j := len(runes); j > 0; {
+		if m := used[j]; m != nil {
+			patterns = append(patterns, m)
+			j = m.i
+		} else {
+			j--
+		}
+	}
+
+	s := Strength{Entropy: best[len(runes)]}
+	for s.Score < len(scoreBits) && s.Entropy >= scoreBits[s.Score] {
+		s.Score++
+	}
+
+	if s.Score <= 2 {
+		s.Warning, s.Suggestions = feedback(patterns)
+	}
+
+	return s
+}
+
+// findMatches returns every guessable pattern of the password.
+func findMatches(runes []rune, words *Wordlist, userInputs []string) []match {
+	lower := make([]rune, len(runes))
+	unl33t := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+		unl33t[i] = lower[i]
+		if sub, ok := l33t[lower[i]]; ok {
+			unl33t[i] = sub
+		}
+	}
+
+	inputs := NewWordlist()
+	for _, input := range userInputs {
+		for _, token := range contextTokens(input) {
+			inputs.add(token)
+		}
+	}
+
+	var matches []match
+	for i := range runes {
+		for j := i + 3; j <= len(runes) && j-i <= maxWordLength; j++ {
+			matches = append(matches, wordMatches(runes, lower, unl33t, i, j, words, inputs)...)
+		}
+	}
+
+	matches = append(matches, runMatches(lower)...)
+	for i := 0; i+4 <= len(runes); i++ {
+		if isYear(runes[i : i+4]) {
+			matches = append(matches, match{i: i, j: i + 4, bits: math.Log2(140), kind: yearMatch})
+		}
+	}
+
+	return matches
+}
+
+// wordMatches returns the matches of runes[i:j] in the word lists, as is
+// and with the l33t substitutions reverted.
+func wordMatches(runes, lower, unl33t []rune, i, j int, words, inputs *Wordlist) []match {
+	var matches []match
+	for _, candidate := range []struct {
+		word string
+		l33t bool
+	}{
+		{string(lower[i:j]), false},
+		{string(unl33t[i:j]), true},
+	} {
+		if candidate.l33t && candidate.word == string(lower[i:j]) {
+			continue
+		}
+
+		// the variations of the word cost a bit each.
+		variations := uppercaseBits(runes[i:j])
+		m := match{i: i, j: j, whole: i == 0 && j == len(runes), upper: variations > 0, l33t: candidate.l33t}
+		if candidate.l33t {
+			variations++
+		}
+
+		if rank, ok := inputs.Rank(candidate.word); ok {
+			m.kind, m.bits = userInputMatch, math.Log2(float64(rank))+variations
+			matches = append(matches, m)
+		}
+
+		if words == nil {
+			continue
+		}
+
+		if rank, ok := words.Rank(candidate.word); ok {
+			m.kind, m.bits = dictionaryMatch, math.Log2(float64(rank))+variations
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+// runMatches returns the maximal keyboard rows, sequences and repeats of at
+// least 3 characters.
+func runMatches(lower []rune) []match {
+	var matches []match
+	for _, kind := range []matchKind{keyboardMatch, sequenceMatch, repeatMatch} {
+		i := 0
+		for i < len(lower) {
+			j, turns := i+1, 0
+			for j < len(lower) {
+				ok, turn := extendsRun(kind, lower, i, j)
+				if !ok {
+					break
+				}
+
+				if turn {
+					turns++
+				}
+
+				j++
+			}
+
+			if j-i >= 3 {
+				matches = append(matches, runMatch(kind, lower, i, j, turns))
+			}
+
+			if j-i > 1 {
+				i = j - 1
+			} else {
+				i = j
+			}
+		}
+	}
+
+	return matches
+}
+
+// extendsRun reports whether lower[j] extends the run lower[i:j] of the
+// kind, and whether the keyboard row run changes its direction.
+func extendsRun(kind matchKind, lower []rune, i, j int) (ok, turn bool) {
+	prev, cur := lower[j-1], lower[j]
+	switch kind {
+	case repeatMatch:
+		return cur == lower[i], false
+	case sequenceMatch:
+		delta := cur - prev
+		if j-i == 1 {
+			return delta == 1 || delta == -1, false
+		}
+
+		return delta == lower[i+1]-lower[i], false
+	}
+
+	row, a := keyboardPosition(prev)
+	rowB, b := keyboardPosition(cur)
+	if row < 0 || row != rowB || (a-b != 1 && b-a != 1) {
+		return false, false
+	}
+
+	if j-i == 1 {
+		return true, false
+	}
+
+	_, before := keyboardPosition(lower[j-2])
+	return true, (b - a) != (a - before)
+}
+
+// runMatch returns the match of the run lower[i:j].
+func runMatch(kind matchKind, lower []rune, i, j, turns int) match {
+	length := math.Log2(float64(j - i))
+	m := match{i: i, j: j, kind: kind, whole: i == 0 && j == len(lower)}
+	switch kind {
+	case repeatMatch:
+		m.bits = math.Log2(float64(classSize(lower[i]))) + length
+	case sequenceMatch:
+		m.bits = math.Log2(float64(classSize(lower[i]))) + length
+		if strings.ContainsRune("a0129z", lower[i]) {
+			m.bits = 1 + length
+		}
+
+		if lower[i+1] < lower[i] {
+			m.bits++
+		}
+	case keyboardMatch:
+		m.bits = math.Log2(36) + length + float64(turns)
+	}
+
+	return m
+}
+
+// keyboardPosition returns the row and the column of the key, or -1 if the
+// key is not on the rows.
+func keyboardPosition(r rune) (int, int) {
+	for row, keys := range keyboardRows {
+		if col := strings.IndexRune(keys, r); col >= 0 {
+			return row, col
+		}
+	}
+
+	return -1, -1
+}
+
+// bruteforceBits returns the entropy of a brute forced character of the
+// password, from the character classes it uses.
+func bruteforceBits(runes []rune) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			size += c.size
+		}
+	}
+
+	return math.Log2(float64(size))
+}
+
+// classSize returns the size of the character class of r.
+func classSize(r rune) int {
+	switch {
+	case unicode.IsLetter(r):
+		return 26
+	case unicode.IsDigit(r):
+		return 10
+	}
+
+	return 33
+}
+
+// uppercaseBits returns the entropy added by the capitalization of a word:
+// none for a lowercase word, a bit for a capitalized or uppercase word, and
+// the number of the less frequent case letters otherwise.
+func uppercaseBits(word []rune) float64 {
+	var upper, lower int
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0 || (upper == 1 && unicode.IsUpper(word[0])):
+		return 1
+	case upper < lower:
+		return float64(upper) + 1
+	}
+
+	return float64(lower) + 1
+}
+
+// isYear reports whether the 4 runes are a year between 1900 and 2039.
+func isYear(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	year := string(runes)
+	return (year >= "1900" && year <= "1999") || (year >= "2000" && year <= "2039")
+}
+
+// contextTokens returns the input and its words of at least 3 letters or
+// digits, in lowercase.
+func contextTokens(input string) []string {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return nil
+	}
+
+	tokens := []string{input}
+	for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 && word != input {
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens
+}
+
+// feedback returns the warning and the suggestions of the guessable
+// patterns of a weak password.
+func feedback(patterns []*match) (string, []string) {
+	suggestions := []string{"Add another word or two. Uncommon words are better."}
+	if len(patterns) == 0 {
+		return "Short passwords are easy to guess.", suggestions
+	}
+
+	top := patterns[0]
+	var upper, l33tUsed, year bool
+	for _, m := range patterns {
+		if m.kind < top.kind {
+			top = m
+		}
+
+		upper = upper || m.upper
+		l33tUsed = l33tUsed || m.l33t
+		year = year || m.kind == yearMatch
+	}
+
+	if upper {
+		suggestions = append(suggestions, "Capitalization doesn't help very much.")
+	}
+
+	if l33tUsed {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much.")
+	}
+
+	if year {
+		suggestions = append(suggestions, "Avoid years that are associated with you.")
+	}
+
+	switch top.kind {
+	case userInputMatch:
+		return "Avoid using your personal information.", suggestions
+	case dictionaryMatch:
+		if top.whole {
+			return "This is a very common password.", suggestions
+		}
+
+		return "This is similar to a commonly used password.", suggestions
+	case keyboardMatch:
+		return "Straight rows of keys are easy to guess.", suggestions
+	case sequenceMatch:
+		return "Sequences like abc or 6543 are easy to guess.", suggestions
+	case repeatMatch:
+		return "Repeats like \"aaa\" are easy to guess.", suggestions
+	}
+
+	return "Recent years are easy to guess.", suggestions
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		score    int
+		warning  string
+	}{
+		{"", 0, ""},
+		{"a", 0, "Short passwords are easy to guess."},
+		{"123456", 0, "This is a very common password."},
+		{"P@ssw0rd", 0, "This is a very common password."},
+		{"Password1!", 1, "This is similar to a commonly used password."},
+		{"abcdefgh", 0, "Sequences like abc or 6543 are easy to guess."},
+		{"aaaaaaaa", 0, `Repeats like "aaa" are easy to guess.`},
+		{"asdfghjk", 0, "Straight rows of keys are easy to guess."},
+		{"19841984", 1, "Recent years are easy to guess."},
+		{"johnsmith99", 1, "Avoid using your personal information."},
+		{"kJh3nX9w", 4, ""},
+		{"correct horse battery staple", 4, ""},
+	}
+
+	for _, tt := range tests {
+		s := EstimateStrength(tt.password, CommonPasswords(), "john.smith@example.com")
+		if s.Score != tt.score || s.Warning != tt.warning {
+			t.Errorf("expected score %d and warning %q for %q but got %d and %q", tt.score, tt.warning, tt.password, s.Score, s.Warning)
+		}
+
+		if s.Score <= 2 && len(s.Suggestions) == 0 {
+			t.Errorf("expected suggestions for %q", tt.password)
+		}
+
+		if s.Score > 2 && (s.Warning != "" || len(s.Suggestions) != 0) {
+			t.Errorf("expected no feedback for %q but got %q, %v", tt.password, s.Warning, s.Suggestions)
+		}
+	}
+}
+
+func TestEstimateStrength_Feedback(t *testing.T) {
+	s := EstimateStrength("P@ssw0rd2019", CommonPasswords())
+	want := []string{
+		"Add another word or two. Uncommon words are better.",
+		"Capitalization doesn't help very much.",
+		"Predictable substitutions like '@' instead of 'a' don't help very much.",
+		"Avoid years that are associated with you.",
+	}
+
+	if strings.Join(s.Suggestions, "|") != strings.Join(want, "|") {
+		t.Errorf("expected suggestions %q but got %q", want, s.Suggestions)
+	}
+}
+
+func TestEstimateStrength_Entropy(t *testing.T) {
+	// 10 random lowercase letters are brute forced.
+	s := EstimateStrength("xqzvjwkpmf", nil)
+	if s.Entropy < 47 || s.Entropy > 47.1 {
+		t.Errorf("expected about 47 bits but got %.2f", s.Entropy)
+	}
+
+	// the patterns are cheaper than the brute force.
+	if s := EstimateStrength("xqzvjwkpmfpassword", CommonPasswords()); s.Entropy > 49 {
+		t.Errorf("expected the common word to add about 1 bit but got %.2f", s.Entropy)
+	}
+
+	// the estimate of the long passwords stays fast.
+	if s := EstimateStrength(strings.Repeat("xq", 1<<14), nil); s.Score != 4 {
+		t.Errorf("expected score 4 but got %d", s.Score)
+	}
+}
+
+func TestContextTokens(t *testing.T) {
+	got := contextTokens(" John.Smith@Example.com ")
+	want := []string{"john.smith@example.com", "john", "smith", "example", "com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	if got := contextTokens("  "); got != nil {
+		t.Errorf("expected no token but got %q", got)
+	}
+}
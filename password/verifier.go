@@ -0,0 +1,124 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Rehasher is a HashComparer that knows whether a hashed password should be
+// hashed again with its current algorithm and parameters.
+type Rehasher interface {
+	HashComparer
+
+	// NeedsRehash reports whether the hashed password uses another algorithm
+	// or weaker parameters than the new hashes.
+	NeedsRehash(hashedPassword string) bool
+}
+
+// VerifierOption is an option to configure the Verifier.
+type VerifierOption func(*Verifier)
+
+// WithComparer registers the hash comparer of the hashes with the given
+// identifier, the text between the first two '$' of the hash, like
+// "argon2id" or "2b". It replaces the default comparer of the identifier.
+func WithComparer(id string, hc HashComparer) VerifierOption {
+	return func(v *Verifier) {
+		v.comparers[id] = hc
+	}
+}
+
+// Verifier is a HashComparer that verifies the hashes of every supported
+// algorithm, detected from the hash prefix, and hashes the new passwords
+// with the current Rehasher.
+//
+// It allows to migrate the stored hashes to a new algorithm or to stronger
+// parameters without a password reset: Verify returns the new hash of the
+// password when the stored hash is outdated, which the caller stores on
+// successful login.
+type Verifier struct {
+	current   Rehasher
+	comparers map[string]HashComparer
+}
+
+// NewVerifier creates a new Verifier that hashes with the current Rehasher.
+// The bcrypt, Argon2id, scrypt and PBKDF2-SHA256 hashes are detected by
+// default, the comparers use the parameters encoded in the hashes. The
+// pre-hashed bcrypt hashes are detected with an empty key, the other keys
+// must be registered with WithComparer("bcrypt-sha256", ...).
+func NewVerifier(current Rehasher, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		current: current,
+		comparers: map[string]HashComparer{
+			"2a":            Bcrypt,
+			"2b":            Bcrypt,
+			"2y":            Bcrypt,
+			"bcrypt-sha256": &BcryptComparer{cost: bcrypt.DefaultCost, prehash: true},
+			"argon2id":      &Argon2id{params: DefaultArgon2idParams},
+			"scrypt":        &Scrypt{params: DefaultScryptParams},
+			"pbkdf2-sha256": &PBKDF2{params: DefaultPBKDF2Params},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Compare compares the hashed password with the plain password using the
+// comparer of the hash algorithm. It returns ErrUnsupported for the unknown
+// algorithms and ErrMismatchedPassword when the password does not match.
+func (v *Verifier) Compare(hashedPassword, plainPassword string) error {
+	hc, ok := v.comparers[hashID(hashedPassword)]
+	if !ok {
+		return ErrUnsupported
+	}
+
+	err := hc.Compare(hashedPassword, plainPassword)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+
+	return err
+}
+
+// Hash hashes the plain password with the current Rehasher.
+func (v *Verifier) Hash(plainPassword string) (string, error) {
+	return v.current.Hash(plainPassword)
+}
+
+// NeedsRehash reports whether the hashed password uses another algorithm or
+// weaker parameters than the current Rehasher.
+func (v *Verifier) NeedsRehash(hashedPassword string) bool {
+	return v.current.NeedsRehash(hashedPassword)
+}
+
+// Verify compares the hashed password with the plain password like Compare.
+// When they match and the hash needs a rehash, it returns the new hash of
+// the password, which should replace the stored hash. Otherwise the new hash
+// is empty.
+func (v *Verifier) Verify(hashedPassword, plainPassword string) (string, error) {
+	if err := v.Compare(hashedPassword, plainPassword); err != nil {
+		return "", err
+	}
+
+	if !v.NeedsRehash(hashedPassword) {
+		return "", nil
+	}
+
+	return v.Hash(plainPassword)
+}
+
+// hashID returns the identifier of the hash algorithm, the text between the
+// first two '$'.
+func hashID(hashedPassword string) string {
+	if !strings.HasPrefix(hashedPassword, "$") {
+		return ""
+	}
+
+	id, _, _ := strings.Cut(hashedPassword[1:], "$")
+	return id
+}
@@ -0,0 +1,136 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	current := mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 2})
+	v := NewVerifier(current)
+
+	weakBcrypt, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	weakArgon2id, err := mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 1}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	weakScrypt, err := mustScrypt(t, ScryptParams{LogN: 4}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	weakPBKDF2, err := mustPBKDF2(t, PBKDF2Params{Iterations: 1}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for _, hashed := range []string{string(weakBcrypt), weakArgon2id, weakScrypt, weakPBKDF2} {
+		if !v.NeedsRehash(hashed) {
+			t.Errorf("expected %q to need a rehash", hashed)
+		}
+
+		if _, err := v.Verify(hashed, "wrong horse"); !errors.Is(err, ErrMismatchedPassword) {
+			t.Errorf("expected error %v but got %v", ErrMismatchedPassword, err)
+		}
+
+		rehashed, err := v.Verify(hashed, "correct horse")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if rehashed == "" || v.NeedsRehash(rehashed) {
+			t.Errorf("expected a new current hash but got %q", rehashed)
+		}
+
+		if err := current.Compare(rehashed, "correct horse"); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		rehashed, err = v.Verify(rehashed, "correct horse")
+		if err != nil || rehashed != "" {
+			t.Errorf("expected no rehash of a current hash but got %q, %v", rehashed, err)
+		}
+	}
+}
+
+func TestVerifier_Compare(t *testing.T) {
+	v := NewVerifier(Bcrypt, WithComparer("legacy", mustPBKDF2(t, DefaultPBKDF2Params)))
+
+	if err := v.Compare("plain-text", "plain-text"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected error %v but got %v", ErrUnsupported, err)
+	}
+
+	if err := v.Compare("$md5$abc", "password"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected error %v but got %v", ErrUnsupported, err)
+	}
+
+	// the registered comparer gets the whole hash.
+	if err := v.Compare("$legacy$i=1$c2FsdA$aGFzaA", "password"); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected error %v but got %v", ErrInvalidHash, err)
+	}
+
+	// the parameters of the stored hashes are bounded.
+	for _, h := range []string{
+		"$argon2id$v=19$m=4294967295,t=4294967295,p=255$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$scrypt$ln=30,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic",
+		"$pbkdf2-sha256$i=2147483647$c29tZXNhbHRzb21lc2FsdA$s5LQUeAEZUMuFVrnmF3OMNPXs3QWnF8SO/5BXmCj6QQ",
+	} {
+		if err := v.Compare(h, "password"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected error %v for %q but got %v", ErrInvalidHash, h, err)
+		}
+	}
+
+	h, err := v.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if id := hashID(h); id != "2a" {
+		t.Errorf("expected a bcrypt hash but got %q", h)
+	}
+
+	if v.NeedsRehash(h) {
+		t.Errorf("expected no rehash of a current hash")
+	}
+}
+
+func TestHashID(t *testing.T) {
+	tests := map[string]string{
+		"$2b$10$abc":                 "2b",
+		"$argon2id$v=19$m=1$abc$def": "argon2id",
+		"$scrypt":                    "scrypt",
+		"argon2id$v=19":              "",
+		"":                           "",
+	}
+
+	for hashed, want := range tests {
+		if got := hashID(hashed); got != want {
+			t.Errorf("expected %q but got %q", want, got)
+		}
+	}
+}
+
+func TestVerifier_BcryptPrehash(t *testing.T) {
+	current, err := NewBcrypt(bcrypt.MinCost, WithBcryptPrehash(nil))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	h, err := current.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	v := NewVerifier(mustArgon2id(t, Argon2idParams{Memory: 1024, Iterations: 1}))
+	rehashed, err := v.Verify(h, "correct horse")
+	if err != nil || hashID(rehashed) != "argon2id" {
+		t.Errorf("expected an argon2id rehash but got %q, %v", rehashed, err)
+	}
+}
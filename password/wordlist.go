@@ -0,0 +1,109 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+//go:embed wordlists/common-passwords.txt
+var commonPasswords []byte
+
+// Wordlist is a case-insensitive list of words ranked by frequency, used to
+// deny the common passwords and to estimate the password strength.
+type Wordlist struct {
+	ranks map[string]int
+}
+
+// NewWordlist creates a new Wordlist of the given words, the most frequent
+// first.
+func NewWordlist(words ...string) *Wordlist {
+	w := &Wordlist{ranks: make(map[string]int, len(words))}
+	for _, word := range words {
+		w.add(word)
+	}
+
+	return w
+}
+
+// LoadWordlist loads the Wordlist of the named file of fsys, one word per
+// line, the most frequent first. The blank lines and the lines starting with
+// '#' are ignored.
+func LoadWordlist(fsys fs.FS, name string) (*Wordlist, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(f fs.File) {
+		_ = f.Close()
+	}(f)
+
+	return readWordlist(f)
+}
+
+var (
+	commonOnce     sync.Once
+	commonWordlist *Wordlist
+)
+
+// CommonPasswords returns the embedded Wordlist of the most common
+// passwords. It only holds about 240 words, enough to deny the worst
+// passwords but not a real breach corpus; load a larger list, such as a
+// top-10k or top-100k list, with LoadWordlist and give it to WithDenylist.
+func CommonPasswords() *Wordlist {
+	commonOnce.Do(func() {
+		// the embedded list is read from memory, it can not fail.
+		commonWordlist, _ = readWordlist(bytes.NewReader(commonPasswords))
+	})
+
+	return commonWordlist
+}
+
+// Contains reports whether the word is in the list.
+func (w *Wordlist) Contains(word string) bool {
+	_, ok := w.Rank(word)
+	return ok
+}
+
+// Rank returns the 1-based frequency rank of the word.
+func (w *Wordlist) Rank(word string) (int, bool) {
+	rank, ok := w.ranks[strings.ToLower(word)]
+	return rank, ok
+}
+
+// Len returns the number of words.
+func (w *Wordlist) Len() int { return len(w.ranks) }
+
+// add adds the word with the next rank, the duplicates keep their first
+// rank.
+func (w *Wordlist) add(word string) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" {
+		return
+	}
+
+	if _, ok := w.ranks[word]; !ok {
+		w.ranks[word] = len(w.ranks) + 1
+	}
+}
+
+func readWordlist(r io.Reader) (*Wordlist, error) {
+	w := NewWordlist()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := s.Text(); !strings.HasPrefix(line, "#") {
+			w.add(line)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
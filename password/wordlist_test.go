@@ -0,0 +1,68 @@
+package password
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWordlist(t *testing.T) {
+	w := NewWordlist("password", "Dragon", " ", "password", "monkey")
+	if n := w.Len(); n != 3 {
+		t.Errorf("expected 3 words but got %d", n)
+	}
+
+	tests := []struct {
+		word string
+		rank int
+		ok   bool
+	}{
+		{"password", 1, true},
+		{"DRAGON", 2, true},
+		{"monkey", 3, true},
+		{"horse", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		rank, ok := w.Rank(tt.word)
+		if rank != tt.rank || ok != tt.ok || w.Contains(tt.word) != tt.ok {
+			t.Errorf("expected rank %d, %v for %q but got %d, %v", tt.rank, tt.ok, tt.word, rank, ok)
+		}
+	}
+}
+
+func TestLoadWordlist(t *testing.T) {
+	fsys := fstest.MapFS{
+		"words.txt": {Data: []byte("# leaked passwords\nhunter2\n\n  Tr0ub4dor  \r\nhunter2\n")},
+	}
+
+	w, err := LoadWordlist(fsys, "words.txt")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if w.Len() != 2 || !w.Contains("hunter2") || !w.Contains("tr0ub4dor") || w.Contains("# leaked passwords") {
+		t.Errorf("unexpected wordlist %v", w.ranks)
+	}
+
+	if _, err := LoadWordlist(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected error %v but got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestCommonPasswords(t *testing.T) {
+	w := CommonPasswords()
+	if w != CommonPasswords() {
+		t.Errorf("expected the embedded list to be loaded once")
+	}
+
+	if rank, ok := w.Rank("123456"); !ok || rank != 1 {
+		t.Errorf("expected rank 1 but got %d, %v", rank, ok)
+	}
+
+	if !w.Contains("Password") || w.Contains("correct horse battery staple") {
+		t.Errorf("unexpected common passwords")
+	}
+}
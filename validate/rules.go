@@ -0,0 +1,197 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// rule is a single parsed validation rule.
+type rule struct {
+	name  string
+	param string
+	check func(parent, rv reflect.Value) (msg string, ok bool)
+}
+
+// parseTag parses the validate tag into the field rules and the element rules
+// that follow "dive". The regex rule consumes the rest of the tag, so it must
+// be the last rule.
+func parseTag(t reflect.Type, tag string) (rules, dive []rule, err error) {
+	if tag == "" {
+		return nil, nil, nil
+	}
+
+	current := &rules
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "dive" {
+			if dive != nil {
+				return nil, nil, fmt.Errorf("nested dive is not supported")
+			}
+
+			dive = []rule{}
+			current = &dive
+			continue
+		}
+
+		r, err := newRule(t, name, param)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		*current = append(*current, r)
+	}
+
+	return rules, dive, nil
+}
+
+func newRule(t reflect.Type, name, param string) (rule, error) {
+	r := rule{name: name, param: param}
+	switch name {
+	case "required":
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return r, fmt.Errorf("rule %s: invalid param %q", name, param)
+		}
+
+		r.check = sizeRule(name, n)
+	case "oneof":
+		r.check = oneOfRule(strings.Fields(param))
+	case "email":
+		r.check = stringRule("must be a valid email address", func(s string) bool {
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Address == s
+		})
+	case "url":
+		r.check = stringRule("must be a valid url", func(s string) bool {
+			u, err := url.Parse(s)
+			return err == nil && u.Scheme != "" && u.Host != ""
+		})
+	case "uuid":
+		r.check = stringRule("must be a valid uuid", func(s string) bool {
+			_, err := uuid.Parse(s)
+			return err == nil
+		})
+	case "regex":
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return r, fmt.Errorf("rule regex: %w", err)
+		}
+
+		r.check = stringRule("must match "+param, re.MatchString)
+	case "eqfield":
+		if _, ok := t.FieldByName(param); !ok {
+			return r, fmt.Errorf("rule eqfield: unknown field %q", param)
+		}
+
+		r.check = eqFieldRule(param)
+	default:
+		return r, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return r, nil
+}
+
+// sizeRule compares the value of numbers, the length in characters of
+// strings, and the length of slices, arrays and maps with n.
+func sizeRule(name string, n float64) func(_, rv reflect.Value) (string, bool) {
+	return func(_, rv reflect.Value) (string, bool) {
+		var (
+			size float64
+			unit string
+		)
+
+		switch rv.Kind() {
+		case reflect.String:
+			size, unit = float64(utf8.RuneCountInString(rv.String())), " characters"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			size, unit = float64(rv.Len()), " items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			size = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			size = rv.Float()
+		default:
+			return fmt.Sprintf("rule %s does not support %s", name, rv.Kind()), false
+		}
+
+		param := strconv.FormatFloat(n, 'f', -1, 64)
+		switch name {
+		case "min":
+			if unit == "" {
+				return "must be at least " + param, size >= n
+			}
+
+			return "must have at least " + param + unit, size >= n
+		case "max":
+			if unit == "" {
+				return "must be at most " + param, size <= n
+			}
+
+			return "must have at most " + param + unit, size <= n
+		}
+
+		if unit == "" {
+			return "must be " + param, size == n
+		}
+
+		return "must have exactly " + param + unit, size == n
+	}
+}
+
+func oneOfRule(options []string) func(_, rv reflect.Value) (string, bool) {
+	msg := "must be one of " + strings.Join(options, ", ")
+	return func(_, rv reflect.Value) (string, bool) {
+		s := fmt.Sprint(rv.Interface())
+		for _, opt := range options {
+			if s == opt {
+				return msg, true
+			}
+		}
+
+		return msg, false
+	}
+}
+
+func stringRule(msg string, fn func(s string) bool) func(_, rv reflect.Value) (string, bool) {
+	return func(_, rv reflect.Value) (string, bool) {
+		if rv.Kind() != reflect.String {
+			return msg, false
+		}
+
+		return msg, fn(rv.String())
+	}
+}
+
+// eqFieldRule compares the value with the sibling field.
+func eqFieldRule(field string) func(parent, rv reflect.Value) (string, bool) {
+	return func(parent, rv reflect.Value) (string, bool) {
+		other := indirect(parent.FieldByName(field))
+		msg := "must be equal to " + field
+		if !rv.IsValid() || !other.IsValid() {
+			return msg, rv.IsValid() == other.IsValid()
+		}
+
+		if rv.Type() != other.Type() || !rv.Type().Comparable() {
+			return msg, false
+		}
+
+		return msg, rv.Interface() == other.Interface()
+	}
+}
@@ -0,0 +1,333 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidTarget is returned when the value to validate is not a struct or
+// a pointer to struct.
+var ErrInvalidTarget = errors.New("validate: target must be a struct or a pointer to struct")
+
+// Validatable is implemented by types that have custom validation rules.
+// Validate is called after the struct tags of the type are validated.
+// Returning Errors reports the field errors relative to the validated value.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldError is a validation error of a single field.
+type FieldError struct {
+	// Field is the path of the field, for example "items[0].name".
+	Field string `json:"field"`
+	// Rule is the rule that is violated, for example "required".
+	Rule string `json:"rule"`
+	// Param is the parameter of the rule, for example "3" for "min=3".
+	Param string `json:"param,omitempty"`
+	// Message is a human-readable description of the error.
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+
+	return e.Field + ": " + e.Message
+}
+
+// Errors is a list of field errors.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Struct validates v using its `validate` struct tags and the Validatable
+// interface of v and its nested values. It returns Errors listing every
+// invalid field, or nil when v is valid.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ErrInvalidTarget
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+
+	return validate(rv)
+}
+
+// Value validates v like Struct, but v can be any value: the elements of a
+// slice, array or map are validated, and a value without structs is valid.
+func Value(v any) error {
+	return validate(reflect.ValueOf(v))
+}
+
+func validate(rv reflect.Value) error {
+	var errs Errors
+	validateValue(rv, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// validateValue validates nested structs, slice, array and map elements,
+// and runs the custom Validatable rules.
+func validateValue(rv reflect.Value, path string, errs *Errors) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		validateStruct(rv, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			validateValue(rv.Index(i), indexPath(path, strconv.Itoa(i)), errs)
+		}
+		return
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), indexPath(path, fmt.Sprint(iter.Key().Interface())), errs)
+		}
+		return
+	default:
+		return
+	}
+
+	validateCustom(rv, path, errs)
+}
+
+func validateStruct(rv reflect.Value, path string, errs *Errors) {
+	s := cachedStruct(rv.Type())
+	for _, f := range s.fields {
+		fv := rv.Field(f.index)
+		fpath := fieldPath(path, f.name)
+		validateRules(rv, fv, fpath, f.rules, errs)
+		if f.dive != nil {
+			validateDive(rv, fv, fpath, f.dive, errs)
+		}
+
+		validateValue(fv, fpath, errs)
+	}
+}
+
+// validateDive applies the rules to every element of a slice, array or map.
+func validateDive(parent, rv reflect.Value, path string, rules []rule, errs *Errors) {
+	rv = indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			validateRules(parent, rv.Index(i), indexPath(path, strconv.Itoa(i)), rules, errs)
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			validateRules(parent, iter.Value(), indexPath(path, fmt.Sprint(iter.Key().Interface())), rules, errs)
+		}
+	}
+}
+
+func validateRules(parent, rv reflect.Value, path string, rules []rule, errs *Errors) {
+	for _, r := range rules {
+		if r.name == "required" {
+			if isZero(rv) {
+				*errs = append(*errs, FieldError{Field: path, Rule: r.name, Message: "is required"})
+				return
+			}
+
+			continue
+		}
+
+		// the other rules only apply to values that are present, the
+		// presence itself is checked by required.
+		if isAbsent(rv) && r.name != "eqfield" {
+			continue
+		}
+
+		if msg, ok := r.check(parent, indirect(rv)); !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: r.name, Param: r.param, Message: msg})
+		}
+	}
+}
+
+func validateCustom(rv reflect.Value, path string, errs *Errors) {
+	v, ok := asValidatable(rv)
+	if !ok {
+		return
+	}
+
+	err := v.Validate()
+	if err == nil {
+		return
+	}
+
+	var fieldErrs Errors
+	if errors.As(err, &fieldErrs) {
+		for _, fe := range fieldErrs {
+			fe.Field = fieldPath(path, fe.Field)
+			*errs = append(*errs, fe)
+		}
+
+		return
+	}
+
+	*errs = append(*errs, FieldError{Field: path, Rule: "custom", Message: err.Error()})
+}
+
+func asValidatable(rv reflect.Value) (Validatable, bool) {
+	if rv.CanInterface() {
+		if v, ok := rv.Interface().(Validatable); ok {
+			return v, true
+		}
+	}
+
+	if rv.CanAddr() && rv.Addr().CanInterface() {
+		if v, ok := rv.Addr().Interface().(Validatable); ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// structInfo is the parsed validation rules of a struct type.
+type structInfo struct {
+	fields []fieldInfo
+}
+
+type fieldInfo struct {
+	index int
+	name  string
+	rules []rule
+	dive  []rule
+}
+
+var structCache sync.Map // map[reflect.Type]*structInfo
+
+func cachedStruct(t reflect.Type) *structInfo {
+	if s, ok := structCache.Load(t); ok {
+		return s.(*structInfo)
+	}
+
+	s := parseStruct(t)
+	actual, _ := structCache.LoadOrStore(t, s)
+	return actual.(*structInfo)
+}
+
+func parseStruct(t reflect.Type) *structInfo {
+	s := &structInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		rules, dive, err := parseTag(t, tag)
+		if err != nil {
+			panic(fmt.Sprintf("validate: field %s.%s: %v", t.Name(), sf.Name, err))
+		}
+
+		s.fields = append(s.fields, fieldInfo{
+			index: i,
+			name:  fieldName(sf),
+			rules: rules,
+			dive:  dive,
+		})
+	}
+
+	return s
+}
+
+// fieldName returns the json name of the field, or the Go name when the
+// field has no json name.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+
+	return name
+}
+
+func fieldPath(parent, name string) string {
+	switch {
+	case name == "":
+		return parent
+	case parent == "":
+		return name
+	case strings.HasPrefix(name, "["):
+		return parent + name
+	}
+
+	return parent + "." + name
+}
+
+func indexPath(parent, index string) string {
+	return parent + "[" + index + "]"
+}
+
+func indirect(rv reflect.Value) reflect.Value {
+	for (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	return rv
+}
+
+// isAbsent reports whether the value is missing: a nil pointer, or an empty
+// string, slice or map. Unlike isZero, the zero numbers and booleans are
+// present values.
+func isAbsent(rv reflect.Value) bool {
+	rv = indirect(rv)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+
+	return false
+}
+
+func isZero(rv reflect.Value) bool {
+	if !rv.IsValid() {
+		return true
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.IsNil() || rv.Len() == 0
+	}
+
+	return rv.IsZero()
+}
@@ -0,0 +1,243 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=5"`
+}
+
+type signup struct {
+	Name     string            `json:"name" validate:"required,min=3,max=10"`
+	Email    string            `json:"email" validate:"required,email"`
+	Website  string            `json:"website" validate:"url"`
+	ID       string            `json:"id" validate:"uuid"`
+	Role     string            `json:"role" validate:"oneof=admin member"`
+	Age      int               `json:"age" validate:"min=18,max=130"`
+	Tags     []string          `json:"tags" validate:"max=2,dive,min=2"`
+	Labels   map[string]string `json:"labels" validate:"dive,oneof=a b"`
+	Password string            `json:"password" validate:"required"`
+	Confirm  string            `json:"confirm" validate:"eqfield=Password"`
+	Code     string            `json:"code" validate:"regex=^[A-Z]{2,3}$"`
+	Address  address           `json:"address"`
+	Previous []*address        `json:"previous"`
+	Ignored  string            `validate:"-"`
+}
+
+func validSignup() signup {
+	return signup{
+		Name:     "gopher",
+		Email:    "gopher@example.com",
+		Website:  "https://go.dev",
+		ID:       "00010203-0405-4607-8809-0a0b0c0d0e0f",
+		Role:     "admin",
+		Age:      20,
+		Tags:     []string{"go", "kit"},
+		Labels:   map[string]string{"x": "a"},
+		Password: "secret",
+		Confirm:  "secret",
+		Code:     "ABC",
+		Address:  address{City: "Jakarta", Zip: "12345"},
+	}
+}
+
+func TestStruct_Valid(t *testing.T) {
+	v := validSignup()
+	if err := Struct(&v); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := Struct(v); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+}
+
+func TestStruct_Invalid(t *testing.T) {
+	v := signup{
+		Name:     "go",
+		Website:  "not a url",
+		ID:       "123",
+		Role:     "guest",
+		Age:      10,
+		Tags:     []string{"a", "bb", "cc"},
+		Labels:   map[string]string{"k": "z"},
+		Password: "secret",
+		Confirm:  "other",
+		Code:     "abc,d",
+		Address:  address{Zip: "1"},
+		Previous: []*address{{City: "Bandung", Zip: "123456"}},
+	}
+
+	err := Struct(&v)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors but got %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fe := range errs {
+		got[fe.Field] = fe.Rule
+	}
+
+	expected := map[string]string{
+		"name":            "min",
+		"email":           "required",
+		"website":         "url",
+		"id":              "uuid",
+		"role":            "oneof",
+		"age":             "min",
+		"tags":            "max",
+		"tags[0]":         "min",
+		"labels[k]":       "oneof",
+		"confirm":         "eqfield",
+		"code":            "regex",
+		"address.city":    "required",
+		"address.zip":     "len",
+		"previous[0].zip": "len",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+}
+
+type account struct {
+	Owner string `json:"owner"`
+	Limit int    `json:"limit"`
+}
+
+func (a account) Validate() error {
+	if a.Owner == "root" {
+		return Errors{{Field: "owner", Rule: "reserved", Message: "is reserved"}}
+	}
+
+	if a.Limit < 0 {
+		return errors.New("limit can not be negative")
+	}
+
+	return nil
+}
+
+type bank struct {
+	Accounts []account `json:"accounts"`
+}
+
+func TestStruct_Validatable(t *testing.T) {
+	err := Struct(bank{Accounts: []account{{Owner: "root"}, {Owner: "gopher", Limit: -1}}})
+
+	expected := Errors{
+		{Field: "accounts[0].owner", Rule: "reserved", Message: "is reserved"},
+		{Field: "accounts[1]", Rule: "custom", Message: "limit can not be negative"},
+	}
+
+	if !reflect.DeepEqual(err, expected) {
+		t.Fatalf("expected %v but got %v", expected, err)
+	}
+}
+
+func TestStruct_ZeroValues(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name string
+		v    any
+		rule string
+	}{
+		{name: "min int", v: struct {
+			Age int `validate:"min=18"`
+		}{}, rule: "min"},
+		{name: "max int", v: struct {
+			Age int `validate:"max=-1"`
+		}{}, rule: "max"},
+		{name: "len float", v: struct {
+			Ratio float64 `validate:"len=1"`
+		}{}, rule: "len"},
+		{name: "oneof uint", v: struct {
+			Level uint `validate:"oneof=1 2"`
+		}{}, rule: "oneof"},
+		{name: "pointer to zero", v: struct {
+			Age *int `validate:"min=18"`
+		}{Age: &zero}, rule: "min"},
+		{name: "nil pointer", v: struct {
+			Age *int `validate:"min=18"`
+		}{}},
+		{name: "empty string", v: struct {
+			Code string `validate:"len=5"`
+		}{}},
+		{name: "empty slice", v: struct {
+			Tags []string `validate:"min=1"`
+		}{}},
+		{name: "zero in range", v: struct {
+			Offset int `validate:"min=0,max=10"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(tt.v)
+			if tt.rule == "" {
+				if err != nil {
+					t.Fatalf("expected no error but got %v", err)
+				}
+
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != tt.rule {
+				t.Fatalf("expected rule %s to fail but got %v", tt.rule, err)
+			}
+		})
+	}
+}
+
+func TestStruct_InvalidTarget(t *testing.T) {
+	var nilSignup *signup
+	for _, v := range []any{nil, 1, "x", nilSignup} {
+		if err := Struct(v); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("expected %v but got %v", ErrInvalidTarget, err)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	for _, v := range []any{nil, 1, "x", []string{"a"}} {
+		if err := Value(v); err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	}
+
+	err := Value([]signup{{}})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) == 0 || !strings.HasPrefix(errs[0].Field, "[0].") {
+		t.Errorf("expected the elements to be validated but got %v", err)
+	}
+}
+
+func TestStruct_InvalidTag(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+
+	_ = Struct(struct {
+		A string `validate:"unknown"`
+	}{})
+}
+
+func TestErrors_Error(t *testing.T) {
+	errs := Errors{
+		{Field: "name", Message: "is required"},
+		{Message: "is invalid"},
+	}
+
+	if got, expected := errs.Error(), "name: is required; is invalid"; got != expected {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}